@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"synapse/internal/migration"
+	"synapse/internal/service"
+
+	"gorm.io/gorm"
+)
+
+const usage = `用法:
+  synapse                     启动服务
+  synapse migrate up          执行所有未执行的迁移
+  synapse migrate down [n]    回滚最近n个迁移（默认1个）
+  synapse migrate status      查看迁移状态
+  synapse reencrypt           使用当前主密钥重新加密所有通道凭证和主题签名密钥`
+
+// runCommand 执行命令行子命令
+func runCommand(db *gorm.DB, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(db, args[1:])
+	case "reencrypt":
+		count, err := service.NewChannelService(db).ReencryptCredentials()
+		if err != nil {
+			return fmt.Errorf("重新加密失败: %w", err)
+		}
+		fmt.Printf("已重新加密%d个通道的凭证\n", count)
+		count, err = service.NewTopicService(db).ReencryptSecrets()
+		if err != nil {
+			return fmt.Errorf("重新加密失败: %w", err)
+		}
+		fmt.Printf("已重新加密%d个主题的签名密钥\n", count)
+		return nil
+	default:
+		return fmt.Errorf("未知命令: %s\n%s", args[0], usage)
+	}
+}
+
+// runMigrate 执行 migrate 子命令
+func runMigrate(db *gorm.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	m := migration.New(db)
+	switch args[0] {
+	case "up":
+		done, err := m.Up()
+		for _, mig := range done {
+			fmt.Printf("已执行迁移 %d: %s\n", mig.Version, mig.Description)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Println("没有需要执行的迁移")
+		}
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("回滚步数格式错误: %s", args[1])
+			}
+			steps = n
+		}
+		done, err := m.Down(steps)
+		for _, mig := range done {
+			fmt.Printf("已回滚迁移 %d: %s\n", mig.Version, mig.Description)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Println("没有可以回滚的迁移")
+		}
+		return nil
+
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "版本\t状态\t执行时间\t说明")
+		for _, s := range statuses {
+			state, appliedAt := "未执行", "-"
+			if s.AppliedAt != nil {
+				state, appliedAt = "已执行", s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, state, appliedAt, s.Description)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("未知的迁移命令: %s\n%s", args[0], usage)
+	}
+}
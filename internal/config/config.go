@@ -11,26 +11,36 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Log      LogConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	JWT        JWTConfig
+	Log        LogConfig
+	Dispatcher DispatcherConfig
+	Webhook    WebhookConfig
+	Security   SecurityConfig
+	Metrics    MetricsConfig
+	Health     HealthConfig
 }
 
 type ServerConfig struct {
-	Port int
-	Mode string
+	Port            int
+	Mode            string
+	ShutdownTimeout int `mapstructure:"shutdown_timeout"` // 停止时等待HTTP请求（包括同步Webhook）处理完成的最长时间（秒）
 }
 
 type DatabaseConfig struct {
+	Driver       string // mysql（默认）、postgres、sqlite
+	DSN          string // 可选，设置后忽略下面的连接参数
 	Host         string
 	Port         int
 	User         string
 	Password     string
 	DBName       string
-	Charset      string
-	MaxOpenConns int `mapstructure:"max_open_conns"`
-	MaxIdleConns int `mapstructure:"max_idle_conns"`
+	Charset      string // MySQL字符集
+	SSLMode      string `mapstructure:"ssl_mode"` // PostgreSQL sslmode，默认disable
+	MaxOpenConns int    `mapstructure:"max_open_conns"`
+	MaxIdleConns int    `mapstructure:"max_idle_conns"`
+	AutoMigrate  bool   `mapstructure:"auto_migrate"` // 启动时自动执行未执行的数据库迁移
 }
 
 type JWTConfig struct {
@@ -43,6 +53,48 @@ type LogConfig struct {
 	Path  string
 }
 
+// DispatcherConfig 异步消息分发配置
+type DispatcherConfig struct {
+	Workers          int `mapstructure:"workers"`           // 工作协程数量
+	QueueSize        int `mapstructure:"queue_size"`        // 内存队列容量
+	TopicConcurrency int `mapstructure:"topic_concurrency"` // 单个主题最大并发数，0表示不限制
+	PollInterval     int `mapstructure:"poll_interval"`     // 轮询数据库间隔（秒）
+	BatchSize        int `mapstructure:"batch_size"`        // 每次轮询最多拉取的消息数
+	ProcessingLease  int `mapstructure:"processing_lease"`  // 处理中消息的租约（秒），超过租约未续期的消息视为处理实例已退出，重置为待处理
+	ShutdownTimeout  int `mapstructure:"shutdown_timeout"`  // 停止时在HTTP服务停止后等待消息投递完成的最长时间（秒）
+}
+
+// WebhookConfig Webhook接收配置
+type WebhookConfig struct {
+	SignatureTolerance int `mapstructure:"signature_tolerance"` // 签名时间戳允许的偏差（秒）
+	NonceWindow        int `mapstructure:"nonce_window"`        // nonce保留时间（秒），用于防止重放
+}
+
+// SecurityConfig 通道凭证加密配置，未配置主密钥时凭证以明文保存
+type SecurityConfig struct {
+	MasterKeys map[string]string `mapstructure:"master_keys"` // 主密钥版本号 -> base64编码的32字节密钥
+	KeyFile    string            `mapstructure:"key_file"`    // 密钥文件，每行 "版本号:base64密钥"
+	ActiveKey  int               `mapstructure:"active_key"`  // 加密使用的主密钥版本，0表示最大版本
+}
+
+// MetricsConfig Prometheus指标配置
+// 按主题、通道区分的指标会随数量增长，可关闭或限制取值数以控制序列数量
+type MetricsConfig struct {
+	Enabled        bool   `mapstructure:"enabled"`
+	Path           string `mapstructure:"path"`             // 指标路径，默认 /metrics
+	Token          string `mapstructure:"token"`            // 可选，设置后抓取时需携带 Authorization: Bearer <token>
+	TopicLabel     bool   `mapstructure:"topic_label"`      // 是否按主题ID区分
+	ChannelLabel   bool   `mapstructure:"channel_label"`    // 是否按通道ID区分
+	MaxLabelValues int    `mapstructure:"max_label_values"` // 每个标签最多区分的取值数，超出的归入 other，0表示不限制
+}
+
+// HealthConfig 健康检查配置
+type HealthConfig struct {
+	MaxBacklog           int `mapstructure:"max_backlog"`            // 待处理消息超过该数量时就绪检查失败，0表示不检查
+	ChannelCheckTTL      int `mapstructure:"channel_check_ttl"`      // 通道检查结果缓存时间（秒）
+	ChannelCheckInterval int `mapstructure:"channel_check_interval"` // 后台定时检查所有通道的间隔（秒），0表示不定时检查
+}
+
 var GlobalConfig Config
 
 func InitConfig(configPath string) {
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"synapse/internal/model"
+	"synapse/internal/service"
+	"synapse/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyController struct {
+	apiKeyService *service.APIKeyService
+}
+
+func NewAPIKeyController(apiKeyService *service.APIKeyService) *APIKeyController {
+	return &APIKeyController{apiKeyService: apiKeyService}
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,min=1,max=255"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// CreateAPIKeyResponse 创建API密钥的响应，key 只在创建时返回一次
+type CreateAPIKeyResponse struct {
+	*model.APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKey 创建API密钥
+// @Summary 创建API密钥
+// @Description 创建用于自动化访问的长期API密钥，明文密钥只在响应中返回一次
+// @Tags API密钥
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body CreateAPIKeyRequest true "密钥信息"
+// @Success 201 {object} CreateAPIKeyResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /api-keys [post]
+func (c *APIKeyController) CreateAPIKey(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "参数错误", err.Error())
+		return
+	}
+
+	key := &model.APIKey{
+		UserID:    userID.(uint64),
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	plaintext, err := c.apiKeyService.CreateAPIKey(key)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "创建API密钥失败", err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, CreateAPIKeyResponse{APIKey: key, Key: plaintext})
+}
+
+// GetAPIKeys 获取用户的所有API密钥
+// @Summary 获取API密钥列表
+// @Description 获取当前用户的所有API密钥，不包含明文密钥
+// @Tags API密钥
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} model.APIKey
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /api-keys [get]
+func (c *APIKeyController) GetAPIKeys(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	keys, err := c.apiKeyService.GetAPIKeysByUserID(userID.(uint64))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "获取API密钥列表失败", err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// DeleteAPIKey 吊销API密钥
+// @Summary 吊销API密钥
+// @Description 吊销指定的API密钥，吊销后立即失效
+// @Tags API密钥
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "密钥ID"
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /api-keys/{id} [delete]
+func (c *APIKeyController) DeleteAPIKey(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的密钥ID", err.Error())
+		return
+	}
+
+	if err := c.apiKeyService.DeleteAPIKey(id, userID.(uint64)); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "吊销API密钥失败", err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -21,6 +21,7 @@ func NewChannelController(channelService *service.ChannelService) *ChannelContro
 }
 
 type CreateChannelRequest struct {
+	OrgID       uint64                 `json:"orgId"` // 所属组织，为空时使用个人组织
 	Name        string                 `json:"name" binding:"required,min=1,max=255"`
 	Type        string                 `json:"type" binding:"required"`
 	Credentials map[string]interface{} `json:"credentials" binding:"required"`
@@ -52,27 +53,29 @@ func (c *ChannelController) CreateChannel(ctx *gin.Context) {
 	}
 
 	channel := &model.Channel{
-		UserID:      userID.(uint64),
+		OrgID:       req.OrgID,
 		Name:        req.Name,
 		Type:        req.Type,
 		Credentials: model.JSON(req.Credentials),
 	}
 
-	if err := c.channelService.CreateChannel(channel); err != nil {
+	if err := c.channelService.CreateChannel(channel, userID.(uint64)); err != nil {
 		utils.ErrorResponse(ctx, http.StatusBadRequest, "创建通道失败", err.Error())
 		return
 	}
 
+	service.MaskCredentials(channel)
 	ctx.JSON(http.StatusCreated, channel)
 }
 
 // GetChannels 获取用户的所有通道
 // @Summary 获取用户通道列表
-// @Description 获取当前用户的所有通知通道
+// @Description 获取当前用户所在组织的所有通知通道
 // @Tags 通道
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
+// @Param orgId query int false "组织ID"
 // @Success 200 {array} model.Channel
 // @Failure 401 {object} utils.ErrorResponse
 // @Router /channels [get]
@@ -83,12 +86,21 @@ func (c *ChannelController) GetChannels(ctx *gin.Context) {
 		return
 	}
 
-	channels, err := c.channelService.GetChannelsByUserID(userID.(uint64))
+	orgID, err := parseOrgIDQuery(ctx)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的组织ID", err.Error())
+		return
+	}
+
+	channels, err := c.channelService.GetChannels(userID.(uint64), orgID)
 	if err != nil {
 		utils.ErrorResponse(ctx, http.StatusInternalServerError, "获取通道列表失败", err.Error())
 		return
 	}
 
+	for i := range channels {
+		service.MaskCredentials(&channels[i])
+	}
 	ctx.JSON(http.StatusOK, channels)
 }
 
@@ -125,6 +137,7 @@ func (c *ChannelController) GetChannel(ctx *gin.Context) {
 		return
 	}
 
+	service.MaskCredentials(channel)
 	ctx.JSON(http.StatusOK, channel)
 }
 
@@ -181,6 +194,7 @@ func (c *ChannelController) UpdateChannel(ctx *gin.Context) {
 		return
 	}
 
+	service.MaskCredentials(channel)
 	ctx.JSON(http.StatusOK, channel)
 }
 
@@ -219,88 +233,96 @@ func (c *ChannelController) DeleteChannel(ctx *gin.Context) {
 	ctx.Status(http.StatusNoContent)
 }
 
-// TestTelegramChannel 测试 Telegram 通道
-func TestTelegramChannel(ctx *gin.Context) {
-	type Req struct {
-		BotToken string `json:"botToken" binding:"required"`
-		ChatID   string `json:"chatId" binding:"required"`
-		Proxy    string `json:"proxy"`
-		Content  string `json:"content" binding:"required"`
-	}
-	var req Req
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(ctx, 400, "参数错误", err.Error())
+// GetChannelTypes 获取支持的通道类型
+// @Summary 获取通道类型列表
+// @Description 获取已注册的通道类型及其凭证字段描述
+// @Tags 通道
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} notifier.Schema
+// @Router /channels/types [get]
+func (c *ChannelController) GetChannelTypes(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, notifier.Schemas())
+}
+
+type TestChannelRequest struct {
+	Title   string `json:"title"`
+	Content string `json:"content" binding:"required"`
+}
+
+// TestChannelByID 使用已保存的凭证测试通道
+// @Summary 测试通道
+// @Description 使用通道已保存的凭证发送测试消息
+// @Tags 通道
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "通道ID"
+// @Param data body TestChannelRequest true "测试消息"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /channels/{id}/test [post]
+func (c *ChannelController) TestChannelByID(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
 		return
 	}
-	err := notifier.SendTelegramMessage(model.TelegramConfig{
-		BotToken: req.BotToken,
-		ChatID:   req.ChatID,
-		Proxy:    req.Proxy,
-	}, req.Content)
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
 	if err != nil {
-		utils.ErrorResponse(ctx, 500, "发送失败", err.Error())
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的通道ID", err.Error())
 		return
 	}
-	ctx.JSON(200, gin.H{"message": "发送成功"})
-}
 
-// TestEmailChannel 测试 Email 通道
-func TestEmailChannel(ctx *gin.Context) {
-	type Req struct {
-		SMTPHost     string `json:"smtpHost"`
-		SMTPPort     int    `json:"smtpPort"`
-		SMTPUsername string `json:"smtpUsername"`
-		SMTPPassword string `json:"smtpPassword"`
-		Sender       string `json:"sender"`
-		To           string `json:"to"`
-		Proxy        string `json:"proxy"`
-		Title        string `json:"title"`
-		Content      string `json:"content"`
-	}
-	var req Req
+	var req TestChannelRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(ctx, 400, "参数错误", err.Error())
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "参数错误", err.Error())
 		return
 	}
-	err := notifier.SendEmail(notifier.EmailConfig{
-		Host:     req.SMTPHost,
-		Port:     req.SMTPPort,
-		Username: req.SMTPUsername,
-		Password: req.SMTPPassword,
-		From:     req.Sender,
-		To:       req.To,
-		Proxy:    req.Proxy,
-	}, req.Title, req.Content)
+
+	res, err := c.channelService.TestChannel(ctx.Request.Context(), id, userID.(uint64), req.Title, req.Content)
 	if err != nil {
-		utils.ErrorResponse(ctx, 500, "发送失败", err.Error())
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "发送失败", err.Error())
 		return
 	}
-	ctx.JSON(200, gin.H{"message": "发送成功"})
+	ctx.JSON(http.StatusOK, gin.H{"message": "发送成功", "response": res.Response})
 }
 
-// TestWebhookChannel 测试 Webhook 通道
-func TestWebhookChannel(ctx *gin.Context) {
-	type Req struct {
-		URL     string            `json:"url"`
-		Method  string            `json:"method"`
-		Headers map[string]string `json:"headers"`
-		Proxy   string            `json:"proxy"`
-		Content string            `json:"content"`
+// TestChannel 使用请求中的凭证测试通道
+// 请求体为通道凭证字段，另加 content（必填）和 title
+func TestChannel(ctx *gin.Context) {
+	n, ok := notifier.Get(ctx.Param("type"))
+	if !ok {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "参数错误", "不支持的通道类型")
+		return
 	}
-	var req Req
+
+	var req map[string]interface{}
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(ctx, 400, "参数错误", err.Error())
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "参数错误", err.Error())
+		return
+	}
+	content, _ := req["content"].(string)
+	title, _ := req["title"].(string)
+	if content == "" {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "参数错误", "content不能为空")
+		return
+	}
+	delete(req, "content")
+	delete(req, "title")
+
+	credentials := notifier.Credentials(req)
+	if err := n.Validate(credentials); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "参数错误", err.Error())
 		return
 	}
-	resp, err := notifier.SendWebhook(notifier.WebhookConfig{
-		URL:     req.URL,
-		Method:  req.Method,
-		Headers: req.Headers,
-		Proxy:   req.Proxy,
-	}, []byte(req.Content))
+	res, err := n.Test(ctx.Request.Context(), credentials, title, content)
 	if err != nil {
-		utils.ErrorResponse(ctx, 500, "发送失败", err.Error())
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "发送失败", err.Error())
 		return
 	}
-	ctx.JSON(200, gin.H{"message": "发送成功", "response": resp})
+	ctx.JSON(http.StatusOK, gin.H{"message": "发送成功", "response": res.Response})
 }
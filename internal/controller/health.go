@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"synapse/internal/dispatcher"
+	"synapse/internal/service"
+	"synapse/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout 就绪检查的超时时间
+const readinessTimeout = 3 * time.Second
+
+type HealthController struct {
+	healthService        *service.HealthService
+	channelHealthService *service.ChannelHealthService
+	dispatcher           *dispatcher.Dispatcher
+}
+
+func NewHealthController(healthService *service.HealthService, channelHealthService *service.ChannelHealthService, dispatcher *dispatcher.Dispatcher) *HealthController {
+	return &HealthController{
+		healthService:        healthService,
+		channelHealthService: channelHealthService,
+		dispatcher:           dispatcher,
+	}
+}
+
+// Healthz 存活检查
+// @Summary 存活检查
+// @Description 进程能处理请求即返回200，用于存活探针
+// @Tags 健康检查
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /healthz [get]
+func (c *HealthController) Healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz 就绪检查
+// @Summary 就绪检查
+// @Description 检查数据库连接和待处理消息积压，任一检查失败时返回503，用于就绪探针
+// @Tags 健康检查
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /readyz [get]
+func (c *HealthController) Readyz(ctx *gin.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	checks, ready := c.healthService.CheckReadiness(checkCtx)
+	response := gin.H{
+		"status":     "ok",
+		"checks":     checks,
+		"queueDepth": c.dispatcher.QueueDepth(),
+		"processing": c.dispatcher.Processing(),
+	}
+	if !ready {
+		response["status"] = "error"
+		ctx.JSON(http.StatusServiceUnavailable, response)
+		return
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// GetChannelHealth 获取通道检查结果
+// @Summary 获取通道检查结果
+// @Description 检查当前用户所在组织的通道凭证是否可用，结果会被缓存，refresh=true时重新检查
+// @Tags 通道
+// @Produce json
+// @Security ApiKeyAuth
+// @Param orgId query int false "组织ID"
+// @Param refresh query bool false "忽略缓存重新检查"
+// @Success 200 {array} service.ChannelHealth
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /channels/health [get]
+func (c *HealthController) GetChannelHealth(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	orgID, err := parseOrgIDQuery(ctx)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的组织ID", err.Error())
+		return
+	}
+	refresh, _ := strconv.ParseBool(ctx.Query("refresh"))
+
+	results, err := c.channelHealthService.GetChannelHealth(ctx.Request.Context(), userID.(uint64), orgID, refresh)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "获取通道检查结果失败", err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, results)
+}
+
+// CheckChannel 获取单个通道的检查结果
+// @Summary 检查通道
+// @Description 检查通道凭证是否可用（不发送消息），结果会被缓存，refresh=true时重新检查
+// @Tags 通道
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "通道ID"
+// @Param refresh query bool false "忽略缓存重新检查"
+// @Success 200 {object} service.ChannelHealth
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /channels/{id}/health [get]
+func (c *HealthController) CheckChannel(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的通道ID", err.Error())
+		return
+	}
+	refresh, _ := strconv.ParseBool(ctx.Query("refresh"))
+
+	health, err := c.channelHealthService.CheckChannel(ctx.Request.Context(), id, userID.(uint64), refresh)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "通道不存在", err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, health)
+}
@@ -0,0 +1,325 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"synapse/internal/dispatcher"
+	"synapse/internal/repository"
+	"synapse/internal/service"
+	"synapse/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MessageController struct {
+	messageService *service.MessageService
+	dispatcher     *dispatcher.Dispatcher
+}
+
+func NewMessageController(messageService *service.MessageService, dispatcher *dispatcher.Dispatcher) *MessageController {
+	return &MessageController{
+		messageService: messageService,
+		dispatcher:     dispatcher,
+	}
+}
+
+// GetMessages 获取消息列表
+// @Summary 获取消息列表
+// @Description 获取当前用户所在组织的主题下的消息，可按主题、状态和接收时间过滤
+// @Tags 消息
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param topicId query int false "主题ID"
+// @Param status query string false "消息状态"
+// @Param since query string false "起始时间(RFC3339)"
+// @Param until query string false "截止时间(RFC3339)"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} utils.PageResponse
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /messages [get]
+func (c *MessageController) GetMessages(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	filter, err := parseMessageFilter(ctx)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "参数错误", err.Error())
+		return
+	}
+
+	page, pageSize := parsePagination(ctx)
+	messages, total, err := c.messageService.GetMessages(userID.(uint64), filter, page, pageSize)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "获取消息列表失败", err.Error())
+		return
+	}
+
+	utils.PageResponseSuccess(ctx, messages, newPagination(page, pageSize, total))
+}
+
+// GetMessage 获取消息详情
+// @Summary 获取消息详情
+// @Description 获取消息内容及其投递日志
+// @Tags 消息
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "消息ID"
+// @Success 200 {object} service.MessageDetail
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /messages/{id} [get]
+func (c *MessageController) GetMessage(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的消息ID", err.Error())
+		return
+	}
+
+	detail, err := c.messageService.GetMessageDetail(id, userID.(uint64))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "获取消息失败", err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, detail)
+}
+
+// ReplayMessage 重放消息
+// @Summary 重放消息
+// @Description 将已处理的消息重新置为待处理，按当前路由重新投递
+// @Tags 消息
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "消息ID"
+// @Success 200 {object} model.Message
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Router /messages/{id}/replay [post]
+func (c *MessageController) ReplayMessage(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的消息ID", err.Error())
+		return
+	}
+
+	message, err := c.messageService.ReplayMessage(id, userID.(uint64))
+	if errors.Is(err, service.ErrMessageConflict) {
+		utils.ErrorResponse(ctx, http.StatusConflict, "重放失败", err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "重放失败", err.Error())
+		return
+	}
+
+	c.dispatcher.Enqueue(message)
+	ctx.JSON(http.StatusOK, message)
+}
+
+type ReplayMessagesRequest struct {
+	TopicID uint64     `json:"topicId"`
+	Status  string     `json:"status"`
+	Since   *time.Time `json:"since"`
+	Until   *time.Time `json:"until"`
+	Limit   int        `json:"limit"`
+}
+
+// ReplayMessages 批量重放消息
+// @Summary 批量重放消息
+// @Description 按条件批量重放已处理的消息，单次最多1000条
+// @Tags 消息
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body ReplayMessagesRequest true "过滤条件"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /messages/replay [post]
+func (c *MessageController) ReplayMessages(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	var req ReplayMessagesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "参数错误", err.Error())
+		return
+	}
+
+	messages, err := c.messageService.ReplayMessages(userID.(uint64), repository.MessageFilter{
+		TopicID: req.TopicID,
+		Status:  req.Status,
+		Since:   req.Since,
+		Until:   req.Until,
+	}, req.Limit)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "重放失败", err.Error())
+		return
+	}
+
+	// 队列已满时由分发器轮询补齐
+	ids := make([]uint64, 0, len(messages))
+	for i := range messages {
+		c.dispatcher.Enqueue(&messages[i])
+		ids = append(ids, messages[i].ID)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"count": len(ids), "messageIds": ids})
+}
+
+// GetDeadMessages 获取死信消息列表
+// @Summary 获取死信消息列表
+// @Description 获取当前用户所在组织的主题下重试耗尽的消息
+// @Tags 消息
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} utils.PageResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /messages/dead [get]
+func (c *MessageController) GetDeadMessages(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	page, pageSize := parsePagination(ctx)
+	messages, total, err := c.messageService.GetDeadMessages(userID.(uint64), page, pageSize)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "获取死信消息失败", err.Error())
+		return
+	}
+
+	utils.PageResponseSuccess(ctx, messages, newPagination(page, pageSize, total))
+}
+
+// RedriveMessage 重新投递死信消息
+// @Summary 重新投递死信消息
+// @Description 将死信消息重新置为待处理并交给分发器投递
+// @Tags 消息
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "消息ID"
+// @Success 200 {object} model.Message
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Router /messages/{id}/redrive [post]
+func (c *MessageController) RedriveMessage(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的消息ID", err.Error())
+		return
+	}
+
+	message, err := c.messageService.RedriveMessage(id, userID.(uint64))
+	if errors.Is(err, service.ErrMessageConflict) {
+		utils.ErrorResponse(ctx, http.StatusConflict, "重新投递失败", err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "重新投递失败", err.Error())
+		return
+	}
+
+	c.dispatcher.Enqueue(message)
+	ctx.JSON(http.StatusOK, message)
+}
+
+// parseMessageFilter 解析消息查询条件
+func parseMessageFilter(ctx *gin.Context) (repository.MessageFilter, error) {
+	filter := repository.MessageFilter{
+		Status: ctx.Query("status"),
+	}
+
+	if topicIDStr := ctx.Query("topicId"); topicIDStr != "" {
+		topicID, err := strconv.ParseUint(topicIDStr, 10, 64)
+		if err != nil {
+			return filter, errors.New("无效的主题ID")
+		}
+		filter.TopicID = topicID
+	}
+	if since := ctx.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return filter, errors.New("since必须是RFC3339格式的时间")
+		}
+		filter.Since = &t
+	}
+	if until := ctx.Query("until"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return filter, errors.New("until必须是RFC3339格式的时间")
+		}
+		filter.Until = &t
+	}
+
+	return filter, nil
+}
+
+// parsePagination 解析分页参数
+func parsePagination(ctx *gin.Context) (int, int) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("pageSize", "20"))
+	if err != nil || pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+	return page, pageSize
+}
+
+// newPagination 构造分页信息
+func newPagination(page, pageSize int, total int64) utils.Pagination {
+	return utils.Pagination{
+		Page:       page,
+		PageSize:   pageSize,
+		TotalCount: int(total),
+		TotalPages: int((total + int64(pageSize) - 1) / int64(pageSize)),
+	}
+}
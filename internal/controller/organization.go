@@ -0,0 +1,342 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"synapse/internal/model"
+	"synapse/internal/service"
+	"synapse/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrganizationController struct {
+	orgService *service.OrganizationService
+}
+
+func NewOrganizationController(orgService *service.OrganizationService) *OrganizationController {
+	return &OrganizationController{orgService: orgService}
+}
+
+type OrganizationRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=255"`
+}
+
+type AddMemberRequest struct {
+	Username string `json:"username" binding:"required"`
+	Role     string `json:"role" binding:"required"`
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// CreateOrganization 创建组织
+// @Summary 创建组织
+// @Description 创建新的组织，创建者成为所有者
+// @Tags 组织
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body OrganizationRequest true "组织信息"
+// @Success 201 {object} model.Organization
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /orgs [post]
+func (c *OrganizationController) CreateOrganization(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	var req OrganizationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "参数错误", err.Error())
+		return
+	}
+
+	org := &model.Organization{Name: req.Name}
+	if err := c.orgService.CreateOrganization(org, userID.(uint64)); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "创建组织失败", err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, org)
+}
+
+// GetOrganizations 获取用户所属的组织
+// @Summary 获取组织列表
+// @Description 获取当前用户所属的所有组织及其角色
+// @Tags 组织
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} service.OrganizationInfo
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /orgs [get]
+func (c *OrganizationController) GetOrganizations(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	orgs, err := c.orgService.GetOrganizations(userID.(uint64))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "获取组织列表失败", err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, orgs)
+}
+
+// GetOrganization 获取组织详情
+// @Summary 获取组织详情
+// @Description 获取组织信息及成员列表
+// @Tags 组织
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "组织ID"
+// @Success 200 {object} service.OrganizationDetail
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /orgs/{id} [get]
+func (c *OrganizationController) GetOrganization(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的组织ID", err.Error())
+		return
+	}
+
+	detail, err := c.orgService.GetOrganization(id, userID.(uint64))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "组织不存在", err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, detail)
+}
+
+// UpdateOrganization 更新组织
+// @Summary 更新组织
+// @Description 修改组织名称，需要管理员角色
+// @Tags 组织
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "组织ID"
+// @Param data body OrganizationRequest true "组织信息"
+// @Success 200 {object} model.Organization
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /orgs/{id} [put]
+func (c *OrganizationController) UpdateOrganization(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的组织ID", err.Error())
+		return
+	}
+
+	var req OrganizationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "参数错误", err.Error())
+		return
+	}
+
+	org := &model.Organization{ID: id, Name: req.Name}
+	if err := c.orgService.UpdateOrganization(org, userID.(uint64)); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "更新组织失败", err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, org)
+}
+
+// DeleteOrganization 删除组织
+// @Summary 删除组织
+// @Description 删除没有主题和通道的组织，需要所有者角色
+// @Tags 组织
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "组织ID"
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /orgs/{id} [delete]
+func (c *OrganizationController) DeleteOrganization(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的组织ID", err.Error())
+		return
+	}
+
+	if err := c.orgService.DeleteOrganization(id, userID.(uint64)); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "删除组织失败", err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// AddMember 添加组织成员
+// @Summary 添加组织成员
+// @Description 按用户名添加成员，需要管理员角色，添加所有者需要所有者角色
+// @Tags 组织
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "组织ID"
+// @Param data body AddMemberRequest true "成员信息"
+// @Success 201 {object} model.OrganizationMember
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /orgs/{id}/members [post]
+func (c *OrganizationController) AddMember(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的组织ID", err.Error())
+		return
+	}
+
+	var req AddMemberRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "参数错误", err.Error())
+		return
+	}
+
+	member, err := c.orgService.AddMember(id, req.Username, req.Role, userID.(uint64))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "添加成员失败", err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, member)
+}
+
+// UpdateMember 修改成员角色
+// @Summary 修改成员角色
+// @Description 修改组织成员的角色，涉及所有者角色时需要所有者权限
+// @Tags 组织
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "组织ID"
+// @Param user_id path int true "用户ID"
+// @Param data body UpdateMemberRequest true "角色"
+// @Success 200 {object} model.OrganizationMember
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /orgs/{id}/members/{user_id} [put]
+func (c *OrganizationController) UpdateMember(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	id, memberID, err := parseMemberParams(ctx)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "参数错误", err.Error())
+		return
+	}
+
+	var req UpdateMemberRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "参数错误", err.Error())
+		return
+	}
+
+	member, err := c.orgService.UpdateMember(id, memberID, req.Role, userID.(uint64))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "修改成员角色失败", err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, member)
+}
+
+// RemoveMember 移除组织成员
+// @Summary 移除组织成员
+// @Description 移除组织成员，成员可以移除自己以退出组织
+// @Tags 组织
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "组织ID"
+// @Param user_id path int true "用户ID"
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /orgs/{id}/members/{user_id} [delete]
+func (c *OrganizationController) RemoveMember(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	id, memberID, err := parseMemberParams(ctx)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "参数错误", err.Error())
+		return
+	}
+
+	if err := c.orgService.RemoveMember(id, memberID, userID.(uint64)); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "移除成员失败", err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// parseMemberParams 解析路径中的组织ID和成员用户ID
+func parseMemberParams(ctx *gin.Context) (uint64, uint64, error) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("无效的组织ID")
+	}
+	memberID, err := strconv.ParseUint(ctx.Param("user_id"), 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("无效的用户ID")
+	}
+	return id, memberID, nil
+}
+
+// parseOrgIDQuery 解析查询参数中的组织ID，未指定时返回0
+func parseOrgIDQuery(ctx *gin.Context) (uint64, error) {
+	orgIDStr := ctx.Query("orgId")
+	if orgIDStr == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(orgIDStr, 10, 64)
+}
@@ -19,6 +19,23 @@ func NewRoutingController(routingService *service.RoutingService) *RoutingContro
 	return &RoutingController{routingService: routingService}
 }
 
+// RetryPolicy 路由重试策略参数，未填写时使用默认值
+type RetryPolicy struct {
+	MaxAttempts       int      `json:"maxAttempts"`
+	RetryInitialDelay int      `json:"retryInitialDelay"`
+	RetryMaxDelay     int      `json:"retryMaxDelay"`
+	RetryMultiplier   float64  `json:"retryMultiplier"`
+	RetryJitter       *float64 `json:"retryJitter"` // 未填写时为默认值，0表示不抖动
+}
+
+// jitter 返回重试延迟抖动比例，未填写时使用默认值
+func (p RetryPolicy) jitter() float64 {
+	if p.RetryJitter == nil {
+		return service.DefaultRetryJitter
+	}
+	return *p.RetryJitter
+}
+
 type CreateRoutingRequest struct {
 	TopicID          uint64                 `json:"topicId" binding:"required"`
 	ChannelID        uint64                 `json:"channelId" binding:"required"`
@@ -26,6 +43,10 @@ type CreateRoutingRequest struct {
 	VariableMappings map[string]interface{} `json:"variableMappings"`
 	MessageTemplate  string                 `json:"messageTemplate"`
 	SubjectTemplate  string                 `json:"subjectTemplate"`
+	HTMLTemplate     string                 `json:"htmlTemplate"` // 邮件HTML正文模板
+	Options          map[string]interface{} `json:"options"`
+	Condition        string                 `json:"condition"`
+	RetryPolicy
 }
 
 // CreateRouting 创建路由
@@ -54,12 +75,20 @@ func (c *RoutingController) CreateRouting(ctx *gin.Context) {
 	}
 
 	routing := &model.Routing{
-		TopicID:          req.TopicID,
-		ChannelID:        req.ChannelID,
-		Priority:         req.Priority,
-		VariableMappings: model.JSON(req.VariableMappings),
-		MessageTemplate:  req.MessageTemplate,
-		SubjectTemplate:  req.SubjectTemplate,
+		TopicID:           req.TopicID,
+		ChannelID:         req.ChannelID,
+		Priority:          req.Priority,
+		VariableMappings:  model.JSON(req.VariableMappings),
+		MessageTemplate:   req.MessageTemplate,
+		SubjectTemplate:   req.SubjectTemplate,
+		HTMLTemplate:      req.HTMLTemplate,
+		Options:           model.JSON(req.Options),
+		Condition:         req.Condition,
+		MaxAttempts:       req.MaxAttempts,
+		RetryInitialDelay: req.RetryInitialDelay,
+		RetryMaxDelay:     req.RetryMaxDelay,
+		RetryMultiplier:   req.RetryMultiplier,
+		RetryJitter:       req.jitter(),
 	}
 
 	if err := c.routingService.CreateRouting(routing, userID.(uint64)); err != nil {
@@ -145,6 +174,10 @@ type UpdateRoutingRequest struct {
 	VariableMappings map[string]interface{} `json:"variableMappings"`
 	MessageTemplate  string                 `json:"messageTemplate"`
 	SubjectTemplate  string                 `json:"subjectTemplate"`
+	HTMLTemplate     string                 `json:"htmlTemplate"` // 邮件HTML正文模板
+	Options          map[string]interface{} `json:"options"`
+	Condition        string                 `json:"condition"`
+	RetryPolicy
 }
 
 // UpdateRouting 更新路由
@@ -190,12 +223,20 @@ func (c *RoutingController) UpdateRouting(ctx *gin.Context) {
 	}
 
 	routing := &model.Routing{
-		TopicID:          topicID,
-		ChannelID:        channelID,
-		Priority:         req.Priority,
-		VariableMappings: model.JSON(req.VariableMappings),
-		MessageTemplate:  req.MessageTemplate,
-		SubjectTemplate:  req.SubjectTemplate,
+		TopicID:           topicID,
+		ChannelID:         channelID,
+		Priority:          req.Priority,
+		VariableMappings:  model.JSON(req.VariableMappings),
+		MessageTemplate:   req.MessageTemplate,
+		SubjectTemplate:   req.SubjectTemplate,
+		HTMLTemplate:      req.HTMLTemplate,
+		Options:           model.JSON(req.Options),
+		Condition:         req.Condition,
+		MaxAttempts:       req.MaxAttempts,
+		RetryInitialDelay: req.RetryInitialDelay,
+		RetryMaxDelay:     req.RetryMaxDelay,
+		RetryMultiplier:   req.RetryMultiplier,
+		RetryJitter:       req.jitter(),
 	}
 
 	if err := c.routingService.UpdateRouting(routing, userID.(uint64)); err != nil {
@@ -248,3 +289,71 @@ func (c *RoutingController) DeleteRouting(ctx *gin.Context) {
 
 	ctx.Status(http.StatusNoContent)
 }
+
+type PreviewRoutingRequest struct {
+	Payload          map[string]interface{} `json:"payload" binding:"required"` // 示例消息内容
+	VariableMappings map[string]interface{} `json:"variableMappings"`           // 以下字段为空时使用已保存的路由配置
+	MessageTemplate  *string                `json:"messageTemplate"`
+	SubjectTemplate  *string                `json:"subjectTemplate"`
+	HTMLTemplate     *string                `json:"htmlTemplate"`
+	Options          map[string]interface{} `json:"options"`
+	Send             bool                   `json:"send"` // 是否实际发送
+}
+
+// PreviewRouting 预览路由
+// @Summary 预览路由渲染结果
+// @Description 使用示例内容解析变量映射并渲染主题和正文，返回模板错误；send为true时通过通道实际发送
+// @Tags 路由
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param topic_id path int true "主题ID"
+// @Param channel_id path int true "通道ID"
+// @Param data body PreviewRoutingRequest true "示例内容"
+// @Success 200 {object} service.RoutingPreview
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /routings/{topic_id}/{channel_id}/preview [post]
+func (c *RoutingController) PreviewRouting(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	topicIDStr := ctx.Param("topic_id")
+	topicID, err := strconv.ParseUint(topicIDStr, 10, 64)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的主题ID", err.Error())
+		return
+	}
+
+	channelIDStr := ctx.Param("channel_id")
+	channelID, err := strconv.ParseUint(channelIDStr, 10, 64)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的通道ID", err.Error())
+		return
+	}
+
+	var req PreviewRoutingRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "参数错误", err.Error())
+		return
+	}
+
+	preview, err := c.routingService.PreviewRouting(ctx.Request.Context(), topicID, channelID, userID.(uint64), &service.RoutingPreviewRequest{
+		Payload:          req.Payload,
+		VariableMappings: model.JSON(req.VariableMappings),
+		MessageTemplate:  req.MessageTemplate,
+		SubjectTemplate:  req.SubjectTemplate,
+		HTMLTemplate:     req.HTMLTemplate,
+		Options:          model.JSON(req.Options),
+		Send:             req.Send,
+	})
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "预览路由失败", err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, preview)
+}
@@ -0,0 +1,213 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"synapse/internal/model"
+	"synapse/internal/service"
+	"synapse/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TemplatePartialController struct {
+	partialService *service.TemplatePartialService
+}
+
+func NewTemplatePartialController(partialService *service.TemplatePartialService) *TemplatePartialController {
+	return &TemplatePartialController{partialService: partialService}
+}
+
+type CreatePartialRequest struct {
+	OrgID   uint64 `json:"orgId"` // 所属组织，为空时使用个人组织
+	Name    string `json:"name" binding:"required,max=100"`
+	Content string `json:"content" binding:"required"`
+}
+
+// CreatePartial 创建模板片段
+// @Summary 创建模板片段
+// @Description 创建组织内可复用的命名模板片段，路由模板中通过 {{template "名称" .}} 引用
+// @Tags 模板片段
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body CreatePartialRequest true "片段信息"
+// @Success 201 {object} model.TemplatePartial
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /partials [post]
+func (c *TemplatePartialController) CreatePartial(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	var req CreatePartialRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "参数错误", err.Error())
+		return
+	}
+
+	partial := &model.TemplatePartial{
+		OrgID:   req.OrgID,
+		Name:    req.Name,
+		Content: req.Content,
+	}
+	if err := c.partialService.CreatePartial(partial, userID.(uint64)); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "创建模板片段失败", err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, partial)
+}
+
+// GetPartials 获取模板片段列表
+// @Summary 获取模板片段列表
+// @Description 获取当前用户所在组织的所有模板片段
+// @Tags 模板片段
+// @Produce json
+// @Security ApiKeyAuth
+// @Param orgId query int false "组织ID"
+// @Success 200 {array} model.TemplatePartial
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /partials [get]
+func (c *TemplatePartialController) GetPartials(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	orgID, err := parseOrgIDQuery(ctx)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的组织ID", err.Error())
+		return
+	}
+
+	partials, err := c.partialService.GetPartials(userID.(uint64), orgID)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "获取模板片段列表失败", err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, partials)
+}
+
+// GetPartial 获取单个模板片段
+// @Summary 获取模板片段详情
+// @Description 根据ID获取模板片段
+// @Tags 模板片段
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "片段ID"
+// @Success 200 {object} model.TemplatePartial
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /partials/{id} [get]
+func (c *TemplatePartialController) GetPartial(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的片段ID", err.Error())
+		return
+	}
+
+	partial, err := c.partialService.GetPartialByID(id, userID.(uint64))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "模板片段不存在", err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, partial)
+}
+
+type UpdatePartialRequest struct {
+	Name    string `json:"name" binding:"required,max=100"`
+	Content string `json:"content" binding:"required"`
+}
+
+// UpdatePartial 更新模板片段
+// @Summary 更新模板片段
+// @Description 更新模板片段的名称和内容，重命名后引用旧名称的路由模板将渲染失败
+// @Tags 模板片段
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "片段ID"
+// @Param data body UpdatePartialRequest true "片段信息"
+// @Success 200 {object} model.TemplatePartial
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /partials/{id} [put]
+func (c *TemplatePartialController) UpdatePartial(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的片段ID", err.Error())
+		return
+	}
+
+	var req UpdatePartialRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "参数错误", err.Error())
+		return
+	}
+
+	partial := &model.TemplatePartial{
+		ID:      id,
+		Name:    req.Name,
+		Content: req.Content,
+	}
+	if err := c.partialService.UpdatePartial(partial, userID.(uint64)); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "更新模板片段失败", err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, partial)
+}
+
+// DeletePartial 删除模板片段
+// @Summary 删除模板片段
+// @Description 删除指定的模板片段
+// @Tags 模板片段
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "片段ID"
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /partials/{id} [delete]
+func (c *TemplatePartialController) DeletePartial(ctx *gin.Context) {
+	userID, exists := ctx.Get("userId")
+	if !exists {
+		utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", "用户信息不存在")
+		return
+	}
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的片段ID", err.Error())
+		return
+	}
+
+	if err := c.partialService.DeletePartial(id, userID.(uint64)); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "删除模板片段失败", err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -20,10 +20,15 @@ func NewTopicController(topicService *service.TopicService) *TopicController {
 }
 
 type CreateTopicRequest struct {
+	OrgID           uint64 `json:"orgId"` // 所属组织，为空时使用个人组织
 	Name            string `json:"name" binding:"required,min=1,max=255"`
 	SendingStrategy string `json:"sendingStrategy" binding:"required"`
 	ExecutionMode   string `json:"executionMode" binding:"required"`
 	Description     string `json:"description"`
+	DedupExpression string `json:"dedupExpression"`
+	DedupWindow     int    `json:"dedupWindow"`
+	SigningScheme   string `json:"signingScheme"`
+	SigningSecret   string `json:"signingSecret"`
 }
 
 // CreateTopic 创建主题
@@ -52,14 +57,18 @@ func (c *TopicController) CreateTopic(ctx *gin.Context) {
 	}
 
 	topic := &model.Topic{
-		UserID:          userID.(uint64),
+		OrgID:           req.OrgID,
 		Name:            req.Name,
 		SendingStrategy: req.SendingStrategy,
 		ExecutionMode:   req.ExecutionMode,
 		Description:     req.Description,
+		DedupExpression: req.DedupExpression,
+		DedupWindow:     req.DedupWindow,
+		SigningScheme:   req.SigningScheme,
+		SigningSecret:   req.SigningSecret,
 	}
 
-	if err := c.topicService.CreateTopic(topic); err != nil {
+	if err := c.topicService.CreateTopic(topic, userID.(uint64)); err != nil {
 		utils.ErrorResponse(ctx, http.StatusBadRequest, "创建主题失败", err.Error())
 		return
 	}
@@ -69,11 +78,12 @@ func (c *TopicController) CreateTopic(ctx *gin.Context) {
 
 // GetTopics 获取用户的所有主题
 // @Summary 获取主题列表
-// @Description 获取当前用户的所有主题
+// @Description 获取当前用户所在组织的所有主题
 // @Tags 主题
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
+// @Param orgId query int false "组织ID"
 // @Success 200 {array} model.Topic
 // @Failure 401 {object} utils.ErrorResponse
 // @Router /topics [get]
@@ -84,7 +94,13 @@ func (c *TopicController) GetTopics(ctx *gin.Context) {
 		return
 	}
 
-	topics, err := c.topicService.GetTopicsByUserID(userID.(uint64))
+	orgID, err := parseOrgIDQuery(ctx)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的组织ID", err.Error())
+		return
+	}
+
+	topics, err := c.topicService.GetTopics(userID.(uint64), orgID)
 	if err != nil {
 		utils.ErrorResponse(ctx, http.StatusInternalServerError, "获取主题列表失败", err.Error())
 		return
@@ -134,6 +150,10 @@ type UpdateTopicRequest struct {
 	SendingStrategy string `json:"sendingStrategy" binding:"required"`
 	ExecutionMode   string `json:"executionMode" binding:"required"`
 	Description     string `json:"description"`
+	DedupExpression string `json:"dedupExpression"`
+	DedupWindow     int    `json:"dedupWindow"`
+	SigningScheme   string `json:"signingScheme"`
+	SigningSecret   string `json:"signingSecret"`
 }
 
 // UpdateTopic 更新主题
@@ -177,6 +197,10 @@ func (c *TopicController) UpdateTopic(ctx *gin.Context) {
 		SendingStrategy: req.SendingStrategy,
 		ExecutionMode:   req.ExecutionMode,
 		Description:     req.Description,
+		DedupExpression: req.DedupExpression,
+		DedupWindow:     req.DedupWindow,
+		SigningScheme:   req.SigningScheme,
+		SigningSecret:   req.SigningSecret,
 	}
 
 	if err := c.topicService.UpdateTopic(topic, userID.(uint64)); err != nil {
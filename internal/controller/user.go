@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
 
 	"synapse/internal/model"
@@ -170,6 +171,7 @@ func (c *UserController) UpdateProfile(ctx *gin.Context) {
 // @Security ApiKeyAuth
 // @Success 204
 // @Failure 401 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
 // @Router /profile [delete]
 func (c *UserController) DeleteAccount(ctx *gin.Context) {
 	userID, exists := ctx.Get("userId")
@@ -179,6 +181,10 @@ func (c *UserController) DeleteAccount(ctx *gin.Context) {
 	}
 
 	if err := c.userService.DeleteUser(userID.(uint64)); err != nil {
+		if errors.Is(err, service.ErrSoleOwner) {
+			utils.ErrorResponse(ctx, http.StatusConflict, "删除失败", err.Error())
+			return
+		}
 		utils.ErrorResponse(ctx, http.StatusInternalServerError, "删除失败", err.Error())
 		return
 	}
@@ -1,24 +1,37 @@
 package controller
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
+	"synapse/internal/dispatcher"
+	"synapse/internal/metrics"
 	"synapse/internal/model"
 	"synapse/internal/service"
 	"synapse/internal/utils"
+	"synapse/pkg/signature"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 type WebhookController struct {
 	topicService   *service.TopicService
 	messageService *service.MessageService
+	dispatcher     *dispatcher.Dispatcher
+	verifier       *signature.Verifier
 }
 
-func NewWebhookController(topicService *service.TopicService, messageService *service.MessageService) *WebhookController {
+func NewWebhookController(topicService *service.TopicService, messageService *service.MessageService, dispatcher *dispatcher.Dispatcher, verifier *signature.Verifier) *WebhookController {
 	return &WebhookController{
 		topicService:   topicService,
 		messageService: messageService,
+		dispatcher:     dispatcher,
+		verifier:       verifier,
 	}
 }
 
@@ -48,44 +61,94 @@ func (c *WebhookController) ReceiveWebhook(ctx *gin.Context) {
 	}
 
 	// 读取请求体
+	body, err := ctx.GetRawData()
+	if err != nil {
+		metrics.WebhookReceived(topic.ID, "rejected")
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "读取请求体失败", err.Error())
+		return
+	}
+
+	// 校验签名
+	if topic.SigningScheme != "" {
+		scope := strconv.FormatUint(topic.ID, 10)
+		nonceKey, err := c.verifier.Verify(topic.SigningScheme, topic.SigningSecret, ctx.Request.Header, body, scope)
+		if err != nil {
+			if errors.Is(err, signature.ErrNonceStore) {
+				utils.ErrorResponse(ctx, http.StatusInternalServerError, "签名校验失败", err.Error())
+				return
+			}
+			metrics.WebhookReceived(topic.ID, "rejected")
+			utils.ErrorResponse(ctx, http.StatusUnauthorized, "签名校验失败", err.Error())
+			return
+		}
+		// 请求没有被接受时释放nonce，发送方重新投递同一请求（如GitHub的Redeliver）时不会被当作重放拒绝
+		defer func() {
+			if ctx.Writer.Status() >= http.StatusBadRequest {
+				if err := c.verifier.Release(nonceKey); err != nil {
+					zap.L().Error("释放Webhook nonce失败", zap.Uint64("topicId", topic.ID), zap.Error(err))
+				}
+			}
+		}()
+	}
+
 	var payload map[string]interface{}
-	if err := ctx.ShouldBindJSON(&payload); err != nil {
+	if err := json.Unmarshal(body, &payload); err != nil {
+		metrics.WebhookReceived(topic.ID, "rejected")
 		utils.ErrorResponse(ctx, http.StatusBadRequest, "无效的JSON格式", err.Error())
 		return
 	}
 
 	// 创建消息记录
+	// 同步模式的消息直接标记为处理中并记录租约时间，避免被分发器重复处理或当作过期消息恢复
 	message := &model.Message{
 		TopicID: topic.ID,
 		Content: model.JSON(payload),
 		Status:  "pending",
 	}
+	if topic.ExecutionMode == "sync" {
+		now := time.Now()
+		message.Status = "processing"
+		message.ClaimedAt = &now
+	}
 
-	if err := c.messageService.CreateMessage(message); err != nil {
+	// 去重：重试的请求返回原消息ID，不再重复发送
+	dedupKey := service.DedupKey(topic, ctx.GetHeader("Idempotency-Key"), body)
+	original, err := c.messageService.CreateMessage(topic, message, dedupKey)
+	if errors.Is(err, service.ErrDuplicateMessage) {
+		metrics.WebhookReceived(topic.ID, "duplicate")
+		ctx.JSON(http.StatusOK, map[string]interface{}{
+			"message_id": original.ID,
+			"status":     "duplicate",
+			"topic":      topic.Name,
+		})
+		return
+	}
+	if err != nil {
 		utils.ErrorResponse(ctx, http.StatusInternalServerError, "保存消息失败", err.Error())
 		return
 	}
+	metrics.WebhookReceived(topic.ID, "accepted")
+
+	// 返回成功响应
+	response := map[string]interface{}{
+		"message_id": message.ID,
+		"status":     "received",
+		"topic":      topic.Name,
+	}
 
 	// 根据执行模式处理消息
 	if topic.ExecutionMode == "sync" {
-		// 同步处理
-		if err := c.messageService.ProcessMessage(message.ID); err != nil {
+		// 同步处理，客户端断开连接不中断投递
+		// 消息已保存，投递失败进入死信时同样返回200，避免发送方重试产生重复消息；只有处理出错时返回500
+		processed, err := c.messageService.ProcessMessage(context.WithoutCancel(ctx.Request.Context()), message.ID)
+		if err != nil && (processed == nil || (processed.Status != "dead" && processed.Status != "failed")) {
 			utils.ErrorResponse(ctx, http.StatusInternalServerError, "处理消息失败", err.Error())
 			return
 		}
+		response["delivery_status"] = processed.Status
 	} else {
-		// 异步处理 - 这里可以发送到消息队列
-		// TODO: 实现异步消息处理
-		go func() {
-			c.messageService.ProcessMessage(message.ID)
-		}()
-	}
-
-	// 返回成功响应
-	response := map[string]interface{}{
-		"message_id": message.ID,
-		"status":     "received",
-		"topic":      topic.Name,
+		// 异步处理 - 交给分发器，队列已满时由分发器轮询补偿
+		c.dispatcher.Enqueue(message)
 	}
 
 	ctx.JSON(http.StatusOK, response)
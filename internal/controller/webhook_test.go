@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"synapse/internal/config"
+	"synapse/internal/dispatcher"
+	"synapse/internal/migration"
+	"synapse/internal/model"
+	"synapse/internal/repository"
+	"synapse/internal/service"
+	"synapse/pkg/signature"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB 打开执行过全部迁移的内存SQLite数据库
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if _, err := migration.New(db).Up(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestReceiveWebhookRetryAfterFailedSave(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := openTestDB(t)
+
+	const secret = "s3cret"
+	topic := &model.Topic{
+		UserID: 1, OrgID: 1, Name: "github", WebhookKey: "github-key",
+		SendingStrategy: "all", ExecutionMode: "async",
+		SigningScheme: signature.SchemeGitHub, SigningSecret: secret,
+	}
+	if err := db.Create(topic).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := signature.NewVerifier(5*time.Minute, 10*time.Minute, repository.NewWebhookNonceRepository(db))
+	c := NewWebhookController(service.NewTopicService(db), service.NewMessageService(db), dispatcher.NewDispatcher(db, config.DispatcherConfig{}), verifier)
+	r := gin.New()
+	r.POST("/webhook/:webhook_key", c.ReceiveWebhook)
+
+	body := []byte(`{"action":"deployed"}`)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	send := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/webhook/github-key", bytes.NewReader(body))
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		req.Header.Set("X-GitHub-Delivery", "delivery-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 第一次投递时保存消息失败
+	failSave := true
+	if err := db.Callback().Create().Before("gorm:create").Register("test:fail_message", func(tx *gorm.DB) {
+		if failSave && tx.Statement.Table == "messages" {
+			tx.AddError(errors.New("disk full"))
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if code := send(); code != http.StatusInternalServerError {
+		t.Fatalf("first delivery status = %d, want 500", code)
+	}
+
+	// 发送方重新投递同一请求（相同的X-GitHub-Delivery）应被接受
+	failSave = false
+	if code := send(); code != http.StatusOK {
+		t.Fatalf("redelivery status = %d, want 200", code)
+	}
+
+	// 已接受的请求再次投递按重放拒绝
+	if code := send(); code != http.StatusUnauthorized {
+		t.Fatalf("replayed delivery status = %d, want 401", code)
+	}
+
+	var count int64
+	if err := db.Model(&model.Message{}).Where("topic_id = ?", topic.ID).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("messages = %d, want 1", count)
+	}
+}
+
+func TestReceiveWebhookSyncDeadLetterReturnsOK(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := openTestDB(t)
+
+	topic := &model.Topic{UserID: 1, OrgID: 1, Name: "sync", WebhookKey: "sync-key", SendingStrategy: "all", ExecutionMode: "sync"}
+	if err := db.Create(topic).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 不支持的通道类型属于永久性错误，消息直接进入死信
+	channel := &model.Channel{UserID: 1, OrgID: 1, Name: "unknown", Type: "no-such-type", Credentials: model.JSON{}}
+	if err := db.Create(channel).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&model.Routing{OrgID: 1, TopicID: topic.ID, ChannelID: channel.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewWebhookController(service.NewTopicService(db), service.NewMessageService(db), dispatcher.NewDispatcher(db, config.DispatcherConfig{}), signature.NewVerifier(time.Minute, time.Minute, nil))
+	r := gin.New()
+	r.POST("/webhook/:webhook_key", c.ReceiveWebhook)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/sync-key", bytes.NewReader([]byte(`{"alert":"disk"}`)))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response["delivery_status"] != "dead" || response["message_id"] == nil {
+		t.Fatalf("response = %v, want delivery_status dead with message_id", response)
+	}
+}
@@ -0,0 +1,108 @@
+// Package database 按配置的驱动建立数据库连接，支持MySQL、PostgreSQL和SQLite
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"synapse/internal/config"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// 支持的数据库驱动
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// sqlitePragmas 开启外键约束和WAL，写锁冲突时等待而不是立即失败
+const sqlitePragmas = "_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_txlock=immediate"
+
+// Open 按配置连接数据库并设置连接池
+func Open(cfg config.DatabaseConfig, gormConfig *gorm.Config) (*gorm.DB, error) {
+	dialector, err := Dialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, gormConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	return db, nil
+}
+
+// Dialector 按驱动构造GORM方言，配置了dsn时直接使用
+func Dialector(cfg config.DatabaseConfig) (gorm.Dialector, error) {
+	switch driver(cfg) {
+	case DriverMySQL:
+		dsn := cfg.DSN
+		if dsn == "" {
+			charset := cfg.Charset
+			if charset == "" {
+				charset = "utf8mb4"
+			}
+			dsn = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=Local",
+				cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DBName, charset)
+		}
+		return mysql.Open(dsn), nil
+
+	case DriverPostgres:
+		dsn := cfg.DSN
+		if dsn == "" {
+			sslMode := cfg.SSLMode
+			if sslMode == "" {
+				sslMode = "disable"
+			}
+			dsn = fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+				cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, sslMode)
+		}
+		return postgres.Open(dsn), nil
+
+	case DriverSQLite:
+		dsn := cfg.DSN
+		if dsn == "" {
+			path := cfg.DBName
+			if path == "" {
+				path = "./storage/synapse.db"
+			}
+			if dir := filepath.Dir(path); dir != "." {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					return nil, err
+				}
+			}
+			dsn = path
+		}
+		if !strings.Contains(dsn, "?") {
+			dsn += "?" + sqlitePragmas
+		}
+		return sqlite.Open(dsn), nil
+	}
+	return nil, fmt.Errorf("不支持的数据库驱动: %s", cfg.Driver)
+}
+
+// driver 返回配置的驱动，未配置时为MySQL
+func driver(cfg config.DatabaseConfig) string {
+	if cfg.Driver == "" {
+		return DriverMySQL
+	}
+	return strings.ToLower(cfg.Driver)
+}
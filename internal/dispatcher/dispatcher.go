@@ -0,0 +1,369 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"synapse/internal/config"
+	"synapse/internal/model"
+	"synapse/internal/repository"
+	"synapse/internal/service"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// job 队列中的待处理消息
+type job struct {
+	messageID uint64
+	topicID   uint64
+}
+
+// Dispatcher 异步消息分发器
+//
+// 消息以pending状态持久化在数据库中，分发器通过有界内存队列和固定数量的工作协程处理消息。
+// 队列已满或主题并发达到上限时消息保持pending状态，由定时轮询补偿，因此重启后不会丢失消息。
+// 发送失败需要重试的消息以pending状态和下次投递时间保存，到期后再次拉取，工作协程不等待重试。
+// 停止时等待处理中的消息投递完成，超过期限则中断投递并将其重置为pending，下次启动后只投递剩余的通道。
+// 抢占消息时记录租约时间，每次轮询为正在投递的消息续期；实例异常退出时遗留的处理中消息在租约到期后
+// 由任一实例重置为pending，其他实例正在投递的消息和同步Webhook正在处理的消息不受影响。
+type Dispatcher struct {
+	cfg            config.DispatcherConfig
+	messageRepo    *repository.MessageRepository
+	messageService *service.MessageService
+
+	queue  chan job
+	wake   chan struct{}
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	ctx    context.Context // 投递上下文，停止超时后取消
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	queued     map[uint64]struct{} // 已入队或处理中的消息ID
+	running    map[uint64]int      // 各主题正在处理的消息数
+	processing map[uint64]struct{} // 已抢占、正在投递的消息ID
+}
+
+func NewDispatcher(db *gorm.DB, cfg config.DispatcherConfig) *Dispatcher {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.ProcessingLease <= 0 {
+		cfg.ProcessingLease = 300
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		cfg:            cfg,
+		messageRepo:    repository.NewMessageRepository(db),
+		messageService: service.NewMessageService(db),
+		queue:          make(chan job, cfg.QueueSize),
+		wake:           make(chan struct{}, 1),
+		stop:           make(chan struct{}),
+		ctx:            ctx,
+		cancel:         cancel,
+		queued:         make(map[uint64]struct{}),
+		running:        make(map[uint64]int),
+		processing:     make(map[uint64]struct{}),
+	}
+}
+
+// Start 恢复租约已过期的消息并启动工作协程
+func (d *Dispatcher) Start() error {
+	if err := d.recoverExpired(); err != nil {
+		return err
+	}
+
+	for i := 0; i < d.cfg.Workers; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+
+	d.wg.Add(1)
+	go d.poll()
+
+	zap.L().Info("消息分发器已启动",
+		zap.Int("workers", d.cfg.Workers),
+		zap.Int("queueSize", d.cfg.QueueSize),
+		zap.Int("topicConcurrency", d.cfg.TopicConcurrency),
+		zap.Int("processingLease", d.cfg.ProcessingLease),
+	)
+	return nil
+}
+
+// recoverExpired 将租约已过期的处理中消息重置为待处理，这些消息所属的实例已退出或失去响应
+func (d *Dispatcher) recoverExpired() error {
+	before := time.Now().Add(-time.Duration(d.cfg.ProcessingLease) * time.Second)
+	recovered, err := d.messageRepo.RecoverExpired(before)
+	if err != nil {
+		return err
+	}
+	if recovered > 0 {
+		zap.L().Info("恢复租约过期的消息", zap.Int64("count", recovered))
+		d.notify()
+	}
+	return nil
+}
+
+// renew 为正在投递的消息续期租约
+func (d *Dispatcher) renew() {
+	if err := d.messageRepo.RenewClaims(d.processingIDs()); err != nil {
+		zap.L().Error("续期消息租约失败", zap.Error(err))
+	}
+}
+
+// Stop 停止拉取新消息，并在ctx截止前等待处理中的消息投递完成
+// 超过截止时间后中断剩余投递，将这些消息重置为pending，下次启动时跳过本批次已有结果的通道继续处理
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	close(d.stop)
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+	}
+
+	zap.L().Warn("等待消息投递超时，中断剩余投递", zap.Int("processing", len(d.processingIDs())))
+	d.cancel()
+
+	// 中断后工作协程会尽快返回，仍未返回的（如阻塞在不支持取消的调用上）不再等待
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+	}
+
+	ids := d.processingIDs()
+	reset, err := d.messageRepo.ResetStatusByIDs(ids, "processing", "pending")
+	if err != nil {
+		return err
+	}
+	if reset > 0 {
+		zap.L().Info("未完成的消息已重置为待处理", zap.Int64("count", reset))
+	}
+	return ctx.Err()
+}
+
+// Enqueue 将消息放入内存队列
+// 队列已满时返回false，消息保持pending状态，由下一次轮询补偿
+func (d *Dispatcher) Enqueue(message *model.Message) bool {
+	return d.push(job{messageID: message.ID, topicID: message.TopicID})
+}
+
+// QueueDepth 返回内存队列中等待处理的消息数
+func (d *Dispatcher) QueueDepth() int {
+	return len(d.queue)
+}
+
+func (d *Dispatcher) push(j job) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.queued[j.messageID]; ok {
+		return true
+	}
+
+	select {
+	case d.queue <- j:
+		d.queued[j.messageID] = struct{}{}
+		return true
+	default:
+		return false
+	}
+}
+
+// poll 定时续期租约、恢复过期消息，并从数据库拉取待处理消息填充队列
+func (d *Dispatcher) poll() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(d.cfg.PollInterval) * time.Second)
+	defer ticker.Stop()
+
+	d.fill()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			d.renew()
+			if err := d.recoverExpired(); err != nil {
+				zap.L().Error("恢复租约过期的消息失败", zap.Error(err))
+			}
+			d.fill()
+		case <-d.wake:
+			d.fill()
+		}
+	}
+}
+
+// fill 拉取待处理消息，跳过已入队的消息和并发已满的主题
+func (d *Dispatcher) fill() {
+	free := cap(d.queue) - len(d.queue)
+	if free <= 0 {
+		return
+	}
+	if free > d.cfg.BatchSize {
+		free = d.cfg.BatchSize
+	}
+
+	d.mu.Lock()
+	excludeIDs := make([]uint64, 0, len(d.queued))
+	for id := range d.queued {
+		excludeIDs = append(excludeIDs, id)
+	}
+	var busyTopics []uint64
+	if d.cfg.TopicConcurrency > 0 {
+		for topicID, n := range d.running {
+			if n >= d.cfg.TopicConcurrency {
+				busyTopics = append(busyTopics, topicID)
+			}
+		}
+	}
+	d.mu.Unlock()
+
+	messages, err := d.messageRepo.FindPending(excludeIDs, busyTopics, free)
+	if err != nil {
+		zap.L().Error("拉取待处理消息失败", zap.Error(err))
+		return
+	}
+
+	for _, message := range messages {
+		if !d.push(job{messageID: message.ID, topicID: message.TopicID}) {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case j := <-d.queue:
+			d.handle(j)
+		}
+	}
+}
+
+// handle 抢占并处理单条消息
+func (d *Dispatcher) handle(j job) {
+	defer d.forget(j.messageID)
+
+	// 主题并发已满时放弃本次处理，消息保持pending状态等待下次轮询
+	if !d.acquireTopic(j.topicID) {
+		return
+	}
+	defer d.releaseTopic(j.topicID)
+
+	claimed, err := d.messageRepo.ClaimPending(j.messageID)
+	if err != nil {
+		zap.L().Error("抢占消息失败", zap.Uint64("messageId", j.messageID), zap.Error(err))
+		return
+	}
+	if !claimed {
+		// 消息已被处理或状态已变更
+		return
+	}
+
+	d.mu.Lock()
+	d.processing[j.messageID] = struct{}{}
+	d.mu.Unlock()
+
+	message, err := d.messageService.ProcessMessage(d.ctx, j.messageID)
+	if errors.Is(err, context.Canceled) {
+		// 服务停止中断了投递，保持processing状态，由Stop重置为pending
+		return
+	}
+
+	d.mu.Lock()
+	delete(d.processing, j.messageID)
+	d.mu.Unlock()
+
+	if err != nil {
+		zap.L().Warn("处理消息失败", zap.Uint64("messageId", j.messageID), zap.Error(err))
+	}
+
+	// 等待重试的消息到期后立即拉取，不必等到下一次轮询
+	if message != nil && message.Status == "pending" && message.NextAttemptAt != nil {
+		time.AfterFunc(time.Until(*message.NextAttemptAt), d.notify)
+	}
+}
+
+// Processing 返回正在投递的消息数
+func (d *Dispatcher) Processing() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.processing)
+}
+
+// processingIDs 返回正在投递的消息ID
+func (d *Dispatcher) processingIDs() []uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ids := make([]uint64, 0, len(d.processing))
+	for id := range d.processing {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (d *Dispatcher) forget(messageID uint64) {
+	d.mu.Lock()
+	delete(d.queued, messageID)
+	d.mu.Unlock()
+}
+
+func (d *Dispatcher) acquireTopic(topicID uint64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.cfg.TopicConcurrency > 0 && d.running[topicID] >= d.cfg.TopicConcurrency {
+		return false
+	}
+	d.running[topicID]++
+	return true
+}
+
+func (d *Dispatcher) releaseTopic(topicID uint64) {
+	d.mu.Lock()
+	wasBusy := d.cfg.TopicConcurrency > 0 && d.running[topicID] >= d.cfg.TopicConcurrency
+	d.running[topicID]--
+	if d.running[topicID] <= 0 {
+		delete(d.running, topicID)
+	}
+	d.mu.Unlock()
+
+	// 主题从满载恢复时尽快补充队列，避免等待下一次轮询
+	if wasBusy {
+		d.notify()
+	}
+}
+
+// notify 唤醒轮询协程立即拉取待处理消息
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
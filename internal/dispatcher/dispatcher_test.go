@@ -0,0 +1,120 @@
+package dispatcher
+
+import (
+	"testing"
+	"time"
+
+	"synapse/internal/config"
+	"synapse/internal/migration"
+	"synapse/internal/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB 打开执行过全部迁移的内存SQLite数据库
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if _, err := migration.New(db).Up(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// createClaimedMessage 创建指定状态和租约时间的消息
+func createClaimedMessage(t *testing.T, db *gorm.DB, status string, claimedAt *time.Time) *model.Message {
+	t.Helper()
+	message := &model.Message{TopicID: 1, Content: model.JSON{"text": "hello"}, Status: status, ClaimedAt: claimedAt}
+	if err := db.Create(message).Error; err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+func messageStatus(t *testing.T, db *gorm.DB, id uint64) string {
+	t.Helper()
+	var message model.Message
+	if err := db.First(&message, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return message.Status
+}
+
+func TestRecoverExpiredOnlyResetsExpiredClaims(t *testing.T) {
+	db := openTestDB(t)
+	d := NewDispatcher(db, config.DispatcherConfig{ProcessingLease: 60})
+
+	now := time.Now()
+	stale := now.Add(-2 * time.Minute)
+	fresh := createClaimedMessage(t, db, "processing", &now) // 其他实例或同步Webhook正在处理
+	expired := createClaimedMessage(t, db, "processing", &stale)
+	legacy := createClaimedMessage(t, db, "processing", nil) // 升级前的版本抢占
+	completed := createClaimedMessage(t, db, "completed", &stale)
+
+	if err := d.recoverExpired(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[uint64]string{
+		fresh.ID:     "processing",
+		expired.ID:   "pending",
+		legacy.ID:    "pending",
+		completed.ID: "completed",
+	}
+	for id, status := range want {
+		if got := messageStatus(t, db, id); got != status {
+			t.Errorf("message %d status = %q, want %q", id, got, status)
+		}
+	}
+}
+
+func TestRenewKeepsInFlightMessages(t *testing.T) {
+	db := openTestDB(t)
+	d := NewDispatcher(db, config.DispatcherConfig{ProcessingLease: 60})
+
+	stale := time.Now().Add(-2 * time.Minute)
+	inFlight := createClaimedMessage(t, db, "processing", &stale)
+	abandoned := createClaimedMessage(t, db, "processing", &stale)
+	d.processing[inFlight.ID] = struct{}{}
+
+	d.renew()
+	if err := d.recoverExpired(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := messageStatus(t, db, inFlight.ID); got != "processing" {
+		t.Errorf("in-flight message status = %q, want processing", got)
+	}
+	if got := messageStatus(t, db, abandoned.ID); got != "pending" {
+		t.Errorf("abandoned message status = %q, want pending", got)
+	}
+}
+
+func TestClaimPendingRecordsLease(t *testing.T) {
+	db := openTestDB(t)
+	d := NewDispatcher(db, config.DispatcherConfig{ProcessingLease: 60})
+	message := createClaimedMessage(t, db, "pending", nil)
+
+	claimed, err := d.messageRepo.ClaimPending(message.ID)
+	if err != nil || !claimed {
+		t.Fatalf("ClaimPending() = %v, %v", claimed, err)
+	}
+	if err := d.recoverExpired(); err != nil {
+		t.Fatal(err)
+	}
+	if got := messageStatus(t, db, message.ID); got != "processing" {
+		t.Errorf("claimed message status = %q after recovery, want processing", got)
+	}
+}
@@ -0,0 +1,213 @@
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"synapse/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// otherLabel 超出取值数上限的标签值
+const otherLabel = "other"
+
+var (
+	enabled  bool
+	registry *prometheus.Registry
+
+	topicLabel   *labelLimiter
+	channelLabel *labelLimiter
+
+	webhooksReceived *prometheus.CounterVec
+	messagesFinished *prometheus.CounterVec
+	deliveryAttempts *prometheus.CounterVec
+	deliveryDuration *prometheus.HistogramVec
+	templateFailures *prometheus.CounterVec
+	dbQueryDuration  *prometheus.HistogramVec
+)
+
+// Init 按配置注册指标，未启用时所有记录函数为空操作
+func Init(cfg config.MetricsConfig) {
+	if !cfg.Enabled {
+		return
+	}
+
+	registry = prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	topicLabel = newLabelLimiter(cfg.TopicLabel, cfg.MaxLabelValues)
+	channelLabel = newLabelLimiter(cfg.ChannelLabel, cfg.MaxLabelValues)
+
+	webhooksReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "synapse_webhooks_received_total",
+		Help: "接收的Webhook请求数，result为 accepted、duplicate 或 rejected",
+	}, []string{"topic", "result"})
+	messagesFinished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "synapse_messages_total",
+		Help: "处理结束的消息数，按最终状态区分",
+	}, []string{"topic", "status"})
+	deliveryAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "synapse_delivery_attempts_total",
+		Help: "通道投递尝试次数，result为 success 或 failure",
+	}, []string{"channel_type", "channel", "result"})
+	deliveryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "synapse_delivery_duration_seconds",
+		Help:    "单次通道投递耗时",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"channel_type", "channel"})
+	templateFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "synapse_template_render_failures_total",
+		Help: "消息模板渲染失败次数",
+	}, []string{"channel_type"})
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "synapse_db_query_duration_seconds",
+		Help:    "数据库查询耗时，按语句类型区分",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+
+	registry.MustRegister(webhooksReceived, messagesFinished, deliveryAttempts, deliveryDuration, templateFailures, dbQueryDuration)
+	enabled = true
+}
+
+// Enabled 返回是否启用了指标
+func Enabled() bool {
+	return enabled
+}
+
+// Handler 返回指标抓取接口，token不为空时校验Bearer令牌
+func Handler(token string) gin.HandlerFunc {
+	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	return func(ctx *gin.Context) {
+		if token != "" {
+			got, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				ctx.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+		}
+		h.ServeHTTP(ctx.Writer, ctx.Request)
+	}
+}
+
+// RegisterDispatcher 注册分发器的队列长度和处理中消息数
+func RegisterDispatcher(queueDepth, processing func() int) {
+	if !enabled {
+		return
+	}
+	registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "synapse_dispatcher_queue_depth",
+			Help: "分发器内存队列中等待处理的消息数",
+		}, func() float64 { return float64(queueDepth()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "synapse_dispatcher_processing",
+			Help: "分发器正在投递的消息数",
+		}, func() float64 { return float64(processing()) }),
+	)
+}
+
+// WebhookReceived 记录接收的Webhook请求
+func WebhookReceived(topicID uint64, result string) {
+	if !enabled {
+		return
+	}
+	webhooksReceived.WithLabelValues(topicLabel.value(topicID), result).Inc()
+}
+
+// MessageFinished 记录消息的最终状态
+func MessageFinished(topicID uint64, status string) {
+	if !enabled {
+		return
+	}
+	messagesFinished.WithLabelValues(topicLabel.value(topicID), status).Inc()
+}
+
+// DeliveryAttempt 记录一次通道投递的结果和耗时
+func DeliveryAttempt(channelType string, channelID uint64, err error, latency time.Duration) {
+	if !enabled {
+		return
+	}
+	channel := channelLabel.value(channelID)
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	deliveryAttempts.WithLabelValues(channelType, channel, result).Inc()
+	deliveryDuration.WithLabelValues(channelType, channel).Observe(latency.Seconds())
+}
+
+// TemplateFailure 记录模板渲染失败
+func TemplateFailure(channelType string) {
+	if !enabled {
+		return
+	}
+	templateFailures.WithLabelValues(channelType).Inc()
+}
+
+// ObserveQuery 记录数据库查询耗时，作为 logger.ZapGormLogger 的查询钩子
+func ObserveQuery(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if !enabled {
+		return
+	}
+	sql, _ := fc()
+	dbQueryDuration.WithLabelValues(operation(sql)).Observe(time.Since(begin).Seconds())
+}
+
+// operation 返回SQL语句类型
+func operation(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if i := strings.IndexAny(sql, " \t\n("); i > 0 {
+		sql = sql[:i]
+	}
+	switch op := strings.ToLower(sql); op {
+	case "select", "insert", "update", "delete":
+		return op
+	default:
+		return otherLabel
+	}
+}
+
+// labelLimiter 控制按ID区分的标签取值数量
+// 关闭时所有取值合并为空，超过上限后新出现的取值归入 other
+type labelLimiter struct {
+	enabled bool
+	max     int
+
+	mu   sync.Mutex
+	seen map[uint64]struct{}
+}
+
+func newLabelLimiter(enabled bool, max int) *labelLimiter {
+	return &labelLimiter{enabled: enabled, max: max, seen: make(map[uint64]struct{})}
+}
+
+func (l *labelLimiter) value(id uint64) string {
+	if !l.enabled {
+		return ""
+	}
+	if l.max <= 0 {
+		return strconv.FormatUint(id, 10)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.seen[id]; !ok {
+		if len(l.seen) >= l.max {
+			return otherLabel
+		}
+		l.seen[id] = struct{}{}
+	}
+	return strconv.FormatUint(id, 10)
+}
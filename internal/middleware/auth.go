@@ -3,13 +3,16 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"synapse/internal/model"
+	"synapse/internal/service"
 	"synapse/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware JWT认证中间件
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware 认证中间件，接受登录获得的JWT或API密钥
+// 使用API密钥认证时，密钥记录存储在上下文的 apiKey 中，供 RequireScope 检查权限
+func AuthMiddleware(apiKeyService *service.APIKeyService) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		// 从Header中获取token
 		authHeader := ctx.GetHeader("Authorization")
@@ -25,6 +28,19 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// API密钥
+		if service.IsAPIKey(parts[1]) {
+			key, err := apiKeyService.Authenticate(parts[1])
+			if err != nil {
+				utils.ErrorResponse(ctx, http.StatusUnauthorized, "未授权", err.Error())
+				return
+			}
+			ctx.Set("userId", key.UserID)
+			ctx.Set("apiKey", key)
+			ctx.Next()
+			return
+		}
+
 		// 解析令牌
 		claims, err := utils.ParseToken(parts[1])
 		if err != nil {
@@ -37,3 +53,34 @@ func AuthMiddleware() gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// RequireScope 要求API密钥拥有指定权限，JWT认证的请求不受限制
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if key, ok := apiKeyFromContext(ctx); ok && !key.HasScope(scope) {
+			utils.ErrorResponse(ctx, http.StatusForbidden, "权限不足", "API密钥缺少权限: "+scope)
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// RequireUserToken 要求使用登录获得的JWT，用于账户和API密钥管理等接口
+func RequireUserToken() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, ok := apiKeyFromContext(ctx); ok {
+			utils.ErrorResponse(ctx, http.StatusForbidden, "权限不足", "此接口不支持API密钥访问")
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func apiKeyFromContext(ctx *gin.Context) (*model.APIKey, bool) {
+	value, exists := ctx.Get("apiKey")
+	if !exists {
+		return nil, false
+	}
+	key, ok := value.(*model.APIKey)
+	return key, ok
+}
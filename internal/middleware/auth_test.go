@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"synapse/internal/config"
+	"synapse/internal/migration"
+	"synapse/internal/model"
+	"synapse/internal/service"
+	"synapse/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB 打开执行过全部迁移的内存SQLite数据库
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if _, err := migration.New(db).Up(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestAuthMiddlewareScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.GlobalConfig.JWT = config.JWTConfig{Secret: "test-secret", ExpireHours: 1}
+	db := openTestDB(t)
+	apiKeyService := service.NewAPIKeyService(db)
+
+	readOnlyKey, err := apiKeyService.CreateAPIKey(&model.APIKey{UserID: 1, Name: "read-only", Scopes: []string{service.ScopeTopicsRead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongKey := []byte(readOnlyKey)
+	wrongKey[len(wrongKey)-1] ^= 1
+	jwt, err := utils.GenerateToken(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	api := r.Group("/api", AuthMiddleware(apiKeyService))
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	api.GET("/topics", RequireScope(service.ScopeTopicsRead), ok)
+	api.POST("/topics", RequireScope(service.ScopeTopicsWrite), ok)
+	api.GET("/profile", RequireUserToken(), ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		want   int
+	}{
+		{"只读密钥读取", http.MethodGet, "/api/topics", "Bearer " + readOnlyKey, http.StatusOK},
+		{"只读密钥写入", http.MethodPost, "/api/topics", "Bearer " + readOnlyKey, http.StatusForbidden},
+		{"密钥访问账户接口", http.MethodGet, "/api/profile", "Bearer " + readOnlyKey, http.StatusForbidden},
+		{"JWT读取", http.MethodGet, "/api/topics", "Bearer " + jwt, http.StatusOK},
+		{"JWT写入", http.MethodPost, "/api/topics", "Bearer " + jwt, http.StatusOK},
+		{"JWT访问账户接口", http.MethodGet, "/api/profile", "Bearer " + jwt, http.StatusOK},
+		{"密钥错误", http.MethodGet, "/api/topics", "Bearer " + string(wrongKey), http.StatusUnauthorized},
+		{"缺少Bearer前缀", http.MethodGet, "/api/topics", readOnlyKey, http.StatusUnauthorized},
+		{"缺少令牌", http.MethodGet, "/api/topics", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
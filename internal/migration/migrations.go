@@ -0,0 +1,222 @@
+package migration
+
+import (
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// All 返回内置的全部迁移，新增迁移时在末尾追加并使用递增的版本号
+// 已发布的迁移不再修改，表结构使用 snapshots.go 中对应版本的快照，数据迁移直接执行SQL
+func All() []Migration {
+	return []Migration{
+		{
+			Version:     1,
+			Description: "创建所有数据表",
+			Up: func(tx *gorm.DB) error {
+				// 对由旧版 db/schema.sql 创建的数据库执行时，会补齐缺失的列和索引
+				return tx.AutoMigrate(v1Tables()...)
+			},
+			Down: func(tx *gorm.DB) error {
+				tables := v1Tables()
+				for i := len(tables) - 1; i >= 0; i-- {
+					if err := tx.Migrator().DropTable(tables[i]); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Version:     2,
+			Description: "将已有主题、通道和路由归入创建者的个人组织",
+			Up: func(tx *gorm.DB) error {
+				return backfillPersonalOrgs(tx)
+			},
+			// 数据迁移不可逆，回滚时保留组织数据
+			Down: nil,
+		},
+		{
+			Version:     3,
+			Description: "创建模板片段表",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&v3TemplatePartial{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&v3TemplatePartial{})
+			},
+		},
+		{
+			Version:     4,
+			Description: "路由增加HTML正文模板",
+			Up: func(tx *gorm.DB) error {
+				return tx.Migrator().AddColumn(&v4Routing{}, "HTMLTemplate")
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropColumn(&v4Routing{}, "HTMLTemplate")
+			},
+		},
+		{
+			Version:     5,
+			Description: "消息和投递日志增加投递批次",
+			Up: func(tx *gorm.DB) error {
+				if err := tx.Migrator().AddColumn(&v5Message{}, "Run"); err != nil {
+					return err
+				}
+				return tx.Migrator().AddColumn(&v5MessageDeliveryLog{}, "Run")
+			},
+			Down: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropColumn(&v5MessageDeliveryLog{}, "Run"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropColumn(&v5Message{}, "Run")
+			},
+		},
+		{
+			Version:     6,
+			Description: "消息增加投递轮次和下次投递时间",
+			Up: func(tx *gorm.DB) error {
+				for _, column := range []string{"Attempt", "NextAttemptAt"} {
+					if err := tx.Migrator().AddColumn(&v6Message{}, column); err != nil {
+						return err
+					}
+				}
+				if err := tx.Migrator().CreateIndex(&v6Message{}, "NextAttemptAt"); err != nil {
+					return err
+				}
+				return tx.Migrator().AddColumn(&v6MessageDeliveryLog{}, "NextAttemptAt")
+			},
+			Down: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropColumn(&v6MessageDeliveryLog{}, "NextAttemptAt"); err != nil {
+					return err
+				}
+				if err := tx.Migrator().DropIndex(&v6Message{}, "NextAttemptAt"); err != nil {
+					return err
+				}
+				for _, column := range []string{"NextAttemptAt", "Attempt"} {
+					if err := tx.Migrator().DropColumn(&v6Message{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Version:     7,
+			Description: "主题内的去重键改为唯一索引",
+			Up: func(tx *gorm.DB) error {
+				// 没有去重键的消息保存为NULL，不参与唯一约束；已有的重复去重键只保留最新的一条
+				if err := tx.Exec("UPDATE messages SET dedup_key = NULL WHERE dedup_key = ''").Error; err != nil {
+					return err
+				}
+				if err := tx.Exec(`UPDATE messages SET dedup_key = NULL WHERE dedup_key IS NOT NULL AND id NOT IN (
+					SELECT id FROM (SELECT MAX(id) AS id FROM messages WHERE dedup_key IS NOT NULL GROUP BY topic_id, dedup_key) latest)`).Error; err != nil {
+					return err
+				}
+				if err := tx.Migrator().DropIndex(&v1Message{}, "idx_topic_dedup_key"); err != nil {
+					return err
+				}
+				return tx.Migrator().CreateIndex(&v7Message{}, "idx_topic_dedup_key")
+			},
+			Down: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropIndex(&v7Message{}, "idx_topic_dedup_key"); err != nil {
+					return err
+				}
+				return tx.Migrator().CreateIndex(&v1Message{}, "idx_topic_dedup_key")
+			},
+		},
+		{
+			Version:     8,
+			Description: "创建入站签名nonce表，签名密钥改为加密保存",
+			Up: func(tx *gorm.DB) error {
+				if err := tx.AutoMigrate(&v8WebhookNonce{}); err != nil {
+					return err
+				}
+				return tx.Migrator().AlterColumn(&v8Topic{}, "SigningSecret")
+			},
+			Down: func(tx *gorm.DB) error {
+				if err := tx.Migrator().AlterColumn(&v1Topic{}, "SigningSecret"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropTable(&v8WebhookNonce{})
+			},
+		},
+		{
+			Version:     9,
+			Description: "消息增加处理租约时间",
+			Up: func(tx *gorm.DB) error {
+				return tx.Migrator().AddColumn(&v9Message{}, "ClaimedAt")
+			},
+			Down: func(tx *gorm.DB) error {
+				// SQLite下 Migrator().DropColumn 会重建表并丢失索引，直接删除列
+				return tx.Exec("ALTER TABLE messages DROP COLUMN claimed_at").Error
+			},
+		},
+	}
+}
+
+// backfillPersonalOrgs 将未归属组织（org_id为0）的主题、通道及其路由归入创建者的个人组织，个人组织不存在时创建
+func backfillPersonalOrgs(tx *gorm.DB) error {
+	var userIDs []uint64
+	if err := tx.Raw("SELECT user_id FROM topics WHERE org_id = 0 UNION SELECT user_id FROM channels WHERE org_id = 0").
+		Scan(&userIDs).Error; err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		orgID, err := personalOrgID(tx, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			zap.L().Warn("资源的创建者不存在，跳过组织归属", zap.Uint64("userId", userID))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Exec("UPDATE topics SET org_id = ? WHERE user_id = ? AND org_id = 0", orgID, userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE channels SET org_id = ? WHERE user_id = ? AND org_id = 0", orgID, userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE routings SET org_id = ? WHERE org_id = 0 AND topic_id IN (SELECT id FROM topics WHERE org_id = ?)", orgID, orgID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// personalOrgID 返回用户的个人组织ID，不存在时创建，用户不存在时返回 gorm.ErrRecordNotFound
+func personalOrgID(tx *gorm.DB, userID uint64) (uint64, error) {
+	var orgIDs []uint64
+	if err := tx.Raw(`SELECT organizations.id FROM organizations
+		JOIN organization_members ON organization_members.org_id = organizations.id
+		WHERE organization_members.user_id = ? AND organization_members.role = ? AND organizations.personal = ? AND organizations.deleted_at IS NULL
+		ORDER BY organizations.id`, userID, "owner", true).Scan(&orgIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(orgIDs) > 0 {
+		return orgIDs[0], nil
+	}
+
+	var usernames []string
+	if err := tx.Raw("SELECT username FROM users WHERE id = ? AND deleted_at IS NULL", userID).Scan(&usernames).Error; err != nil {
+		return 0, err
+	}
+	if len(usernames) == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+
+	now := time.Now()
+	org := &v1Organization{Name: usernames[0], Personal: true, CreatedAt: now, UpdatedAt: now}
+	if err := tx.Create(org).Error; err != nil {
+		return 0, err
+	}
+	member := &v1OrganizationMember{OrgID: org.ID, UserID: userID, Role: "owner", CreatedAt: now, UpdatedAt: now}
+	if err := tx.Create(member).Error; err != nil {
+		return 0, err
+	}
+	return org.ID, nil
+}
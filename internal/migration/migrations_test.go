@@ -0,0 +1,160 @@
+package migration
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"synapse/internal/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// TestMigrationsCoverModels 执行全部迁移后，当前模型的每一列都应已存在，模型新增字段时需要追加迁移
+func TestMigrationsCoverModels(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := New(db).Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	models := []interface{}{
+		&model.User{},
+		&model.Organization{},
+		&model.OrganizationMember{},
+		&model.APIKey{},
+		&model.Channel{},
+		&model.Topic{},
+		&model.Routing{},
+		&model.Message{},
+		&model.MessageDeliveryLog{},
+		&model.SlackThread{},
+		&model.TemplatePartial{},
+		&model.WebhookNonce{},
+	}
+	for _, m := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			if !db.Migrator().HasColumn(m, field.DBName) {
+				t.Errorf("%s.%s 没有对应的迁移", stmt.Schema.Table, field.DBName)
+			}
+		}
+	}
+}
+
+func TestMigrationsDown(t *testing.T) {
+	db := openTestDB(t)
+	m := New(db)
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Down(len(All())); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"users", "topics", "routings", "messages", "template_partials"} {
+		if db.Migrator().HasTable(table) {
+			t.Errorf("回滚后 %s 表仍然存在", table)
+		}
+	}
+}
+
+func TestBackfillPersonalOrgs(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := New(db, All()[0]).Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sql := range []string{
+		"INSERT INTO users (id, username, password, active) VALUES (1, 'alice', 'x', true), (2, 'bob', 'x', true)",
+		"INSERT INTO organizations (id, name, personal) VALUES (10, 'bob', true)",
+		"INSERT INTO organization_members (org_id, user_id, role) VALUES (10, 2, 'owner')",
+		"INSERT INTO topics (id, user_id, org_id, name, webhook_key) VALUES (1, 1, 0, 'a', 'k1'), (2, 2, 0, 'b', 'k2'), (3, 99, 0, 'c', 'k3')",
+		"INSERT INTO channels (id, user_id, org_id, name, type, credentials) VALUES (1, 1, 0, 'a', 'email', '{}')",
+		"INSERT INTO routings (topic_id, channel_id, org_id) VALUES (1, 1, 0)",
+	} {
+		if err := db.Exec(sql).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := New(db).Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	var aliceOrg uint64
+	db.Raw("SELECT org_id FROM organization_members WHERE user_id = 1 AND role = 'owner'").Scan(&aliceOrg)
+	if aliceOrg == 0 {
+		t.Fatal("没有为alice创建个人组织")
+	}
+
+	orgOf := func(table string, id int) uint64 {
+		var orgID uint64
+		db.Raw("SELECT org_id FROM "+table+" WHERE id = ?", id).Scan(&orgID)
+		return orgID
+	}
+	if got := orgOf("topics", 1); got != aliceOrg {
+		t.Errorf("topic 1 org_id = %d, want %d", got, aliceOrg)
+	}
+	if got := orgOf("channels", 1); got != aliceOrg {
+		t.Errorf("channel 1 org_id = %d, want %d", got, aliceOrg)
+	}
+	if got := orgOf("topics", 2); got != 10 {
+		t.Errorf("topic 2 org_id = %d, want 10（已有的个人组织）", got)
+	}
+	if got := orgOf("topics", 3); got != 0 {
+		t.Errorf("创建者不存在的topic 3 org_id = %d, want 0", got)
+	}
+
+	var routingOrg uint64
+	db.Raw("SELECT org_id FROM routings WHERE topic_id = 1").Scan(&routingOrg)
+	if routingOrg != aliceOrg {
+		t.Errorf("routing org_id = %d, want %d", routingOrg, aliceOrg)
+	}
+}
+
+func TestUniqueDedupKeyMigration(t *testing.T) {
+	db := openTestDB(t)
+	all := All()
+	if _, err := New(db, all[:6]...).Up(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec(`INSERT INTO messages (id, topic_id, content, dedup_key) VALUES
+		(1, 1, '{}', ''), (2, 1, '{}', ''), (3, 1, '{}', 'k'), (4, 1, '{}', 'k'), (5, 2, '{}', 'k')`).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(db).Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []sql.NullString
+	db.Raw("SELECT dedup_key FROM messages ORDER BY id").Scan(&keys)
+	want := []sql.NullString{{}, {}, {}, {String: "k", Valid: true}, {String: "k", Valid: true}}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("dedup_key = %v, want %v", keys, want)
+	}
+	if err := db.Exec("INSERT INTO messages (topic_id, content, dedup_key) VALUES (1, '{}', 'k')").Error; err == nil {
+		t.Error("唯一索引应拒绝重复的去重键")
+	}
+}
@@ -0,0 +1,278 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// 迁移使用的表结构快照
+//
+// 每个迁移只引用本文件中对应版本的结构体，不引用 internal/model 中的模型，
+// 模型后续的修改不会改变已发布的迁移，表结构的变化需要追加新的迁移。
+
+// jsonColumn 与 model.JSON 相同的列类型：MySQL使用json，PostgreSQL使用jsonb，SQLite使用text
+type jsonColumn string
+
+// GormDBDataType 按数据库返回列类型
+func (jsonColumn) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return "json"
+	case "postgres":
+		return "jsonb"
+	}
+	return "text"
+}
+
+// 版本1：基线表结构
+
+type v1User struct {
+	ID        uint64         `gorm:"primaryKey;autoIncrement;comment:用户ID"`
+	CreatedAt time.Time      `gorm:"precision:3;index;comment:接收时间"`
+	UpdatedAt time.Time      `gorm:"precision:3;index;comment:更新时间"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	Username  string         `gorm:"size:50;uniqueIndex;not null"`
+	Password  string         `gorm:"size:128;not null"`
+	Email     string         `gorm:"size:100;uniqueIndex"`
+	Active    bool           `gorm:"default:true"`
+}
+
+func (v1User) TableName() string { return "users" }
+
+type v1Organization struct {
+	ID        uint64         `gorm:"primaryKey;autoIncrement;comment:组织ID"`
+	Name      string         `gorm:"type:varchar(255);not null;comment:组织名称"`
+	Personal  bool           `gorm:"default:false;comment:是否为用户的个人组织"`
+	CreatedAt time.Time      `gorm:"precision:3;index;comment:创建时间"`
+	UpdatedAt time.Time      `gorm:"precision:3;index;comment:更新时间"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (v1Organization) TableName() string { return "organizations" }
+
+type v1OrganizationMember struct {
+	OrgID     uint64    `gorm:"primaryKey;comment:组织ID"`
+	UserID    uint64    `gorm:"primaryKey;index;comment:用户ID"`
+	Role      string    `gorm:"type:varchar(20);not null;comment:角色"`
+	CreatedAt time.Time `gorm:"precision:3;comment:加入时间"`
+	UpdatedAt time.Time `gorm:"precision:3;comment:更新时间"`
+}
+
+func (v1OrganizationMember) TableName() string { return "organization_members" }
+
+type v1APIKey struct {
+	ID         uint64         `gorm:"primaryKey;autoIncrement;comment:密钥ID"`
+	UserID     uint64         `gorm:"not null;index;comment:所属用户的ID"`
+	Name       string         `gorm:"type:varchar(255);not null;comment:密钥名称"`
+	Prefix     string         `gorm:"type:varchar(32);not null;uniqueIndex;comment:密钥前缀"`
+	KeyHash    string         `gorm:"type:varchar(64);not null;comment:密钥SHA-256哈希"`
+	Scopes     string         `gorm:"type:text;comment:权限范围"`
+	ExpiresAt  *time.Time     `gorm:"precision:3;comment:过期时间"`
+	LastUsedAt *time.Time     `gorm:"precision:3;comment:最后使用时间"`
+	CreatedAt  time.Time      `gorm:"precision:3;index;comment:创建时间"`
+	UpdatedAt  time.Time      `gorm:"precision:3;index;comment:更新时间"`
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+}
+
+func (v1APIKey) TableName() string { return "api_keys" }
+
+type v1Channel struct {
+	ID          uint64         `gorm:"primaryKey;autoIncrement;comment:通道ID"`
+	UserID      uint64         `gorm:"not null;index;comment:创建者的ID"`
+	OrgID       uint64         `gorm:"not null;index;comment:所属组织的ID"`
+	Name        string         `gorm:"type:varchar(255);not null;comment:通道名称"`
+	Type        string         `gorm:"type:varchar(50);not null;comment:通道类型"`
+	Credentials jsonColumn     `gorm:"not null;comment:凭证"`
+	CreatedAt   time.Time      `gorm:"precision:3;index;comment:接收时间"`
+	UpdatedAt   time.Time      `gorm:"precision:3;index;comment:更新时间"`
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (v1Channel) TableName() string { return "channels" }
+
+type v1Topic struct {
+	ID              uint64         `gorm:"primaryKey;autoIncrement;comment:项目ID"`
+	UserID          uint64         `gorm:"not null;index;comment:创建者的ID"`
+	OrgID           uint64         `gorm:"not null;index;comment:所属组织的ID"`
+	Name            string         `gorm:"type:varchar(255);not null;comment:项目名称"`
+	WebhookKey      string         `gorm:"type:varchar(36);not null;uniqueIndex;comment:Webhook Key"`
+	SendingStrategy string         `gorm:"type:varchar(50);default:'all';comment:发送策略"`
+	ExecutionMode   string         `gorm:"type:varchar(50);default:'async';comment:执行模式"`
+	Description     string         `gorm:"type:text;comment:项目描述"`
+	DedupExpression string         `gorm:"type:varchar(512);comment:去重表达式"`
+	DedupWindow     int            `gorm:"default:3600;comment:去重时间窗口(秒)"`
+	SigningScheme   string         `gorm:"type:varchar(50);comment:入站签名方案"`
+	SigningSecret   string         `gorm:"type:varchar(255);comment:入站签名密钥"`
+	CreatedAt       time.Time      `gorm:"precision:3;index;comment:接收时间"`
+	UpdatedAt       time.Time      `gorm:"precision:3;index;comment:更新时间"`
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
+}
+
+func (v1Topic) TableName() string { return "topics" }
+
+type v1Routing struct {
+	TopicID           uint64         `gorm:"primaryKey;comment:项目ID"`
+	ChannelID         uint64         `gorm:"primaryKey;comment:通道ID"`
+	OrgID             uint64         `gorm:"not null;index;comment:所属组织的ID"`
+	Priority          int            `gorm:"default:0;comment:优先级"`
+	VariableMappings  jsonColumn     `gorm:"comment:变量映射规则"`
+	MessageTemplate   string         `gorm:"type:text;comment:消息模板"`
+	SubjectTemplate   string         `gorm:"type:text;comment:邮件主题模板"`
+	Options           jsonColumn     `gorm:"comment:通道相关的扩展选项"`
+	Condition         string         `gorm:"type:text;comment:路由条件表达式"`
+	MaxAttempts       int            `gorm:"default:1;comment:最大尝试次数"`
+	RetryInitialDelay int            `gorm:"default:1000;comment:首次重试延迟(毫秒)"`
+	RetryMaxDelay     int            `gorm:"default:60000;comment:最大重试延迟(毫秒)"`
+	RetryMultiplier   float64        `gorm:"default:2;comment:重试延迟倍数"`
+	RetryJitter       float64        `gorm:"default:0.2;comment:重试延迟抖动比例"`
+	CreatedAt         time.Time      `gorm:"precision:3;index;comment:接收时间"`
+	UpdatedAt         time.Time      `gorm:"precision:3;index;comment:更新时间"`
+	DeletedAt         gorm.DeletedAt `gorm:"index"`
+}
+
+func (v1Routing) TableName() string { return "routings" }
+
+type v1Message struct {
+	ID        uint64         `gorm:"primaryKey;autoIncrement;comment:消息ID"`
+	TopicID   uint64         `gorm:"not null;index;index:idx_topic_dedup_key,priority:1;comment:来源主题ID"`
+	Content   jsonColumn     `gorm:"not null;comment:原始消息内容"`
+	Status    string         `gorm:"type:varchar(50);default:'pending';index;comment:处理状态"`
+	DedupKey  string         `gorm:"type:varchar(64);index:idx_topic_dedup_key,priority:2;comment:去重键"`
+	CreatedAt time.Time      `gorm:"precision:3;index;comment:接收时间"`
+	UpdatedAt time.Time      `gorm:"precision:3;index;comment:更新时间"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (v1Message) TableName() string { return "messages" }
+
+type v1MessageDeliveryLog struct {
+	ID                uint64         `gorm:"primaryKey;autoIncrement;comment:日志ID"`
+	MessageID         uint64         `gorm:"not null;index;comment:消息ID"`
+	ChannelID         uint64         `gorm:"not null;index;comment:目标通道ID"`
+	Attempt           int            `gorm:"default:1;comment:第几次尝试"`
+	Status            string         `gorm:"type:varchar(50);not null;comment:投递状态"`
+	StatusCode        int            `gorm:"default:0;comment:HTTP状态码或SMTP响应码"`
+	LatencyMs         int64          `gorm:"default:0;comment:发送耗时(毫秒)"`
+	Response          string         `gorm:"type:text;comment:API响应"`
+	ProviderMessageID string         `gorm:"type:varchar(255);comment:服务方消息ID"`
+	CreatedAt         time.Time      `gorm:"precision:3;index;comment:接收时间"`
+	UpdatedAt         time.Time      `gorm:"precision:3;index;comment:更新时间"`
+	DeletedAt         gorm.DeletedAt `gorm:"index"`
+}
+
+func (v1MessageDeliveryLog) TableName() string { return "message_delivery_logs" }
+
+type v1SlackThread struct {
+	ID           uint64    `gorm:"primaryKey;autoIncrement;comment:记录ID"`
+	ChannelID    uint64    `gorm:"not null;uniqueIndex:idx_channel_thread_key;comment:通道ID"`
+	ThreadKey    string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_channel_thread_key;comment:线程键"`
+	SlackChannel string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_channel_thread_key;comment:Slack频道"`
+	TS           string    `gorm:"type:varchar(64);not null;comment:线程根消息ts"`
+	CreatedAt    time.Time `gorm:"precision:3;index;comment:创建时间"`
+	UpdatedAt    time.Time `gorm:"precision:3;index;comment:更新时间"`
+}
+
+func (v1SlackThread) TableName() string { return "slack_threads" }
+
+// v1Tables 按依赖顺序排列的基线表
+func v1Tables() []interface{} {
+	return []interface{}{
+		&v1User{},
+		&v1Organization{},
+		&v1OrganizationMember{},
+		&v1APIKey{},
+		&v1Channel{},
+		&v1Topic{},
+		&v1Routing{},
+		&v1Message{},
+		&v1MessageDeliveryLog{},
+		&v1SlackThread{},
+	}
+}
+
+// 版本3：模板片段表
+
+type v3TemplatePartial struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement;comment:片段ID"`
+	OrgID     uint64    `gorm:"not null;uniqueIndex:idx_org_partial_name;comment:所属组织的ID"`
+	UserID    uint64    `gorm:"not null;index;comment:创建者ID"`
+	Name      string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_org_partial_name;comment:片段名称"`
+	Content   string    `gorm:"type:text;not null;comment:片段内容"`
+	CreatedAt time.Time `gorm:"precision:3;index;comment:创建时间"`
+	UpdatedAt time.Time `gorm:"precision:3;index;comment:更新时间"`
+}
+
+func (v3TemplatePartial) TableName() string { return "template_partials" }
+
+// 版本4：路由的HTML正文模板列
+
+type v4Routing struct {
+	HTMLTemplate string `gorm:"type:text;comment:HTML正文模板"`
+}
+
+func (v4Routing) TableName() string { return "routings" }
+
+// 版本5：消息和投递日志的投递批次列
+
+type v5Message struct {
+	Run int `gorm:"default:0;comment:投递批次，重放和重新投递时递增"`
+}
+
+func (v5Message) TableName() string { return "messages" }
+
+type v5MessageDeliveryLog struct {
+	Run int `gorm:"default:0;comment:消息的投递批次"`
+}
+
+func (v5MessageDeliveryLog) TableName() string { return "message_delivery_logs" }
+
+// 版本6：消息的投递轮次和重试时间
+
+type v6Message struct {
+	Attempt       int        `gorm:"default:0;comment:当前批次已处理的轮次"`
+	NextAttemptAt *time.Time `gorm:"precision:3;index;comment:下次投递时间，等待重试时设置"`
+}
+
+func (v6Message) TableName() string { return "messages" }
+
+type v6MessageDeliveryLog struct {
+	NextAttemptAt *time.Time `gorm:"precision:3;comment:计划重试时间"`
+}
+
+func (v6MessageDeliveryLog) TableName() string { return "message_delivery_logs" }
+
+// 版本7：主题内去重键唯一
+
+type v7Message struct {
+	TopicID  uint64  `gorm:"not null;uniqueIndex:idx_topic_dedup_key,priority:1;comment:来源主题ID"`
+	DedupKey *string `gorm:"type:varchar(64);uniqueIndex:idx_topic_dedup_key,priority:2;comment:去重键，为空时不去重"`
+}
+
+func (v7Message) TableName() string { return "messages" }
+
+// 版本8：入站签名nonce表，签名密钥列加长以保存密文
+
+type v8WebhookNonce struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement;comment:记录ID"`
+	NonceKey  string    `gorm:"type:varchar(64);not null;uniqueIndex;comment:主题和nonce的SHA-256摘要"`
+	ExpiresAt time.Time `gorm:"precision:3;not null;index;comment:过期时间"`
+	CreatedAt time.Time `gorm:"precision:3;comment:创建时间"`
+}
+
+func (v8WebhookNonce) TableName() string { return "webhook_nonces" }
+
+type v8Topic struct {
+	SigningSecret string `gorm:"type:text;comment:入站签名密钥"`
+}
+
+func (v8Topic) TableName() string { return "topics" }
+
+// 版本9：处理中消息的租约时间
+
+type v9Message struct {
+	ClaimedAt *time.Time `gorm:"precision:3;comment:处理中消息的租约时间，处理期间定期续期"`
+}
+
+func (v9Message) TableName() string { return "messages" }
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey 用户创建的长期访问密钥，只保存密钥的哈希
+type APIKey struct {
+	ID         uint64         `gorm:"primaryKey;autoIncrement;comment:密钥ID" json:"id"`
+	UserID     uint64         `gorm:"not null;index;comment:所属用户的ID" json:"userId"`
+	Name       string         `gorm:"type:varchar(255);not null;comment:密钥名称" json:"name"`
+	Prefix     string         `gorm:"type:varchar(32);not null;uniqueIndex;comment:密钥前缀" json:"prefix"`
+	KeyHash    string         `gorm:"type:varchar(64);not null;comment:密钥SHA-256哈希" json:"-"`
+	Scopes     []string       `gorm:"type:text;serializer:json;comment:权限范围" json:"scopes"`
+	ExpiresAt  *time.Time     `gorm:"precision:3;comment:过期时间" json:"expiresAt"`
+	LastUsedAt *time.Time     `gorm:"precision:3;comment:最后使用时间" json:"lastUsedAt"`
+	CreatedAt  time.Time      `gorm:"precision:3;index;comment:创建时间" json:"createdAt"`
+	UpdatedAt  time.Time      `gorm:"precision:3;index;comment:更新时间" json:"updatedAt"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// HasScope 判断密钥是否拥有指定权限
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,10 @@
 package model
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"synapse/pkg/keyring"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,29 +12,104 @@ import (
 
 type Channel struct {
 	ID          uint64         `gorm:"primaryKey;autoIncrement;comment:通道ID" json:"id"`
-	UserID      uint64         `gorm:"not null;index;comment:所属用户的ID" json:"userId"`
+	UserID      uint64         `gorm:"not null;index;comment:创建者的ID" json:"userId"`
+	OrgID       uint64         `gorm:"not null;index;comment:所属组织的ID" json:"orgId"`
 	Name        string         `gorm:"type:varchar(255);not null;comment:通道名称" json:"name"`
 	Type        string         `gorm:"type:varchar(50);not null;comment:通道类型" json:"type"`
-	Credentials JSON           `gorm:"type:json;not null;comment:凭证" json:"credentials"` // 使用自定义JSON类型（需实现Scanner/Valuer接口）
-	CreatedAt   time.Time      `gorm:"type:datetime(3);default:CURRENT_TIMESTAMP(3);index;comment:接收时间" json:"createdAt"`
-	UpdatedAt   time.Time      `gorm:"type:datetime(3);default:CURRENT_TIMESTAMP(3);index;comment:更新时间" json:"updatedAt"`
+	Credentials JSON           `gorm:"not null;comment:凭证" json:"credentials"` // 使用自定义JSON类型（需实现Scanner/Valuer接口）
+	CreatedAt   time.Time      `gorm:"precision:3;index;comment:接收时间" json:"createdAt"`
+	UpdatedAt   time.Time      `gorm:"precision:3;index;comment:更新时间" json:"updatedAt"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+
+	plainCredentials JSON // 保存期间暂存的明文凭证
+}
+
+// encryptedCredentialsKey 加密后的凭证在JSON列中的键
+const encryptedCredentialsKey = "_encrypted"
+
+// CredentialsKeyVersion 返回凭证加密使用的主密钥版本，未加密时返回0
+func CredentialsKeyVersion(credentials JSON) int {
+	env, ok := credentials[encryptedCredentialsKey].(map[string]interface{})
+	if !ok || len(credentials) != 1 {
+		return 0
+	}
+	version, _ := env["v"].(float64)
+	return int(version)
+}
+
+// BeforeSave 钩子 - 保存前加密凭证，未配置主密钥时以明文保存
+func (c *Channel) BeforeSave(tx *gorm.DB) error {
+	k := keyring.Default()
+	if k == nil || c.Credentials == nil || CredentialsKeyVersion(c.Credentials) != 0 {
+		return nil
+	}
+
+	plaintext, err := json.Marshal(c.Credentials)
+	if err != nil {
+		return err
+	}
+	env, err := k.Encrypt(plaintext)
+	if err != nil {
+		return err
+	}
+	encrypted, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	var envMap map[string]interface{}
+	if err := json.Unmarshal(encrypted, &envMap); err != nil {
+		return err
+	}
+
+	c.plainCredentials = c.Credentials
+	c.Credentials = JSON{encryptedCredentialsKey: envMap}
+	return nil
+}
+
+// AfterSave 钩子 - 保存后恢复明文凭证
+func (c *Channel) AfterSave(tx *gorm.DB) error {
+	if c.plainCredentials != nil {
+		c.Credentials = c.plainCredentials
+		c.plainCredentials = nil
+	}
+	return nil
 }
 
-// Telegram 配置（结构化）
-type TelegramConfig struct {
-	BotToken  string `json:"botToken"`
-	ChatID    string `json:"chatId"`
-	ParseMode string `json:"parseMode"`
-	Proxy     string `json:"proxy"`
+// AfterFind 钩子 - 查询后解密凭证
+func (c *Channel) AfterFind(tx *gorm.DB) error {
+	if CredentialsKeyVersion(c.Credentials) == 0 {
+		return nil
+	}
+	k := keyring.Default()
+	if k == nil {
+		return errors.New("通道凭证已加密，但未配置主密钥")
+	}
+
+	encrypted, err := json.Marshal(c.Credentials[encryptedCredentialsKey])
+	if err != nil {
+		return err
+	}
+	var env keyring.Envelope
+	if err := json.Unmarshal(encrypted, &env); err != nil {
+		return err
+	}
+	plaintext, err := k.Decrypt(&env)
+	if err != nil {
+		return fmt.Errorf("通道%d凭证解密失败: %w", c.ID, err)
+	}
+
+	var credentials JSON
+	if err := json.Unmarshal(plaintext, &credentials); err != nil {
+		return err
+	}
+	c.Credentials = credentials
+	return nil
 }
 
-// email 配置（结构化）
-type EmailConfig struct {
-	SMTPHost     string `json:"smtpHost"`
-	SMTPPort     int    `json:"smtpPort"`
-	SMTPUsername string `json:"smtpUsername"`
-	SMTPPassword string `json:"smtpPassword"`
-	Sender       string `json:"sender"`
-	To           string `json:"to"`
+// slack 配置（结构化）
+type SlackConfig struct {
+	WebhookURL string `json:"webhookUrl"`
+	BotToken   string `json:"botToken"`
+	Channel    string `json:"channel"`
+	Proxy      string `json:"proxy"`
 }
@@ -8,12 +8,18 @@ import (
 
 // MessageDeliveryLog 投递日志模型
 type MessageDeliveryLog struct {
-	ID        uint64         `gorm:"primaryKey;autoIncrement;comment:日志ID" json:"id"`
-	MessageID uint64         `gorm:"not null;index;comment:消息ID" json:"messageId"`
-	ChannelID uint64         `gorm:"not null;index;comment:目标通道ID" json:"channelId"`
-	Status    string         `gorm:"type:varchar(50);not null;comment:投递状态" json:"status"`
-	Response  string         `gorm:"type:text;comment:API响应" json:"response"`
-	CreatedAt time.Time      `gorm:"type:datetime(3);default:CURRENT_TIMESTAMP(3);index;comment:接收时间" json:"createdAt"`
-	UpdatedAt time.Time      `gorm:"type:datetime(3);default:CURRENT_TIMESTAMP(3);index;comment:更新时间" json:"updatedAt"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                uint64         `gorm:"primaryKey;autoIncrement;comment:日志ID" json:"id"`
+	MessageID         uint64         `gorm:"not null;index;comment:消息ID" json:"messageId"`
+	ChannelID         uint64         `gorm:"not null;index;comment:目标通道ID" json:"channelId"`
+	Run               int            `gorm:"default:0;comment:消息的投递批次" json:"run"`
+	Attempt           int            `gorm:"default:1;comment:第几次尝试" json:"attempt"`
+	Status            string         `gorm:"type:varchar(50);not null;comment:投递状态" json:"status"`
+	StatusCode        int            `gorm:"default:0;comment:HTTP状态码或SMTP响应码" json:"statusCode"`
+	LatencyMs         int64          `gorm:"default:0;comment:发送耗时(毫秒)" json:"latencyMs"`
+	Response          string         `gorm:"type:text;comment:API响应" json:"response"`
+	ProviderMessageID string         `gorm:"type:varchar(255);comment:服务方消息ID" json:"providerMessageId"`
+	NextAttemptAt     *time.Time     `gorm:"precision:3;comment:计划重试时间" json:"nextAttemptAt"`
+	CreatedAt         time.Time      `gorm:"precision:3;index;comment:接收时间" json:"createdAt"`
+	UpdatedAt         time.Time      `gorm:"precision:3;index;comment:更新时间" json:"updatedAt"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
 }
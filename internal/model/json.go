@@ -4,24 +4,54 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
+// JSON 以JSON格式保存的对象，MySQL使用json列，PostgreSQL使用jsonb列，SQLite使用text列
 type JSON map[string]interface{}
 
+// GormDataType 通用数据类型
+func (JSON) GormDataType() string {
+	return "json"
+}
+
+// GormDBDataType 按数据库返回列类型
+func (JSON) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return "json"
+	case "postgres":
+		return "jsonb"
+	}
+	return "text"
+}
+
+// Value 序列化为JSON字符串，各数据库驱动都能直接写入
 func (j JSON) Value() (driver.Value, error) {
 	if j == nil {
 		return nil, nil
 	}
-	return json.Marshal(j)
+	bytes, err := json.Marshal(j)
+	if err != nil {
+		return nil, err
+	}
+	return string(bytes), nil
 }
 
+// Scan 兼容驱动返回的[]byte和string
 func (j *JSON) Scan(value interface{}) error {
-	if value == nil {
+	var bytes []byte
+	switch v := value.(type) {
+	case nil:
 		*j = nil
 		return nil
-	}
-	bytes, ok := value.([]byte)
-	if !ok {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
 		return fmt.Errorf("failed to scan JSON: %v", value)
 	}
 	var result map[string]interface{}
@@ -8,11 +8,16 @@ import (
 
 // Message 消息模型
 type Message struct {
-	ID        uint64         `gorm:"primaryKey;autoIncrement;comment:消息ID" json:"id"`
-	TopicID   uint64         `gorm:"not null;index;comment:来源主题ID" json:"topicId"`
-	Content   JSON           `gorm:"type:json;not null;comment:原始消息内容" json:"content"`
-	Status    string         `gorm:"type:varchar(50);default:'pending';index;comment:处理状态" json:"status"`
-	CreatedAt time.Time      `gorm:"type:datetime(3);default:CURRENT_TIMESTAMP(3);index;comment:接收时间" json:"createdAt"`
-	UpdatedAt time.Time      `gorm:"type:datetime(3);default:CURRENT_TIMESTAMP(3);index;comment:更新时间" json:"updatedAt"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID            uint64         `gorm:"primaryKey;autoIncrement;comment:消息ID" json:"id"`
+	TopicID       uint64         `gorm:"not null;index;uniqueIndex:idx_topic_dedup_key,priority:1;comment:来源主题ID" json:"topicId"`
+	Content       JSON           `gorm:"not null;comment:原始消息内容" json:"content"`
+	Status        string         `gorm:"type:varchar(50);default:'pending';index;comment:处理状态" json:"status"`
+	DedupKey      *string        `gorm:"type:varchar(64);uniqueIndex:idx_topic_dedup_key,priority:2;comment:去重键，为空时不去重" json:"-"`
+	Run           int            `gorm:"default:0;comment:投递批次，重放和重新投递时递增" json:"run"`
+	Attempt       int            `gorm:"default:0;comment:当前批次已处理的轮次" json:"attempt"`
+	NextAttemptAt *time.Time     `gorm:"precision:3;index;comment:下次投递时间，等待重试时设置" json:"nextAttemptAt"`
+	ClaimedAt     *time.Time     `gorm:"precision:3;comment:处理中消息的租约时间，处理期间定期续期" json:"-"`
+	CreatedAt     time.Time      `gorm:"precision:3;index;comment:接收时间" json:"createdAt"`
+	UpdatedAt     time.Time      `gorm:"precision:3;index;comment:更新时间" json:"updatedAt"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 }
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Organization 组织，主题、通道和路由归属于组织，由成员按角色共享
+type Organization struct {
+	ID        uint64         `gorm:"primaryKey;autoIncrement;comment:组织ID" json:"id"`
+	Name      string         `gorm:"type:varchar(255);not null;comment:组织名称" json:"name"`
+	Personal  bool           `gorm:"default:false;comment:是否为用户的个人组织" json:"personal"`
+	CreatedAt time.Time      `gorm:"precision:3;index;comment:创建时间" json:"createdAt"`
+	UpdatedAt time.Time      `gorm:"precision:3;index;comment:更新时间" json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// 组织成员角色
+const (
+	RoleOwner  = "owner"  // 所有者：全部权限，包括删除组织和管理所有者
+	RoleAdmin  = "admin"  // 管理员：管理资源和非所有者成员
+	RoleEditor = "editor" // 编辑者：管理主题、通道、路由，重放消息
+	RoleViewer = "viewer" // 查看者：只读
+)
+
+// OrganizationMember 组织成员
+type OrganizationMember struct {
+	OrgID     uint64    `gorm:"primaryKey;comment:组织ID" json:"orgId"`
+	UserID    uint64    `gorm:"primaryKey;index;comment:用户ID" json:"userId"`
+	Role      string    `gorm:"type:varchar(20);not null;comment:角色" json:"role"`
+	CreatedAt time.Time `gorm:"precision:3;comment:加入时间" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"precision:3;comment:更新时间" json:"updatedAt"`
+
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
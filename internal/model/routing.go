@@ -8,13 +8,22 @@ import (
 
 // Routing 路由模型
 type Routing struct {
-	TopicID          uint64         `gorm:"primaryKey;comment:项目ID" json:"topicId"`
-	ChannelID        uint64         `gorm:"primaryKey;comment:通道ID" json:"channelId"`
-	Priority         int            `gorm:"default:0;comment:优先级" json:"priority"`
-	VariableMappings JSON           `gorm:"type:json;comment:变量映射规则" json:"variableMappings"`
-	MessageTemplate  string         `gorm:"type:text;comment:消息模板" json:"messageTemplate"`
-	SubjectTemplate  string         `gorm:"type:text;comment:邮件主题模板" json:"subjectTemplate"`
-	CreatedAt        time.Time      `gorm:"type:datetime(3);default:CURRENT_TIMESTAMP(3);index;comment:接收时间" json:"createdAt"`
-	UpdatedAt        time.Time      `gorm:"type:datetime(3);default:CURRENT_TIMESTAMP(3);index;comment:更新时间" json:"updatedAt"`
-	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+	TopicID           uint64         `gorm:"primaryKey;comment:项目ID" json:"topicId"`
+	ChannelID         uint64         `gorm:"primaryKey;comment:通道ID" json:"channelId"`
+	OrgID             uint64         `gorm:"not null;index;comment:所属组织的ID" json:"orgId"`
+	Priority          int            `gorm:"default:0;comment:优先级" json:"priority"`
+	VariableMappings  JSON           `gorm:"comment:变量映射规则" json:"variableMappings"`
+	MessageTemplate   string         `gorm:"type:text;comment:消息模板" json:"messageTemplate"`
+	SubjectTemplate   string         `gorm:"type:text;comment:邮件主题模板" json:"subjectTemplate"`
+	HTMLTemplate      string         `gorm:"type:text;comment:HTML正文模板" json:"htmlTemplate"`
+	Options           JSON           `gorm:"comment:通道相关的扩展选项" json:"options"`
+	Condition         string         `gorm:"type:text;comment:路由条件表达式" json:"condition"` // condition是MySQL保留字，GORM会自动加引号，手写SQL时需自行加引号
+	MaxAttempts       int            `gorm:"comment:最大尝试次数" json:"maxAttempts"`
+	RetryInitialDelay int            `gorm:"comment:首次重试延迟(毫秒)" json:"retryInitialDelay"`
+	RetryMaxDelay     int            `gorm:"comment:最大重试延迟(毫秒)" json:"retryMaxDelay"`
+	RetryMultiplier   float64        `gorm:"comment:重试延迟倍数" json:"retryMultiplier"`
+	RetryJitter       float64        `gorm:"comment:重试延迟抖动比例" json:"retryJitter"`
+	CreatedAt         time.Time      `gorm:"precision:3;index;comment:接收时间" json:"createdAt"`
+	UpdatedAt         time.Time      `gorm:"precision:3;index;comment:更新时间" json:"updatedAt"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
 }
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+)
+
+// SlackThread 记录线程键对应的Slack消息，用于后续消息回复到同一线程
+type SlackThread struct {
+	ID           uint64    `gorm:"primaryKey;autoIncrement;comment:记录ID" json:"id"`
+	ChannelID    uint64    `gorm:"not null;uniqueIndex:idx_channel_thread_key;comment:通道ID" json:"channelId"`
+	ThreadKey    string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_channel_thread_key;comment:线程键" json:"threadKey"`
+	SlackChannel string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_channel_thread_key;comment:Slack频道" json:"slackChannel"`
+	TS           string    `gorm:"type:varchar(64);not null;comment:线程根消息ts" json:"ts"`
+	CreatedAt    time.Time `gorm:"precision:3;index;comment:创建时间" json:"createdAt"`
+	UpdatedAt    time.Time `gorm:"precision:3;index;comment:更新时间" json:"updatedAt"`
+}
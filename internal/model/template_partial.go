@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+)
+
+// TemplatePartial 组织内可复用的命名模板片段，路由模板中通过 {{template "名称" .}} 引用
+type TemplatePartial struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement;comment:片段ID" json:"id"`
+	OrgID     uint64    `gorm:"not null;uniqueIndex:idx_org_partial_name;comment:所属组织的ID" json:"orgId"`
+	UserID    uint64    `gorm:"not null;index;comment:创建者ID" json:"userId"`
+	Name      string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_org_partial_name;comment:片段名称" json:"name"`
+	Content   string    `gorm:"type:text;not null;comment:片段内容" json:"content"`
+	CreatedAt time.Time `gorm:"precision:3;index;comment:创建时间" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"precision:3;index;comment:更新时间" json:"updatedAt"`
+}
@@ -1,6 +1,11 @@
 package model
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"synapse/pkg/keyring"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,13 +13,93 @@ import (
 
 type Topic struct {
 	ID              uint64         `gorm:"primaryKey;autoIncrement;comment:项目ID" json:"id"`
-	UserID          uint64         `gorm:"not null;index;comment:所属用户的ID" json:"userId"`
+	UserID          uint64         `gorm:"not null;index;comment:创建者的ID" json:"userId"`
+	OrgID           uint64         `gorm:"not null;index;comment:所属组织的ID" json:"orgId"`
 	Name            string         `gorm:"type:varchar(255);not null;comment:项目名称" json:"name"`
 	WebhookKey      string         `gorm:"type:varchar(36);not null;uniqueIndex;comment:Webhook Key" json:"webhookKey"`
 	SendingStrategy string         `gorm:"type:varchar(50);default:'all';comment:发送策略" json:"sendingStrategy"`
 	ExecutionMode   string         `gorm:"type:varchar(50);default:'async';comment:执行模式" json:"executionMode"`
 	Description     string         `gorm:"type:text;comment:项目描述" json:"description"`
-	CreatedAt       time.Time      `gorm:"type:datetime(3);default:CURRENT_TIMESTAMP(3);index;comment:接收时间" json:"createdAt"`
-	UpdatedAt       time.Time      `gorm:"type:datetime(3);default:CURRENT_TIMESTAMP(3);index;comment:更新时间" json:"updatedAt"`
+	DedupExpression string         `gorm:"type:varchar(512);comment:去重表达式" json:"dedupExpression"`
+	DedupWindow     int            `gorm:"default:3600;comment:去重时间窗口(秒)" json:"dedupWindow"`
+	SigningScheme   string         `gorm:"type:varchar(50);comment:入站签名方案" json:"signingScheme"`
+	SigningSecret   string         `gorm:"type:text;comment:入站签名密钥" json:"-"`
+	CreatedAt       time.Time      `gorm:"precision:3;index;comment:接收时间" json:"createdAt"`
+	UpdatedAt       time.Time      `gorm:"precision:3;index;comment:更新时间" json:"updatedAt"`
 	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+
+	plainSigningSecret string // 保存期间暂存的明文签名密钥
+}
+
+// encryptedSecretPrefix 加密后的签名密钥的前缀，之后为信封的JSON
+const encryptedSecretPrefix = "_encrypted:"
+
+// SigningSecretKeyVersion 返回签名密钥加密使用的主密钥版本，未加密时返回0
+func SigningSecretKeyVersion(secret string) int {
+	env, ok := parseEncryptedSecret(secret)
+	if !ok {
+		return 0
+	}
+	return env.Version
+}
+
+func parseEncryptedSecret(secret string) (*keyring.Envelope, bool) {
+	encoded, ok := strings.CutPrefix(secret, encryptedSecretPrefix)
+	if !ok {
+		return nil, false
+	}
+	var env keyring.Envelope
+	if err := json.Unmarshal([]byte(encoded), &env); err != nil || env.Version == 0 {
+		return nil, false
+	}
+	return &env, true
+}
+
+// BeforeSave 钩子 - 保存前加密签名密钥，未配置主密钥时以明文保存
+func (t *Topic) BeforeSave(tx *gorm.DB) error {
+	k := keyring.Default()
+	if k == nil || t.SigningSecret == "" || SigningSecretKeyVersion(t.SigningSecret) != 0 {
+		return nil
+	}
+
+	env, err := k.Encrypt([]byte(t.SigningSecret))
+	if err != nil {
+		return err
+	}
+	encrypted, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+
+	t.plainSigningSecret = t.SigningSecret
+	t.SigningSecret = encryptedSecretPrefix + string(encrypted)
+	return nil
+}
+
+// AfterSave 钩子 - 保存后恢复明文签名密钥
+func (t *Topic) AfterSave(tx *gorm.DB) error {
+	if t.plainSigningSecret != "" {
+		t.SigningSecret = t.plainSigningSecret
+		t.plainSigningSecret = ""
+	}
+	return nil
+}
+
+// AfterFind 钩子 - 查询后解密签名密钥
+func (t *Topic) AfterFind(tx *gorm.DB) error {
+	env, ok := parseEncryptedSecret(t.SigningSecret)
+	if !ok {
+		return nil
+	}
+	k := keyring.Default()
+	if k == nil {
+		return errors.New("主题签名密钥已加密，但未配置主密钥")
+	}
+
+	plaintext, err := k.Decrypt(env)
+	if err != nil {
+		return fmt.Errorf("主题%d签名密钥解密失败: %w", t.ID, err)
+	}
+	t.SigningSecret = string(plaintext)
+	return nil
 }
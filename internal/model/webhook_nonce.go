@@ -0,0 +1,13 @@
+package model
+
+import (
+	"time"
+)
+
+// WebhookNonce 已使用的入站签名nonce，用于在多实例间防止重放
+type WebhookNonce struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement;comment:记录ID" json:"id"`
+	NonceKey  string    `gorm:"type:varchar(64);not null;uniqueIndex;comment:主题和nonce的SHA-256摘要" json:"nonceKey"`
+	ExpiresAt time.Time `gorm:"precision:3;not null;index;comment:过期时间" json:"expiresAt"`
+	CreatedAt time.Time `gorm:"precision:3;comment:创建时间" json:"createdAt"`
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"synapse/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// Create 创建API密钥
+func (r *APIKeyRepository) Create(key *model.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+// FindByID 根据ID查找API密钥
+func (r *APIKeyRepository) FindByID(id uint64) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.First(&key, id).Error
+	return &key, err
+}
+
+// FindByPrefix 根据前缀查找API密钥
+func (r *APIKeyRepository) FindByPrefix(prefix string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.Where("prefix = ?", prefix).First(&key).Error
+	return &key, err
+}
+
+// FindByUserID 根据用户ID查找所有API密钥
+func (r *APIKeyRepository) FindByUserID(userID uint64) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// UpdateLastUsed 更新最后使用时间
+func (r *APIKeyRepository) UpdateLastUsed(id uint64, usedAt time.Time) error {
+	return r.db.Model(&model.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", usedAt).Error
+}
+
+// Delete 删除API密钥
+func (r *APIKeyRepository) Delete(id uint64) error {
+	return r.db.Delete(&model.APIKey{}, id).Error
+}
+
+// DeleteByUserID 删除用户的所有API密钥
+func (r *APIKeyRepository) DeleteByUserID(userID uint64) error {
+	return r.db.Where("user_id = ?", userID).Delete(&model.APIKey{}).Error
+}
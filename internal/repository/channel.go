@@ -26,10 +26,10 @@ func (r *ChannelRepository) FindByID(id uint64) (*model.Channel, error) {
 	return &channel, err
 }
 
-// FindByUserID 根据用户ID查找所有通道
-func (r *ChannelRepository) FindByUserID(userID uint64) ([]model.Channel, error) {
+// FindByOrgIDs 查找组织下的所有通道
+func (r *ChannelRepository) FindByOrgIDs(orgIDs []uint64) ([]model.Channel, error) {
 	var channels []model.Channel
-	err := r.db.Where("user_id = ?", userID).Find(&channels).Error
+	err := r.db.Where("org_id IN ?", orgIDs).Find(&channels).Error
 	return channels, err
 }
 
@@ -38,6 +38,27 @@ func (r *ChannelRepository) Update(channel *model.Channel) error {
 	return r.db.Save(channel).Error
 }
 
+// FindActiveInBatches 分批遍历未删除的通道
+func (r *ChannelRepository) FindActiveInBatches(batchSize int, fn func(channels []model.Channel) error) error {
+	var channels []model.Channel
+	return r.db.FindInBatches(&channels, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(channels)
+	}).Error
+}
+
+// FindInBatches 分批遍历所有通道（包括已删除的）
+func (r *ChannelRepository) FindInBatches(batchSize int, fn func(channels []model.Channel) error) error {
+	var channels []model.Channel
+	return r.db.Unscoped().FindInBatches(&channels, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(channels)
+	}).Error
+}
+
+// SaveUnscoped 保存通道（包括已删除的）
+func (r *ChannelRepository) SaveUnscoped(channel *model.Channel) error {
+	return r.db.Unscoped().Save(channel).Error
+}
+
 // Delete 删除通道
 func (r *ChannelRepository) Delete(id uint64) error {
 	return r.db.Delete(&model.Channel{}, id).Error
@@ -26,6 +26,13 @@ func (r *DeliveryRepository) FindByMessageID(messageID uint64) ([]model.MessageD
 	return logs, err
 }
 
+// FindByMessageRun 按记录顺序查找消息在指定投递批次中的投递日志
+func (r *DeliveryRepository) FindByMessageRun(messageID uint64, run int) ([]model.MessageDeliveryLog, error) {
+	var logs []model.MessageDeliveryLog
+	err := r.db.Where("message_id = ? AND run = ?", messageID, run).Order("id ASC").Find(&logs).Error
+	return logs, err
+}
+
 // FindByChannelID 根据通道ID查找投递日志
 func (r *DeliveryRepository) FindByChannelID(channelID uint64, limit, offset int) ([]model.MessageDeliveryLog, error) {
 	var logs []model.MessageDeliveryLog
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"synapse/internal/model"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -26,6 +27,18 @@ func (r *MessageRepository) FindByID(id uint64) (*model.Message, error) {
 	return &message, err
 }
 
+// FindByDedupKey 查找持有主题中去重键的消息，包括已删除的消息
+func (r *MessageRepository) FindByDedupKey(topicID uint64, dedupKey string) (*model.Message, error) {
+	var message model.Message
+	err := r.db.Unscoped().Where("topic_id = ? AND dedup_key = ?", topicID, dedupKey).First(&message).Error
+	return &message, err
+}
+
+// ReleaseDedupKey 清除消息的去重键，使相同去重键的新消息可以插入
+func (r *MessageRepository) ReleaseDedupKey(id uint64, dedupKey string) error {
+	return r.db.Unscoped().Model(&model.Message{}).Where("id = ? AND dedup_key = ?", id, dedupKey).Update("dedup_key", nil).Error
+}
+
 // FindByTopicID 根据主题ID查找消息
 func (r *MessageRepository) FindByTopicID(topicID uint64, limit, offset int) ([]model.Message, error) {
 	var messages []model.Message
@@ -40,11 +53,171 @@ func (r *MessageRepository) CountByTopicID(topicID uint64) (int64, error) {
 	return count, err
 }
 
+// CountByStatus 统计指定状态的消息数量
+func (r *MessageRepository) CountByStatus(status string) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Message{}).Where("status = ?", status).Count(&count).Error
+	return count, err
+}
+
+// CountDue 统计已到投递时间的待处理消息数量，不包括等待重试的消息
+func (r *MessageRepository) CountDue() (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Message{}).Scopes(due).Count(&count).Error
+	return count, err
+}
+
+// due 已到投递时间的待处理消息
+func due(db *gorm.DB) *gorm.DB {
+	return db.Where("status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)", "pending", time.Now())
+}
+
+// MessageFilter 消息查询条件，OrgIDs为可访问的组织（为空时查不到任何消息），其余为空时不过滤
+type MessageFilter struct {
+	OrgIDs  []uint64
+	TopicID uint64
+	Status  string
+	Since   *time.Time
+	Until   *time.Time
+}
+
+// scope 构造按组织主题和过滤条件查询消息的语句
+func (r *MessageRepository) scope(filter MessageFilter) *gorm.DB {
+	query := r.db.Model(&model.Message{}).
+		Joins("JOIN topics ON topics.id = messages.topic_id AND topics.deleted_at IS NULL").
+		Where("topics.org_id IN ?", filter.OrgIDs)
+	if filter.TopicID != 0 {
+		query = query.Where("messages.topic_id = ?", filter.TopicID)
+	}
+	if filter.Status != "" {
+		query = query.Where("messages.status = ?", filter.Status)
+	}
+	if filter.Since != nil {
+		query = query.Where("messages.created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("messages.created_at < ?", *filter.Until)
+	}
+	return query
+}
+
+// FindByFilter 按条件查找消息
+func (r *MessageRepository) FindByFilter(filter MessageFilter, limit, offset int) ([]model.Message, error) {
+	var messages []model.Message
+	err := r.scope(filter).Order("messages.created_at DESC").Limit(limit).Offset(offset).Find(&messages).Error
+	return messages, err
+}
+
+// CountByFilter 按条件统计消息数量
+func (r *MessageRepository) CountByFilter(filter MessageFilter) (int64, error) {
+	var count int64
+	err := r.scope(filter).Count(&count).Error
+	return count, err
+}
+
+// FindReplayable 按条件查找已处理完成（非待处理/处理中）的消息
+func (r *MessageRepository) FindReplayable(filter MessageFilter, limit int) ([]model.Message, error) {
+	var messages []model.Message
+	err := r.scope(filter).Where("messages.status IN ?", FinishedStatuses).
+		Order("messages.id ASC").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
 // UpdateStatus 更新消息状态
 func (r *MessageRepository) UpdateStatus(id uint64, status string) error {
 	return r.db.Model(&model.Message{}).Where("id = ?", id).Update("status", status).Error
 }
 
+// ClaimPending 将已到投递时间的待处理消息标记为处理中并记录租约时间，返回是否抢占成功
+func (r *MessageRepository) ClaimPending(id uint64) (bool, error) {
+	result := r.db.Model(&model.Message{}).Where("id = ?", id).Scopes(due).Updates(map[string]interface{}{
+		"status":     "processing",
+		"claimed_at": time.Now(),
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+// StartAttempt 将消息标记为处理中、递增投递轮次并刷新租约时间
+func (r *MessageRepository) StartAttempt(id uint64) error {
+	return r.db.Model(&model.Message{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     "processing",
+		"attempt":    gorm.Expr("attempt + 1"),
+		"claimed_at": time.Now(),
+	}).Error
+}
+
+// RenewClaims 刷新指定消息中仍在处理中的消息的租约时间
+func (r *MessageRepository) RenewClaims(ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&model.Message{}).Where("id IN ? AND status = ?", ids, "processing").
+		Update("claimed_at", time.Now()).Error
+}
+
+// ScheduleRetry 将消息重新置为待处理，到达指定时间后再由分发器处理
+func (r *MessageRepository) ScheduleRetry(id uint64, at time.Time) error {
+	return r.db.Model(&model.Message{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          "pending",
+		"next_attempt_at": at,
+	}).Error
+}
+
+// Finish 更新消息的最终状态并清除重试时间
+func (r *MessageRepository) Finish(id uint64, status string) error {
+	return r.db.Model(&model.Message{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          status,
+		"next_attempt_at": nil,
+	}).Error
+}
+
+// FindPending 按接收顺序查找已到投递时间的待处理消息，可排除指定消息和主题
+func (r *MessageRepository) FindPending(excludeIDs, excludeTopicIDs []uint64, limit int) ([]model.Message, error) {
+	var messages []model.Message
+	query := r.db.Select("id", "topic_id").Scopes(due)
+	if len(excludeIDs) > 0 {
+		query = query.Where("id NOT IN ?", excludeIDs)
+	}
+	if len(excludeTopicIDs) > 0 {
+		query = query.Where("topic_id NOT IN ?", excludeTopicIDs)
+	}
+	err := query.Order("id ASC").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+// FinishedStatuses 投递已结束的消息状态，只有这些状态的消息可以重放
+var FinishedStatuses = []string{"completed", "partial", "failed", "dead"}
+
+// Requeue 将处于指定状态的消息重新置为待处理并开始新的投递批次，返回实际重新置为待处理的消息数
+// 状态检查和更新在同一条语句中完成，已被其他请求重放或正在处理的消息不会被重复置为待处理
+func (r *MessageRepository) Requeue(ids []uint64, statuses []string) (int64, error) {
+	result := r.db.Model(&model.Message{}).Where("id IN ? AND status IN ?", ids, statuses).Updates(map[string]interface{}{
+		"status":          "pending",
+		"run":             gorm.Expr("run + 1"),
+		"attempt":         0,
+		"next_attempt_at": nil,
+	})
+	return result.RowsAffected, result.Error
+}
+
+// RecoverExpired 将租约在指定时间之前到期的处理中消息重置为待处理，返回重置的消息数
+// 没有租约时间的消息由升级前的版本抢占，同样视为已过期
+func (r *MessageRepository) RecoverExpired(before time.Time) (int64, error) {
+	result := r.db.Model(&model.Message{}).
+		Where("status = ? AND (claimed_at IS NULL OR claimed_at < ?)", "processing", before).
+		Update("status", "pending")
+	return result.RowsAffected, result.Error
+}
+
+// ResetStatusByIDs 将指定消息中处于某状态的消息重置为新状态
+func (r *MessageRepository) ResetStatusByIDs(ids []uint64, from, to string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := r.db.Model(&model.Message{}).Where("id IN ? AND status = ?", ids, from).Update("status", to)
+	return result.RowsAffected, result.Error
+}
+
 // Delete 删除消息
 func (r *MessageRepository) Delete(id uint64) error {
 	return r.db.Delete(&model.Message{}, id).Error
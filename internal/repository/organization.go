@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"synapse/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type OrganizationRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganizationRepository(db *gorm.DB) *OrganizationRepository {
+	return &OrganizationRepository{db: db}
+}
+
+// CreateWithOwner 创建组织并将用户设为所有者
+func (r *OrganizationRepository) CreateWithOwner(org *model.Organization, userID uint64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.OrganizationMember{OrgID: org.ID, UserID: userID, Role: model.RoleOwner}).Error
+	})
+}
+
+// FindByID 根据ID查找组织
+func (r *OrganizationRepository) FindByID(id uint64) (*model.Organization, error) {
+	var org model.Organization
+	err := r.db.First(&org, id).Error
+	return &org, err
+}
+
+// FindByUserID 查找用户所属的所有组织
+func (r *OrganizationRepository) FindByUserID(userID uint64) ([]model.Organization, error) {
+	var orgs []model.Organization
+	err := r.db.Joins("JOIN organization_members ON organization_members.org_id = organizations.id").
+		Where("organization_members.user_id = ?", userID).
+		Order("organizations.id").Find(&orgs).Error
+	return orgs, err
+}
+
+// FindPersonal 查找用户的个人组织
+func (r *OrganizationRepository) FindPersonal(userID uint64) (*model.Organization, error) {
+	var org model.Organization
+	err := r.db.Joins("JOIN organization_members ON organization_members.org_id = organizations.id").
+		Where("organization_members.user_id = ? AND organization_members.role = ? AND organizations.personal = ?", userID, model.RoleOwner, true).
+		Order("organizations.id").First(&org).Error
+	return &org, err
+}
+
+// FindOrgIDsByUserRoles 查找用户以指定角色之一所属的组织ID
+func (r *OrganizationRepository) FindOrgIDsByUserRoles(userID uint64, roles []string) ([]uint64, error) {
+	var ids []uint64
+	err := r.db.Model(&model.OrganizationMember{}).
+		Joins("JOIN organizations ON organizations.id = organization_members.org_id AND organizations.deleted_at IS NULL").
+		Where("organization_members.user_id = ? AND organization_members.role IN ?", userID, roles).
+		Pluck("organization_members.org_id", &ids).Error
+	return ids, err
+}
+
+// Update 更新组织
+func (r *OrganizationRepository) Update(org *model.Organization) error {
+	return r.db.Save(org).Error
+}
+
+// Delete 删除组织及其成员关系和模板片段
+func (r *OrganizationRepository) Delete(id uint64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("org_id = ?", id).Delete(&model.OrganizationMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("org_id = ?", id).Delete(&model.TemplatePartial{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Organization{}, id).Error
+	})
+}
+
+// FindMember 查找组织成员
+func (r *OrganizationRepository) FindMember(orgID, userID uint64) (*model.OrganizationMember, error) {
+	var member model.OrganizationMember
+	err := r.db.Where("org_id = ? AND user_id = ?", orgID, userID).First(&member).Error
+	return &member, err
+}
+
+// FindMembers 查找组织的所有成员
+func (r *OrganizationRepository) FindMembers(orgID uint64) ([]model.OrganizationMember, error) {
+	var members []model.OrganizationMember
+	err := r.db.Preload("User").Where("org_id = ?", orgID).Order("created_at").Find(&members).Error
+	return members, err
+}
+
+// SaveMember 添加或更新组织成员
+func (r *OrganizationRepository) SaveMember(member *model.OrganizationMember) error {
+	return r.db.Save(member).Error
+}
+
+// DeleteMember 移除组织成员
+func (r *OrganizationRepository) DeleteMember(orgID, userID uint64) error {
+	return r.db.Where("org_id = ? AND user_id = ?", orgID, userID).Delete(&model.OrganizationMember{}).Error
+}
+
+// DeleteMembersByUserID 移除用户的所有成员关系
+func (r *OrganizationRepository) DeleteMembersByUserID(userID uint64) error {
+	return r.db.Where("user_id = ?", userID).Delete(&model.OrganizationMember{}).Error
+}
+
+// FindSoleOwnedShared 查找用户是唯一所有者的非个人组织
+func (r *OrganizationRepository) FindSoleOwnedShared(userID uint64) ([]model.Organization, error) {
+	var orgs []model.Organization
+	owners := r.db.Model(&model.OrganizationMember{}).Select("COUNT(*)").
+		Where("organization_members.org_id = organizations.id AND organization_members.role = ?", model.RoleOwner)
+	err := r.db.Joins("JOIN organization_members ON organization_members.org_id = organizations.id").
+		Where("organization_members.user_id = ? AND organization_members.role = ? AND organizations.personal = ?", userID, model.RoleOwner, false).
+		Where("(?) = 1", owners).
+		Order("organizations.id").Find(&orgs).Error
+	return orgs, err
+}
+
+// CountOwners 统计组织的所有者数量
+func (r *OrganizationRepository) CountOwners(orgID uint64) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.OrganizationMember{}).Where("org_id = ? AND role = ?", orgID, model.RoleOwner).Count(&count).Error
+	return count, err
+}
+
+// CountResources 统计组织下的主题和通道数量
+func (r *OrganizationRepository) CountResources(orgID uint64) (int64, error) {
+	var topics, channels int64
+	if err := r.db.Model(&model.Topic{}).Where("org_id = ?", orgID).Count(&topics).Error; err != nil {
+		return 0, err
+	}
+	if err := r.db.Model(&model.Channel{}).Where("org_id = ?", orgID).Count(&channels).Error; err != nil {
+		return 0, err
+	}
+	return topics + channels, nil
+}
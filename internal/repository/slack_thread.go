@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"synapse/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type SlackThreadRepository struct {
+	db *gorm.DB
+}
+
+func NewSlackThreadRepository(db *gorm.DB) *SlackThreadRepository {
+	return &SlackThreadRepository{db: db}
+}
+
+// Create 创建线程记录
+func (r *SlackThreadRepository) Create(thread *model.SlackThread) error {
+	return r.db.Create(thread).Error
+}
+
+// FindByKey 根据通道ID、Slack频道和线程键查找线程
+func (r *SlackThreadRepository) FindByKey(channelID uint64, slackChannel, threadKey string) (*model.SlackThread, error) {
+	var thread model.SlackThread
+	err := r.db.Where("channel_id = ? AND slack_channel = ? AND thread_key = ?", channelID, slackChannel, threadKey).First(&thread).Error
+	return &thread, err
+}
+
+// DeleteByChannelID 删除通道的所有线程记录
+func (r *SlackThreadRepository) DeleteByChannelID(channelID uint64) error {
+	return r.db.Where("channel_id = ?", channelID).Delete(&model.SlackThread{}).Error
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"synapse/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type TemplatePartialRepository struct {
+	db *gorm.DB
+}
+
+func NewTemplatePartialRepository(db *gorm.DB) *TemplatePartialRepository {
+	return &TemplatePartialRepository{db: db}
+}
+
+// Create 创建模板片段
+func (r *TemplatePartialRepository) Create(partial *model.TemplatePartial) error {
+	return r.db.Create(partial).Error
+}
+
+// FindByID 根据ID查找模板片段
+func (r *TemplatePartialRepository) FindByID(id uint64) (*model.TemplatePartial, error) {
+	var partial model.TemplatePartial
+	err := r.db.First(&partial, id).Error
+	return &partial, err
+}
+
+// FindByOrgIDs 查找组织下的所有模板片段
+func (r *TemplatePartialRepository) FindByOrgIDs(orgIDs []uint64) ([]model.TemplatePartial, error) {
+	var partials []model.TemplatePartial
+	err := r.db.Where("org_id IN ?", orgIDs).Order("name").Find(&partials).Error
+	return partials, err
+}
+
+// FindByOrgAndName 根据组织和名称查找模板片段
+func (r *TemplatePartialRepository) FindByOrgAndName(orgID uint64, name string) (*model.TemplatePartial, error) {
+	var partial model.TemplatePartial
+	err := r.db.Where("org_id = ? AND name = ?", orgID, name).First(&partial).Error
+	return &partial, err
+}
+
+// Update 更新模板片段
+func (r *TemplatePartialRepository) Update(partial *model.TemplatePartial) error {
+	return r.db.Save(partial).Error
+}
+
+// Delete 删除模板片段
+func (r *TemplatePartialRepository) Delete(id uint64) error {
+	return r.db.Delete(&model.TemplatePartial{}, id).Error
+}
@@ -30,10 +30,10 @@ func (r *TopicRepository) FindByID(id uint64) (*model.Topic, error) {
 	return &topic, err
 }
 
-// FindByUserID 根据用户ID查找所有主题
-func (r *TopicRepository) FindByUserID(userID uint64) ([]model.Topic, error) {
+// FindByOrgIDs 查找组织下的所有主题
+func (r *TopicRepository) FindByOrgIDs(orgIDs []uint64) ([]model.Topic, error) {
 	var topics []model.Topic
-	err := r.db.Where("user_id = ?", userID).Find(&topics).Error
+	err := r.db.Where("org_id IN ?", orgIDs).Find(&topics).Error
 	return topics, err
 }
 
@@ -49,6 +49,19 @@ func (r *TopicRepository) Update(topic *model.Topic) error {
 	return r.db.Save(topic).Error
 }
 
+// FindInBatches 分批遍历所有主题（包括已删除的）
+func (r *TopicRepository) FindInBatches(batchSize int, fn func(topics []model.Topic) error) error {
+	var topics []model.Topic
+	return r.db.Unscoped().FindInBatches(&topics, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(topics)
+	}).Error
+}
+
+// SaveUnscoped 保存主题（包括已删除的）
+func (r *TopicRepository) SaveUnscoped(topic *model.Topic) error {
+	return r.db.Unscoped().Save(topic).Error
+}
+
 // Delete 删除主题
 func (r *TopicRepository) Delete(id uint64) error {
 	return r.db.Delete(&model.Topic{}, id).Error
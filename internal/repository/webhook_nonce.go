@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"synapse/internal/model"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// nonceSweepInterval 清理过期nonce的最小间隔
+const nonceSweepInterval = time.Minute
+
+// WebhookNonceRepository 在数据库中记录入站签名的nonce，实现 signature.NonceStore
+type WebhookNonceRepository struct {
+	db *gorm.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewWebhookNonceRepository(db *gorm.DB) *WebhookNonceRepository {
+	return &WebhookNonceRepository{db: db}
+}
+
+// Remember 插入nonce记录，唯一索引冲突且记录未过期时返回false
+func (r *WebhookNonceRepository) Remember(key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	if err := r.sweep(now); err != nil {
+		return false, err
+	}
+	// 释放已过期的同名记录，使其可以重新插入
+	if err := r.db.Where("nonce_key = ? AND expires_at < ?", key, now).Delete(&model.WebhookNonce{}).Error; err != nil {
+		return false, err
+	}
+
+	err := r.db.Create(&model.WebhookNonce{NonceKey: key, ExpiresAt: now.Add(ttl)}).Error
+	if err == nil {
+		return true, nil
+	}
+	// 插入失败时确认是否因为记录已存在，其他错误原样返回
+	var count int64
+	if countErr := r.db.Model(&model.WebhookNonce{}).Where("nonce_key = ?", key).Count(&count).Error; countErr != nil || count == 0 {
+		return false, err
+	}
+	return false, nil
+}
+
+// sweep 按间隔删除过期的nonce
+func (r *WebhookNonceRepository) sweep(now time.Time) error {
+	r.mu.Lock()
+	if now.Sub(r.lastSweep) < nonceSweepInterval {
+		r.mu.Unlock()
+		return nil
+	}
+	r.lastSweep = now
+	r.mu.Unlock()
+	return r.db.Where("expires_at < ?", now).Delete(&model.WebhookNonce{}).Error
+}
+
+// Forget 删除nonce记录
+func (r *WebhookNonceRepository) Forget(key string) error {
+	return r.db.Where("nonce_key = ?", key).Delete(&model.WebhookNonce{}).Error
+}
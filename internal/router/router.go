@@ -1,28 +1,44 @@
 package router
 
 import (
+	"time"
+
+	"synapse/internal/config"
 	"synapse/internal/controller"
+	"synapse/internal/dispatcher"
+	"synapse/internal/metrics"
 	"synapse/internal/middleware"
+	"synapse/internal/repository"
 	"synapse/internal/service"
+	"synapse/pkg/signature"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func SetupRouter(db *gorm.DB) *gin.Engine {
+func SetupRouter(db *gorm.DB, d *dispatcher.Dispatcher, channelHealthService *service.ChannelHealthService) *gin.Engine {
 	// 创建服务
 	userService := service.NewUserService(db)
 	channelService := service.NewChannelService(db)
 	topicService := service.NewTopicService(db)
 	routingService := service.NewRoutingService(db)
 	messageService := service.NewMessageService(db)
+	apiKeyService := service.NewAPIKeyService(db)
+	orgService := service.NewOrganizationService(db)
+	partialService := service.NewTemplatePartialService(db)
+	healthService := service.NewHealthService(db, config.GlobalConfig.Health)
 
 	// 创建控制器
 	userController := controller.NewUserController(userService)
 	channelController := controller.NewChannelController(channelService)
 	topicController := controller.NewTopicController(topicService)
 	routingController := controller.NewRoutingController(routingService)
-	webhookController := controller.NewWebhookController(topicService, messageService)
+	webhookController := controller.NewWebhookController(topicService, messageService, d, newSignatureVerifier(db, config.GlobalConfig.Webhook))
+	messageController := controller.NewMessageController(messageService, d)
+	apiKeyController := controller.NewAPIKeyController(apiKeyService)
+	orgController := controller.NewOrganizationController(orgService)
+	partialController := controller.NewTemplatePartialController(partialService)
+	healthController := controller.NewHealthController(healthService, channelHealthService, d)
 
 	// 初始化Gin
 	r := gin.Default()
@@ -32,6 +48,19 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 	r.Use(middleware.CorsMiddleware())
 	r.Use(middleware.RecoveryMiddleware())
 
+	// Prometheus指标
+	if metrics.Enabled() {
+		path := config.GlobalConfig.Metrics.Path
+		if path == "" {
+			path = "/metrics"
+		}
+		r.GET(path, metrics.Handler(config.GlobalConfig.Metrics.Token))
+	}
+
+	// 存活和就绪探针
+	r.GET("/healthz", healthController.Healthz)
+	r.GET("/readyz", healthController.Readyz)
+
 	// 公开路由
 	public := r.Group("/api")
 	{
@@ -46,52 +75,119 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 		webhook.GET("/:webhook_key/info", webhookController.GetWebhookInfo)
 	}
 
-	// 需要认证的路由
+	// 需要认证的路由，API密钥按权限范围访问
 	protected := r.Group("/api")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddleware(apiKeyService))
 	{
-		// 用户相关
-		protected.GET("/profile", userController.GetProfile)
-		protected.PUT("/profile", userController.UpdateProfile)
-		protected.DELETE("/profile", userController.DeleteAccount)
+		// 用户相关（仅限登录令牌）
+		account := protected.Group("", middleware.RequireUserToken())
+		{
+			account.GET("/profile", userController.GetProfile)
+			account.PUT("/profile", userController.UpdateProfile)
+			account.DELETE("/profile", userController.DeleteAccount)
+
+			// API密钥相关
+			account.POST("/api-keys", apiKeyController.CreateAPIKey)
+			account.GET("/api-keys", apiKeyController.GetAPIKeys)
+			account.DELETE("/api-keys/:id", apiKeyController.DeleteAPIKey)
+		}
+
+		orgsRead := middleware.RequireScope(service.ScopeOrgsRead)
+		orgsWrite := middleware.RequireScope(service.ScopeOrgsWrite)
+		channelsRead := middleware.RequireScope(service.ScopeChannelsRead)
+		channelsWrite := middleware.RequireScope(service.ScopeChannelsWrite)
+		topicsRead := middleware.RequireScope(service.ScopeTopicsRead)
+		topicsWrite := middleware.RequireScope(service.ScopeTopicsWrite)
+		routingsRead := middleware.RequireScope(service.ScopeRoutingsRead)
+		routingsWrite := middleware.RequireScope(service.ScopeRoutingsWrite)
+		messagesRead := middleware.RequireScope(service.ScopeMessagesRead)
+		messagesReplay := middleware.RequireScope(service.ScopeMessagesReplay)
+
+		// 组织相关
+		orgs := protected.Group("/orgs")
+		{
+			orgs.POST("", orgsWrite, orgController.CreateOrganization)
+			orgs.GET("", orgsRead, orgController.GetOrganizations)
+			orgs.GET("/:id", orgsRead, orgController.GetOrganization)
+			orgs.PUT("/:id", orgsWrite, orgController.UpdateOrganization)
+			orgs.DELETE("/:id", orgsWrite, orgController.DeleteOrganization)
+			orgs.POST("/:id/members", orgsWrite, orgController.AddMember)
+			orgs.PUT("/:id/members/:user_id", orgsWrite, orgController.UpdateMember)
+			orgs.DELETE("/:id/members/:user_id", orgsWrite, orgController.RemoveMember)
+		}
 
 		// 通道相关
 		channels := protected.Group("/channels")
 		{
-			channels.POST("", channelController.CreateChannel)
-			channels.GET("", channelController.GetChannels)
-			channels.GET("/:id", channelController.GetChannel)
-			channels.PUT("/:id", channelController.UpdateChannel)
-			channels.DELETE("/:id", channelController.DeleteChannel)
+			channels.POST("", channelsWrite, channelController.CreateChannel)
+			channels.GET("", channelsRead, channelController.GetChannels)
+			channels.GET("/types", channelsRead, channelController.GetChannelTypes)
+			channels.GET("/health", channelsRead, healthController.GetChannelHealth)
+			channels.GET("/:id", channelsRead, channelController.GetChannel)
+			channels.PUT("/:id", channelsWrite, channelController.UpdateChannel)
+			channels.DELETE("/:id", channelsWrite, channelController.DeleteChannel)
+			channels.GET("/:id/health", channelsRead, healthController.CheckChannel)
 			// 通道路由相关
-			channels.GET("/:id/routings", routingController.GetRoutingsByChannel)
+			channels.GET("/:id/routings", routingsRead, routingController.GetRoutingsByChannel)
 			// 通道测试接口
-			channels.POST("/test/telegram", controller.TestTelegramChannel)
-			channels.POST("/test/email", controller.TestEmailChannel)
-			channels.POST("/test/webhook", controller.TestWebhookChannel)
+			channels.POST("/test/:type", channelsWrite, controller.TestChannel)
+			channels.POST("/:id/test", channelsWrite, channelController.TestChannelByID)
 		}
 
 		// 主题相关
 		topics := protected.Group("/topics")
 		{
-			topics.POST("", topicController.CreateTopic)
-			topics.GET("", topicController.GetTopics)
-			topics.GET("/:id", topicController.GetTopic)
-			topics.PUT("/:id", topicController.UpdateTopic)
-			topics.DELETE("/:id", topicController.DeleteTopic)
-			topics.POST("/:id/regenerate-key", topicController.RegenerateWebhookKey)
+			topics.POST("", topicsWrite, topicController.CreateTopic)
+			topics.GET("", topicsRead, topicController.GetTopics)
+			topics.GET("/:id", topicsRead, topicController.GetTopic)
+			topics.PUT("/:id", topicsWrite, topicController.UpdateTopic)
+			topics.DELETE("/:id", topicsWrite, topicController.DeleteTopic)
+			topics.POST("/:id/regenerate-key", topicsWrite, topicController.RegenerateWebhookKey)
 			// 主题路由相关
-			topics.GET("/:id/routings", routingController.GetRoutingsByTopic)
+			topics.GET("/:id/routings", routingsRead, routingController.GetRoutingsByTopic)
 		}
 
 		// 路由相关
 		routings := protected.Group("/routings")
 		{
-			routings.POST("", routingController.CreateRouting)
-			routings.PUT("/:topic_id/:channel_id", routingController.UpdateRouting)
-			routings.DELETE("/:topic_id/:channel_id", routingController.DeleteRouting)
+			routings.POST("", routingsWrite, routingController.CreateRouting)
+			routings.PUT("/:topic_id/:channel_id", routingsWrite, routingController.UpdateRouting)
+			routings.DELETE("/:topic_id/:channel_id", routingsWrite, routingController.DeleteRouting)
+			routings.POST("/:topic_id/:channel_id/preview", routingsWrite, routingController.PreviewRouting)
+		}
+
+		// 模板片段相关
+		partials := protected.Group("/partials")
+		{
+			partials.POST("", routingsWrite, partialController.CreatePartial)
+			partials.GET("", routingsRead, partialController.GetPartials)
+			partials.GET("/:id", routingsRead, partialController.GetPartial)
+			partials.PUT("/:id", routingsWrite, partialController.UpdatePartial)
+			partials.DELETE("/:id", routingsWrite, partialController.DeletePartial)
+		}
+
+		// 消息相关
+		messages := protected.Group("/messages")
+		{
+			messages.GET("", messagesRead, messageController.GetMessages)
+			messages.GET("/dead", messagesRead, messageController.GetDeadMessages)
+			messages.POST("/replay", messagesReplay, messageController.ReplayMessages)
+			messages.GET("/:id", messagesRead, messageController.GetMessage)
+			messages.POST("/:id/replay", messagesReplay, messageController.ReplayMessage)
+			messages.POST("/:id/redrive", messagesReplay, messageController.RedriveMessage)
 		}
 	}
 
 	return r
 }
+
+// newSignatureVerifier 按配置创建入站签名校验器，未配置时时间戳允许偏差5分钟
+// nonce记录在数据库中，重启后仍然有效，并在多个实例间共享
+func newSignatureVerifier(db *gorm.DB, cfg config.WebhookConfig) *signature.Verifier {
+	tolerance := cfg.SignatureTolerance
+	if tolerance <= 0 {
+		tolerance = 300
+	}
+	return signature.NewVerifier(time.Duration(tolerance)*time.Second, time.Duration(cfg.NonceWindow)*time.Second,
+		repository.NewWebhookNonceRepository(db))
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"synapse/internal/model"
+	"synapse/internal/repository"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// API密钥权限范围
+const (
+	ScopeOrgsRead       = "orgs:read"
+	ScopeOrgsWrite      = "orgs:write"
+	ScopeTopicsRead     = "topics:read"
+	ScopeTopicsWrite    = "topics:write"
+	ScopeChannelsRead   = "channels:read"
+	ScopeChannelsWrite  = "channels:write"
+	ScopeRoutingsRead   = "routings:read"
+	ScopeRoutingsWrite  = "routings:write"
+	ScopeMessagesRead   = "messages:read"
+	ScopeMessagesReplay = "messages:replay"
+)
+
+// ValidScopes 支持的API密钥权限范围
+var ValidScopes = []string{
+	ScopeOrgsRead, ScopeOrgsWrite,
+	ScopeTopicsRead, ScopeTopicsWrite,
+	ScopeChannelsRead, ScopeChannelsWrite,
+	ScopeRoutingsRead, ScopeRoutingsWrite,
+	ScopeMessagesRead, ScopeMessagesReplay,
+}
+
+const (
+	// APIKeyPrefix API密钥的固定前缀，用于和JWT区分
+	APIKeyPrefix = "syn_"
+	// apiKeyPrefixLength 密钥中用于查找的前缀长度（含固定前缀）
+	apiKeyPrefixLength = len(APIKeyPrefix) + 12
+	// lastUsedInterval 最后使用时间的更新间隔，避免每个请求都写库
+	lastUsedInterval = time.Minute
+)
+
+var ErrInvalidAPIKey = errors.New("无效的API密钥")
+
+type APIKeyService struct {
+	apiKeyRepo *repository.APIKeyRepository
+}
+
+func NewAPIKeyService(db *gorm.DB) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo: repository.NewAPIKeyRepository(db),
+	}
+}
+
+// IsAPIKey 判断令牌是否为API密钥
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// CreateAPIKey 创建API密钥，返回的明文密钥只在创建时可见
+func (s *APIKeyService) CreateAPIKey(key *model.APIKey) (string, error) {
+	if len(key.Scopes) == 0 {
+		return "", errors.New("至少需要一个权限范围")
+	}
+	for _, scope := range key.Scopes {
+		if !isValidScope(scope) {
+			return "", errors.New("不支持的权限范围: " + scope)
+		}
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()) {
+		return "", errors.New("过期时间必须晚于当前时间")
+	}
+
+	prefix := make([]byte, (apiKeyPrefixLength-len(APIKeyPrefix))/2)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	key.Prefix = APIKeyPrefix + hex.EncodeToString(prefix)
+	plaintext := key.Prefix + "_" + hex.EncodeToString(secret)
+	key.KeyHash = hashAPIKey(plaintext)
+
+	if err := s.apiKeyRepo.Create(key); err != nil {
+		return "", err
+	}
+	return plaintext, nil
+}
+
+// GetAPIKeysByUserID 获取用户的所有API密钥
+func (s *APIKeyService) GetAPIKeysByUserID(userID uint64) ([]model.APIKey, error) {
+	return s.apiKeyRepo.FindByUserID(userID)
+}
+
+// DeleteAPIKey 吊销API密钥
+func (s *APIKeyService) DeleteAPIKey(id uint64, userID uint64) error {
+	key, err := s.apiKeyRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	if key.UserID != userID {
+		return errors.New("无权删除此API密钥")
+	}
+
+	return s.apiKeyRepo.Delete(id)
+}
+
+// Authenticate 校验API密钥，成功时返回密钥记录并更新最后使用时间
+func (s *APIKeyService) Authenticate(plaintext string) (*model.APIKey, error) {
+	if len(plaintext) <= apiKeyPrefixLength || !IsAPIKey(plaintext) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyRepo.FindByPrefix(plaintext[:apiKeyPrefixLength])
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashAPIKey(plaintext))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, errors.New("API密钥已过期")
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedInterval {
+		if err := s.apiKeyRepo.UpdateLastUsed(key.ID, now); err != nil {
+			zap.L().Warn("更新API密钥使用时间失败", zap.Uint64("apiKeyId", key.ID), zap.Error(err))
+		}
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
+
+// hashAPIKey 计算API密钥的SHA-256哈希，密钥本身是高熵随机串，无需加盐
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+// isValidScope 验证权限范围是否有效
+func isValidScope(scope string) bool {
+	for _, s := range ValidScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"synapse/internal/model"
+)
+
+func TestAPIKeyAuthenticate(t *testing.T) {
+	db := openTestDB(t)
+	s := NewAPIKeyService(db)
+	user := registerUser(t, db, "alice")
+
+	key := &model.APIKey{UserID: user.ID, Name: "ci", Scopes: []string{ScopeTopicsRead}}
+	plaintext, err := s.CreateAPIKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authenticated, err := s.Authenticate(plaintext)
+	if err != nil {
+		t.Fatalf("Authenticate() error: %v", err)
+	}
+	if authenticated.ID != key.ID || authenticated.UserID != user.ID || authenticated.LastUsedAt == nil {
+		t.Fatalf("Authenticate() = %+v", authenticated)
+	}
+	if !authenticated.HasScope(ScopeTopicsRead) || authenticated.HasScope(ScopeTopicsWrite) {
+		t.Fatalf("scopes = %v", authenticated.Scopes)
+	}
+
+	// 前缀正确但密钥错误、未知前缀和格式错误的令牌
+	wrongSecret := plaintext[:len(plaintext)-1] + "0"
+	if wrongSecret == plaintext {
+		wrongSecret = plaintext[:len(plaintext)-1] + "1"
+	}
+	for _, token := range []string{wrongSecret, APIKeyPrefix + "000000000000_" + plaintext[apiKeyPrefixLength+1:], key.Prefix, "not-a-key"} {
+		if _, err := s.Authenticate(token); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("Authenticate(%q) = %v, want ErrInvalidAPIKey", token, err)
+		}
+	}
+
+	// 其他用户不能吊销，吊销后不能再认证
+	if err := s.DeleteAPIKey(key.ID, user.ID+1); err == nil {
+		t.Fatal("DeleteAPIKey() by another user succeeded")
+	}
+	if err := s.DeleteAPIKey(key.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Authenticate(plaintext); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("Authenticate() after revoke = %v, want ErrInvalidAPIKey", err)
+	}
+}
+
+func TestAPIKeyExpired(t *testing.T) {
+	db := openTestDB(t)
+	s := NewAPIKeyService(db)
+	user := registerUser(t, db, "alice")
+
+	expiresAt := time.Now().Add(time.Hour)
+	key := &model.APIKey{UserID: user.ID, Name: "ci", Scopes: []string{ScopeTopicsRead}, ExpiresAt: &expiresAt}
+	plaintext, err := s.CreateAPIKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Authenticate(plaintext); err != nil {
+		t.Fatalf("Authenticate() before expiry = %v", err)
+	}
+
+	if err := db.Model(&model.APIKey{}).Where("id = ?", key.ID).Update("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Authenticate(plaintext); err == nil {
+		t.Fatal("Authenticate() with expired key succeeded")
+	}
+}
+
+func TestCreateAPIKeyValidation(t *testing.T) {
+	db := openTestDB(t)
+	s := NewAPIKeyService(db)
+	past := time.Now().Add(-time.Hour)
+	for name, key := range map[string]*model.APIKey{
+		"没有权限范围":   {UserID: 1, Name: "a"},
+		"不支持的权限范围": {UserID: 1, Name: "b", Scopes: []string{"topics:delete"}},
+		"过期时间已过":   {UserID: 1, Name: "c", Scopes: []string{ScopeTopicsRead}, ExpiresAt: &past},
+	} {
+		if _, err := s.CreateAPIKey(key); err == nil {
+			t.Errorf("%s: CreateAPIKey() succeeded", name)
+		}
+	}
+}
+
+func TestDeleteUserRevokesAPIKeys(t *testing.T) {
+	db := openTestDB(t)
+	s := NewAPIKeyService(db)
+	user := registerUser(t, db, "alice")
+
+	plaintext, err := s.CreateAPIKey(&model.APIKey{UserID: user.ID, Name: "ci", Scopes: []string{ScopeTopicsRead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewUserService(db).DeleteUser(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Authenticate(plaintext); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("Authenticate() after deleting user = %v, want ErrInvalidAPIKey", err)
+	}
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"errors"
+	"synapse/internal/model"
+	"synapse/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// Action 组织内的操作级别，角色可执行不高于自身级别的操作
+type Action int
+
+const (
+	ActionRead   Action = iota // 查看主题、通道、路由和消息
+	ActionWrite                // 管理主题、通道、路由，重放消息
+	ActionManage               // 管理组织信息和非所有者成员
+	ActionOwn                  // 删除组织，管理所有者
+)
+
+var roleActions = map[string]Action{
+	model.RoleViewer: ActionRead,
+	model.RoleEditor: ActionWrite,
+	model.RoleAdmin:  ActionManage,
+	model.RoleOwner:  ActionOwn,
+}
+
+var (
+	ErrNotMember = errors.New("无权访问此组织")
+	ErrForbidden = errors.New("当前角色无权执行此操作")
+)
+
+// IsValidRole 判断组织角色是否有效
+func IsValidRole(role string) bool {
+	_, ok := roleActions[role]
+	return ok
+}
+
+// RoleAllows 判断角色是否可以执行操作
+func RoleAllows(role string, action Action) bool {
+	level, ok := roleActions[role]
+	return ok && level >= action
+}
+
+// rolesAllowing 返回可以执行操作的所有角色
+func rolesAllowing(action Action) []string {
+	var roles []string
+	for role, level := range roleActions {
+		if level >= action {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// Authorizer 基于组织成员角色的统一授权
+type Authorizer struct {
+	orgRepo  *repository.OrganizationRepository
+	userRepo *repository.UserRepository
+}
+
+func NewAuthorizer(db *gorm.DB) *Authorizer {
+	return &Authorizer{
+		orgRepo:  repository.NewOrganizationRepository(db),
+		userRepo: repository.NewUserRepository(db),
+	}
+}
+
+// Role 返回用户在组织中的角色
+func (a *Authorizer) Role(userID, orgID uint64) (string, error) {
+	member, err := a.orgRepo.FindMember(orgID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrNotMember
+	}
+	if err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+// Authorize 检查用户能否在组织中执行操作
+func (a *Authorizer) Authorize(userID, orgID uint64, action Action) error {
+	role, err := a.Role(userID, orgID)
+	if err != nil {
+		return err
+	}
+	if !RoleAllows(role, action) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// OrgIDs 返回用户可以执行操作的所有组织ID
+func (a *Authorizer) OrgIDs(userID uint64, action Action) ([]uint64, error) {
+	return a.orgRepo.FindOrgIDsByUserRoles(userID, rolesAllowing(action))
+}
+
+// ResolveOrgID 确定新建资源所属的组织并检查权限，未指定时使用用户的个人组织
+func (a *Authorizer) ResolveOrgID(userID, orgID uint64, action Action) (uint64, error) {
+	if orgID == 0 {
+		return a.PersonalOrgID(userID)
+	}
+	if err := a.Authorize(userID, orgID, action); err != nil {
+		return 0, err
+	}
+	return orgID, nil
+}
+
+// PersonalOrgID 返回用户的个人组织ID，不存在时创建
+func (a *Authorizer) PersonalOrgID(userID uint64) (uint64, error) {
+	org, err := a.orgRepo.FindPersonal(userID)
+	if err == nil {
+		return org.ID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+
+	user, err := a.userRepo.FindByID(userID)
+	if err != nil {
+		return 0, err
+	}
+	org = &model.Organization{Name: user.Username, Personal: true}
+	if err := a.orgRepo.CreateWithOwner(org, userID); err != nil {
+		return 0, err
+	}
+	return org.ID, nil
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"synapse/internal/model"
+	"synapse/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// orgFixture 共享组织及其中各角色的成员和资源
+type orgFixture struct {
+	org                          *model.Organization
+	owner, admin, editor, viewer *model.User
+	topic                        *model.Topic
+	channel                      *model.Channel
+	message                      *model.Message
+}
+
+// newOrgFixture 创建包含所有者、管理员、编辑者、查看者的共享组织，以及组织下的主题、通道、路由和消息
+func newOrgFixture(t *testing.T, db *gorm.DB) *orgFixture {
+	t.Helper()
+	f := &orgFixture{
+		owner:  registerUser(t, db, "owner"),
+		admin:  registerUser(t, db, "admin"),
+		editor: registerUser(t, db, "editor"),
+		viewer: registerUser(t, db, "viewer"),
+		org:    &model.Organization{Name: "team"},
+	}
+
+	orgService := NewOrganizationService(db)
+	if err := orgService.CreateOrganization(f.org, f.owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []struct {
+		user *model.User
+		role string
+	}{{f.admin, model.RoleAdmin}, {f.editor, model.RoleEditor}, {f.viewer, model.RoleViewer}} {
+		if _, err := orgService.AddMember(f.org.ID, m.user.Username, m.role, f.owner.ID); err != nil {
+			t.Fatalf("add %s: %v", m.role, err)
+		}
+	}
+
+	f.topic = &model.Topic{OrgID: f.org.ID, Name: "alerts", SendingStrategy: "all", ExecutionMode: "async"}
+	if err := NewTopicService(db).CreateTopic(f.topic, f.owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	f.channel = &model.Channel{OrgID: f.org.ID, Name: "recorder", Type: recorderType, Credentials: model.JSON{}}
+	if err := NewChannelService(db).CreateChannel(f.channel, f.owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	routing := &model.Routing{TopicID: f.topic.ID, ChannelID: f.channel.ID, OrgID: f.org.ID}
+	if err := db.Create(routing).Error; err != nil {
+		t.Fatal(err)
+	}
+	f.message = createMessage(t, db, f.topic)
+	if err := db.Model(f.message).Update("status", "completed").Error; err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestRoleAllows(t *testing.T) {
+	actions := []Action{ActionRead, ActionWrite, ActionManage, ActionOwn}
+	tests := []struct {
+		role    string
+		allowed int // 允许的操作数，按 actions 顺序
+	}{
+		{model.RoleViewer, 1},
+		{model.RoleEditor, 2},
+		{model.RoleAdmin, 3},
+		{model.RoleOwner, 4},
+		{"unknown", 0},
+	}
+	for _, tt := range tests {
+		for i, action := range actions {
+			if got, want := RoleAllows(tt.role, action), i < tt.allowed; got != want {
+				t.Errorf("RoleAllows(%q, %v) = %v, want %v", tt.role, action, got, want)
+			}
+		}
+	}
+}
+
+func TestAuthorizeAndOrgIDs(t *testing.T) {
+	db := openTestDB(t)
+	f := newOrgFixture(t, db)
+	outsider := registerUser(t, db, "outsider")
+	authz := NewAuthorizer(db)
+
+	tests := []struct {
+		user   *model.User
+		action Action
+		want   error
+	}{
+		{f.viewer, ActionRead, nil},
+		{f.viewer, ActionWrite, ErrForbidden},
+		{f.editor, ActionWrite, nil},
+		{f.editor, ActionManage, ErrForbidden},
+		{f.admin, ActionManage, nil},
+		{f.admin, ActionOwn, ErrForbidden},
+		{f.owner, ActionOwn, nil},
+		{outsider, ActionRead, ErrNotMember},
+	}
+	for _, tt := range tests {
+		if err := authz.Authorize(tt.user.ID, f.org.ID, tt.action); !errors.Is(err, tt.want) {
+			t.Errorf("Authorize(%s, %v) = %v, want %v", tt.user.Username, tt.action, err, tt.want)
+		}
+	}
+
+	readable, err := authz.OrgIDs(f.viewer.ID, ActionRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(readable, f.org.ID) {
+		t.Errorf("viewer readable orgs = %v, want to contain %d", readable, f.org.ID)
+	}
+	writable, err := authz.OrgIDs(f.viewer.ID, ActionWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(writable, f.org.ID) {
+		t.Errorf("viewer writable orgs = %v, must not contain %d", writable, f.org.ID)
+	}
+	outsiderOrgs, err := authz.OrgIDs(outsider.ID, ActionRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(outsiderOrgs, f.org.ID) {
+		t.Errorf("outsider orgs = %v, must not contain %d", outsiderOrgs, f.org.ID)
+	}
+}
+
+func TestViewerCannotWrite(t *testing.T) {
+	db := openTestDB(t)
+	f := newOrgFixture(t, db)
+	topicService := NewTopicService(db)
+	channelService := NewChannelService(db)
+	routingService := NewRoutingService(db)
+	messageService := NewMessageService(db)
+
+	if _, err := topicService.GetTopicByID(f.topic.ID, f.viewer.ID); err != nil {
+		t.Fatalf("viewer read topic: %v", err)
+	}
+
+	checks := map[string]error{
+		"create topic":   topicService.CreateTopic(&model.Topic{OrgID: f.org.ID, Name: "x", SendingStrategy: "all", ExecutionMode: "async"}, f.viewer.ID),
+		"update topic":   topicService.UpdateTopic(&model.Topic{ID: f.topic.ID, Name: "x", SendingStrategy: "all", ExecutionMode: "async"}, f.viewer.ID),
+		"delete topic":   topicService.DeleteTopic(f.topic.ID, f.viewer.ID),
+		"create channel": channelService.CreateChannel(&model.Channel{OrgID: f.org.ID, Name: "x", Type: recorderType, Credentials: model.JSON{}}, f.viewer.ID),
+		"update channel": channelService.UpdateChannel(&model.Channel{ID: f.channel.ID, Name: "x", Type: recorderType, Credentials: model.JSON{}}, f.viewer.ID),
+		"delete channel": channelService.DeleteChannel(f.channel.ID, f.viewer.ID),
+		"update routing": routingService.UpdateRouting(&model.Routing{TopicID: f.topic.ID, ChannelID: f.channel.ID}, f.viewer.ID),
+		"delete routing": routingService.DeleteRouting(f.topic.ID, f.channel.ID, f.viewer.ID),
+	}
+	_, checks["replay message"] = messageService.ReplayMessage(f.message.ID, f.viewer.ID)
+	for name, err := range checks {
+		if !errors.Is(err, ErrForbidden) {
+			t.Errorf("viewer %s: err = %v, want ErrForbidden", name, err)
+		}
+	}
+
+	replayed, err := messageService.ReplayMessages(f.viewer.ID, repository.MessageFilter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed) != 0 {
+		t.Errorf("viewer bulk replay requeued %d messages, want 0", len(replayed))
+	}
+}
+
+func TestEditorCannotManageMembers(t *testing.T) {
+	db := openTestDB(t)
+	f := newOrgFixture(t, db)
+	registerUser(t, db, "newcomer")
+	orgService := NewOrganizationService(db)
+
+	if _, err := orgService.AddMember(f.org.ID, "newcomer", model.RoleViewer, f.editor.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("editor add member: err = %v, want ErrForbidden", err)
+	}
+	if _, err := orgService.UpdateMember(f.org.ID, f.viewer.ID, model.RoleEditor, f.editor.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("editor update member: err = %v, want ErrForbidden", err)
+	}
+	if err := orgService.RemoveMember(f.org.ID, f.viewer.ID, f.editor.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("editor remove member: err = %v, want ErrForbidden", err)
+	}
+	if err := orgService.UpdateOrganization(&model.Organization{ID: f.org.ID, Name: "renamed"}, f.editor.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("editor rename org: err = %v, want ErrForbidden", err)
+	}
+
+	// 成员可以自行退出
+	if err := orgService.RemoveMember(f.org.ID, f.editor.ID, f.editor.ID); err != nil {
+		t.Errorf("editor leave org: %v", err)
+	}
+}
+
+func TestAdminCannotGrantOwner(t *testing.T) {
+	db := openTestDB(t)
+	f := newOrgFixture(t, db)
+	registerUser(t, db, "newcomer")
+	orgService := NewOrganizationService(db)
+
+	if _, err := orgService.AddMember(f.org.ID, "newcomer", model.RoleOwner, f.admin.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("admin add owner: err = %v, want ErrForbidden", err)
+	}
+	if _, err := orgService.UpdateMember(f.org.ID, f.editor.ID, model.RoleOwner, f.admin.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("admin promote to owner: err = %v, want ErrForbidden", err)
+	}
+	if _, err := orgService.UpdateMember(f.org.ID, f.admin.ID, model.RoleOwner, f.admin.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("admin promote self to owner: err = %v, want ErrForbidden", err)
+	}
+	if _, err := orgService.UpdateMember(f.org.ID, f.owner.ID, model.RoleViewer, f.admin.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("admin demote owner: err = %v, want ErrForbidden", err)
+	}
+	if err := orgService.RemoveMember(f.org.ID, f.owner.ID, f.admin.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("admin remove owner: err = %v, want ErrForbidden", err)
+	}
+
+	// 管理员可以管理非所有者成员
+	if _, err := orgService.AddMember(f.org.ID, "newcomer", model.RoleEditor, f.admin.ID); err != nil {
+		t.Errorf("admin add editor: %v", err)
+	}
+	if _, err := orgService.UpdateMember(f.org.ID, f.viewer.ID, model.RoleEditor, f.admin.ID); err != nil {
+		t.Errorf("admin promote viewer: %v", err)
+	}
+
+	role, err := NewAuthorizer(db).Role(f.editor.ID, f.org.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role != model.RoleEditor {
+		t.Errorf("editor role = %q after rejected promotion", role)
+	}
+}
+
+func TestCrossOrgAccess(t *testing.T) {
+	db := openTestDB(t)
+	f := newOrgFixture(t, db)
+	// mallory 是自己个人组织的所有者，但不是共享组织的成员
+	mallory := registerUser(t, db, "mallory")
+	topicService := NewTopicService(db)
+	channelService := NewChannelService(db)
+	routingService := NewRoutingService(db)
+	messageService := NewMessageService(db)
+
+	checks := map[string]error{}
+	_, checks["read topic"] = topicService.GetTopicByID(f.topic.ID, mallory.ID)
+	_, checks["list org topics"] = topicService.GetTopics(mallory.ID, f.org.ID)
+	checks["create topic"] = topicService.CreateTopic(&model.Topic{OrgID: f.org.ID, Name: "x", SendingStrategy: "all", ExecutionMode: "async"}, mallory.ID)
+	checks["update topic"] = topicService.UpdateTopic(&model.Topic{ID: f.topic.ID, Name: "x", SendingStrategy: "all", ExecutionMode: "async"}, mallory.ID)
+	checks["delete topic"] = topicService.DeleteTopic(f.topic.ID, mallory.ID)
+	_, checks["regenerate webhook key"] = topicService.RegenerateWebhookKey(f.topic.ID, mallory.ID)
+	_, checks["read channel"] = channelService.GetChannelByID(f.channel.ID, mallory.ID)
+	_, checks["list org channels"] = channelService.GetChannels(mallory.ID, f.org.ID)
+	checks["update channel"] = channelService.UpdateChannel(&model.Channel{ID: f.channel.ID, Name: "x", Type: recorderType, Credentials: model.JSON{}}, mallory.ID)
+	checks["delete channel"] = channelService.DeleteChannel(f.channel.ID, mallory.ID)
+	_, checks["read routings by topic"] = routingService.GetRoutingsByTopicID(f.topic.ID, mallory.ID)
+	_, checks["read routings by channel"] = routingService.GetRoutingsByChannelID(f.channel.ID, mallory.ID)
+	checks["update routing"] = routingService.UpdateRouting(&model.Routing{TopicID: f.topic.ID, ChannelID: f.channel.ID}, mallory.ID)
+	checks["delete routing"] = routingService.DeleteRouting(f.topic.ID, f.channel.ID, mallory.ID)
+	_, checks["read message"] = messageService.GetMessageDetail(f.message.ID, mallory.ID)
+	_, checks["replay message"] = messageService.ReplayMessage(f.message.ID, mallory.ID)
+	for name, err := range checks {
+		if !errors.Is(err, ErrNotMember) {
+			t.Errorf("outsider %s: err = %v, want ErrNotMember", name, err)
+		}
+	}
+
+	// 用自己组织的通道也不能路由到其他组织的主题
+	own := &model.Channel{Name: "mine", Type: recorderType, Credentials: model.JSON{}}
+	if err := channelService.CreateChannel(own, mallory.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := routingService.CreateRouting(&model.Routing{TopicID: f.topic.ID, ChannelID: own.ID}, mallory.ID); err == nil {
+		t.Error("outsider routed own channel to another org's topic")
+	}
+
+	// 列表中不出现其他组织的资源
+	topics, err := topicService.GetTopics(mallory.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics) != 0 {
+		t.Errorf("outsider sees %d topics, want 0", len(topics))
+	}
+	channels, err := channelService.GetChannels(mallory.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 1 || channels[0].ID != own.ID {
+		t.Errorf("outsider sees channels %+v, want only own channel", channels)
+	}
+	messages, total, err := messageService.GetMessages(mallory.ID, repository.MessageFilter{}, 1, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 0 || total != 0 {
+		t.Errorf("outsider sees %d messages (total %d), want 0", len(messages), total)
+	}
+	replayed, err := messageService.ReplayMessages(mallory.ID, repository.MessageFilter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed) != 0 {
+		t.Errorf("outsider bulk replay requeued %d messages, want 0", len(replayed))
+	}
+
+	// 资源未被修改或删除
+	if _, err := topicService.GetTopicByID(f.topic.ID, f.viewer.ID); err != nil {
+		t.Errorf("topic after outsider access: %v", err)
+	}
+	if _, err := channelService.GetChannelByID(f.channel.ID, f.viewer.ID); err != nil {
+		t.Errorf("channel after outsider access: %v", err)
+	}
+	routings, err := routingService.GetRoutingsByTopicID(f.topic.ID, f.viewer.ID)
+	if err != nil || len(routings) != 1 {
+		t.Errorf("routings after outsider access = %d, %v", len(routings), err)
+	}
+	detail, err := messageService.GetMessageDetail(f.message.ID, f.viewer.ID)
+	if err != nil || detail.Status != "completed" {
+		t.Errorf("message after outsider access: %+v, %v", detail, err)
+	}
+}
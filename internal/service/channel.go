@@ -1,31 +1,47 @@
 package service
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"synapse/internal/model"
 	"synapse/internal/repository"
+	"synapse/pkg/keyring"
+	"synapse/pkg/notifier"
 
 	"gorm.io/gorm"
 )
 
 type ChannelService struct {
 	channelRepo *repository.ChannelRepository
+	authz       *Authorizer
 }
 
 func NewChannelService(db *gorm.DB) *ChannelService {
 	return &ChannelService{
 		channelRepo: repository.NewChannelRepository(db),
+		authz:       NewAuthorizer(db),
 	}
 }
 
-// CreateChannel 创建通道
-func (s *ChannelService) CreateChannel(channel *model.Channel) error {
+// CreateChannel 在用户有编辑权限的组织中创建通道，未指定组织时使用个人组织
+func (s *ChannelService) CreateChannel(channel *model.Channel, userID uint64) error {
+	orgID, err := s.authz.ResolveOrgID(userID, channel.OrgID, ActionWrite)
+	if err != nil {
+		return err
+	}
+	channel.OrgID = orgID
+	channel.UserID = userID
+
 	// 验证通道类型
 	if !s.isValidChannelType(channel.Type) {
 		return errors.New("不支持的通道类型")
 	}
 
+	// 新建通道没有可沿用的密钥
+	if err := notifier.RejectMasked(notifier.Credentials(channel.Credentials)); err != nil {
+		return err
+	}
+
 	// 验证凭证格式
 	if err := s.validateCredentials(channel.Type, channel.Credentials); err != nil {
 		return err
@@ -41,29 +57,36 @@ func (s *ChannelService) GetChannelByID(id uint64, userID uint64) (*model.Channe
 		return nil, err
 	}
 
-	// 验证通道所有权
-	if channel.UserID != userID {
-		return nil, errors.New("无权访问此通道")
+	if err := s.authz.Authorize(userID, channel.OrgID, ActionRead); err != nil {
+		return nil, err
 	}
 
 	return channel, nil
 }
 
-// GetChannelsByUserID 获取用户的所有通道
-func (s *ChannelService) GetChannelsByUserID(userID uint64) ([]model.Channel, error) {
-	return s.channelRepo.FindByUserID(userID)
+// GetChannels 获取用户可访问的通道，orgID不为0时只返回该组织的通道
+func (s *ChannelService) GetChannels(userID, orgID uint64) ([]model.Channel, error) {
+	orgIDs := []uint64{orgID}
+	if orgID == 0 {
+		var err error
+		if orgIDs, err = s.authz.OrgIDs(userID, ActionRead); err != nil {
+			return nil, err
+		}
+	} else if err := s.authz.Authorize(userID, orgID, ActionRead); err != nil {
+		return nil, err
+	}
+	return s.channelRepo.FindByOrgIDs(orgIDs)
 }
 
 // UpdateChannel 更新通道
 func (s *ChannelService) UpdateChannel(channel *model.Channel, userID uint64) error {
-	// 验证通道所有权
 	existingChannel, err := s.channelRepo.FindByID(channel.ID)
 	if err != nil {
 		return err
 	}
 
-	if existingChannel.UserID != userID {
-		return errors.New("无权修改此通道")
+	if err := s.authz.Authorize(userID, existingChannel.OrgID, ActionWrite); err != nil {
+		return err
 	}
 
 	// 验证通道类型
@@ -71,68 +94,101 @@ func (s *ChannelService) UpdateChannel(channel *model.Channel, userID uint64) er
 		return errors.New("不支持的通道类型")
 	}
 
+	// 保持掩码不变的密钥字段沿用已保存的值
+	if channel.Type == existingChannel.Type {
+		n, _ := notifier.Get(channel.Type)
+		credentials, err := n.Schema().Unmask(notifier.Credentials(channel.Credentials), notifier.Credentials(existingChannel.Credentials))
+		if err != nil {
+			return err
+		}
+		channel.Credentials = model.JSON(credentials)
+	} else if err := notifier.RejectMasked(notifier.Credentials(channel.Credentials)); err != nil {
+		// 修改通道类型时没有可沿用的值
+		return err
+	}
+
 	// 验证凭证格式
 	if err := s.validateCredentials(channel.Type, channel.Credentials); err != nil {
 		return err
 	}
 
-	channel.UserID = userID // 确保用户ID不被修改
+	channel.UserID = existingChannel.UserID // 保持创建者不变
+	channel.OrgID = existingChannel.OrgID   // 通道不能移动到其他组织
 	return s.channelRepo.Update(channel)
 }
 
 // DeleteChannel 删除通道
 func (s *ChannelService) DeleteChannel(id uint64, userID uint64) error {
-	// 验证通道所有权
 	channel, err := s.channelRepo.FindByID(id)
 	if err != nil {
 		return err
 	}
 
-	if channel.UserID != userID {
-		return errors.New("无权删除此通道")
+	if err := s.authz.Authorize(userID, channel.OrgID, ActionWrite); err != nil {
+		return err
 	}
 
 	return s.channelRepo.Delete(id)
 }
 
-// isValidChannelType 验证通道类型是否有效
-func (s *ChannelService) isValidChannelType(channelType string) bool {
-	validTypes := []string{"telegram", "email", "slack", "webhook"}
-	for _, t := range validTypes {
-		if t == channelType {
-			return true
-		}
+// TestChannel 使用已保存的凭证发送测试消息
+func (s *ChannelService) TestChannel(ctx context.Context, id uint64, userID uint64, subject, content string) (*notifier.Result, error) {
+	channel, err := s.channelRepo.FindByID(id)
+	if err != nil {
+		return nil, errors.New("通道不存在")
+	}
+
+	if err := s.authz.Authorize(userID, channel.OrgID, ActionWrite); err != nil {
+		return nil, err
+	}
+
+	n, ok := notifier.Get(channel.Type)
+	if !ok {
+		return nil, errors.New("不支持的通道类型")
 	}
-	return false
+	return n.Test(ctx, notifier.Credentials(channel.Credentials), subject, content)
 }
 
-// validateCredentials 验证凭证格式
-func (s *ChannelService) validateCredentials(channelType string, credentials model.JSON) error {
-	switch channelType {
-	case "telegram":
-		var config model.TelegramConfig
-		credentialsBytes, err := json.Marshal(credentials)
-		if err != nil {
-			return errors.New("Telegram配置格式错误")
-		}
-		if err := json.Unmarshal(credentialsBytes, &config); err != nil {
-			return errors.New("Telegram配置格式错误")
-		}
-		if config.BotToken == "" || config.ChatID == "" {
-			return errors.New("Telegram配置不完整")
-		}
-	case "email":
-		var config model.EmailConfig
-		credentialsBytes, err := json.Marshal(credentials)
-		if err != nil {
-			return errors.New("Email配置格式错误")
-		}
-		if err := json.Unmarshal(credentialsBytes, &config); err != nil {
-			return errors.New("Email配置格式错误")
-		}
-		if config.SMTPHost == "" || config.SMTPUsername == "" || config.SMTPPassword == "" {
-			return errors.New("Email配置不完整")
+// MaskCredentials 将通道凭证中的密钥字段替换为掩码，用于接口响应
+func MaskCredentials(channel *model.Channel) {
+	n, ok := notifier.Get(channel.Type)
+	if !ok {
+		channel.Credentials = nil
+		return
+	}
+	channel.Credentials = model.JSON(n.Schema().Mask(notifier.Credentials(channel.Credentials)))
+}
+
+// ReencryptCredentials 使用当前主密钥重新加密所有通道的凭证，返回处理的通道数
+func (s *ChannelService) ReencryptCredentials() (int, error) {
+	if keyring.Default() == nil {
+		return 0, errors.New("未配置主密钥")
+	}
+
+	count := 0
+	err := s.channelRepo.FindInBatches(100, func(channels []model.Channel) error {
+		for i := range channels {
+			if err := s.channelRepo.SaveUnscoped(&channels[i]); err != nil {
+				return err
+			}
+			count++
 		}
+		return nil
+	})
+	return count, err
+}
+
+// isValidChannelType 验证通道类型是否已注册
+func (s *ChannelService) isValidChannelType(channelType string) bool {
+	_, ok := notifier.Get(channelType)
+	return ok
+}
+
+// validateCredentials 由通道类型实现验证凭证格式
+func (s *ChannelService) validateCredentials(channelType string, credentials model.JSON) error {
+	n, ok := notifier.Get(channelType)
+	if !ok {
+		return errors.New("无效的通道类型")
 	}
-	return nil
+	return n.Validate(notifier.Credentials(credentials))
 }
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"synapse/internal/config"
+	"synapse/internal/model"
+	"synapse/internal/repository"
+	"synapse/pkg/notifier"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// channelCheckTimeout 单个通道检查的超时时间
+	channelCheckTimeout = 10 * time.Second
+	// channelCheckConcurrency 同时检查的通道数
+	channelCheckConcurrency = 8
+)
+
+// ChannelHealth 通道凭证检查结果
+type ChannelHealth struct {
+	ChannelID uint64    `json:"channelId"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Status    string    `json:"status"` // ok / error / unsupported
+	Detail    string    `json:"detail,omitempty"`
+	LatencyMs int64     `json:"latencyMs"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// ChannelHealthService 检查通道凭证是否可用，结果按通道缓存
+// 检查不发送消息，由通道类型实现 notifier.Checker，如Telegram getMe、SMTP EHLO+AUTH、Webhook HEAD
+type ChannelHealthService struct {
+	channelRepo *repository.ChannelRepository
+	authz       *Authorizer
+	ttl         time.Duration
+	interval    time.Duration
+
+	mu    sync.Mutex
+	cache map[uint64]ChannelHealth
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewChannelHealthService(db *gorm.DB, cfg config.HealthConfig) *ChannelHealthService {
+	ttl := time.Duration(cfg.ChannelCheckTTL) * time.Second
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return &ChannelHealthService{
+		channelRepo: repository.NewChannelRepository(db),
+		authz:       NewAuthorizer(db),
+		ttl:         ttl,
+		interval:    time.Duration(cfg.ChannelCheckInterval) * time.Second,
+		cache:       make(map[uint64]ChannelHealth),
+		stop:        make(chan struct{}),
+	}
+}
+
+// Start 按配置的间隔在后台定时检查所有通道，间隔为0时不启动
+func (s *ChannelHealthService) Start() {
+	if s.interval <= 0 {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			s.checkAll()
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop 停止后台检查
+func (s *ChannelHealthService) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// GetChannelHealth 返回用户可访问通道的检查结果，没有结果、结果过期或refresh为true时重新检查
+func (s *ChannelHealthService) GetChannelHealth(ctx context.Context, userID, orgID uint64, refresh bool) ([]ChannelHealth, error) {
+	orgIDs := []uint64{orgID}
+	if orgID == 0 {
+		var err error
+		if orgIDs, err = s.authz.OrgIDs(userID, ActionRead); err != nil {
+			return nil, err
+		}
+	} else if err := s.authz.Authorize(userID, orgID, ActionRead); err != nil {
+		return nil, err
+	}
+
+	channels, err := s.channelRepo.FindByOrgIDs(orgIDs)
+	if err != nil {
+		return nil, err
+	}
+	return s.checkChannels(ctx, channels, refresh), nil
+}
+
+// CheckChannel 返回单个通道的检查结果，refresh为true时忽略缓存
+func (s *ChannelHealthService) CheckChannel(ctx context.Context, id, userID uint64, refresh bool) (*ChannelHealth, error) {
+	channel, err := s.channelRepo.FindByID(id)
+	if err != nil {
+		return nil, errors.New("通道不存在")
+	}
+	if err := s.authz.Authorize(userID, channel.OrgID, ActionRead); err != nil {
+		return nil, err
+	}
+
+	health := s.checkChannels(ctx, []model.Channel{*channel}, refresh)[0]
+	return &health, nil
+}
+
+// checkAll 检查所有未删除的通道，不可用的通道记录告警日志
+func (s *ChannelHealthService) checkAll() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := s.channelRepo.FindActiveInBatches(100, func(channels []model.Channel) error {
+		for _, health := range s.checkChannels(ctx, channels, true) {
+			if health.Status == "error" {
+				zap.L().Warn("通道检查失败",
+					zap.Uint64("channelId", health.ChannelID),
+					zap.String("type", health.Type),
+					zap.String("detail", health.Detail),
+				)
+			}
+		}
+		return ctx.Err()
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		zap.L().Error("通道检查失败", zap.Error(err))
+	}
+}
+
+// checkChannels 并发检查通道，按输入顺序返回结果
+func (s *ChannelHealthService) checkChannels(ctx context.Context, channels []model.Channel, refresh bool) []ChannelHealth {
+	results := make([]ChannelHealth, len(channels))
+	sem := make(chan struct{}, channelCheckConcurrency)
+	var wg sync.WaitGroup
+
+	for i := range channels {
+		channel := &channels[i]
+		if health, ok := s.cached(channel); ok && !refresh {
+			results[i] = health
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = s.check(ctx, channel)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// cached 返回未过期的检查结果，通道在检查后被修改时视为过期
+func (s *ChannelHealthService) cached(channel *model.Channel) (ChannelHealth, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	health, ok := s.cache[channel.ID]
+	if !ok || time.Since(health.CheckedAt) > s.ttl || channel.UpdatedAt.After(health.CheckedAt) {
+		return ChannelHealth{}, false
+	}
+	health.Name = channel.Name
+	return health, true
+}
+
+// check 检查单个通道并更新缓存
+func (s *ChannelHealthService) check(ctx context.Context, channel *model.Channel) ChannelHealth {
+	health := ChannelHealth{
+		ChannelID: channel.ID,
+		Name:      channel.Name,
+		Type:      channel.Type,
+		CheckedAt: time.Now(),
+	}
+
+	n, ok := notifier.Get(channel.Type)
+	checker, supported := n.(notifier.Checker)
+	switch {
+	case !ok:
+		health.Status = "error"
+		health.Detail = "不支持的通道类型"
+	case !supported:
+		health.Status = "unsupported"
+	default:
+		checkCtx, cancel := context.WithTimeout(ctx, channelCheckTimeout)
+		res, err := checker.Check(checkCtx, notifier.Credentials(channel.Credentials))
+		cancel()
+
+		health.LatencyMs = time.Since(health.CheckedAt).Milliseconds()
+		if err != nil {
+			health.Status = "error"
+			health.Detail = err.Error()
+		} else {
+			health.Status = "ok"
+			if res != nil {
+				health.Detail = res.Response
+			}
+		}
+	}
+
+	// 请求取消或服务停止导致的失败不缓存
+	if ctx.Err() == nil {
+		s.mu.Lock()
+		s.cache[channel.ID] = health
+		s.mu.Unlock()
+	}
+	return health
+}
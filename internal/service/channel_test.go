@@ -0,0 +1,70 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"synapse/internal/model"
+	"synapse/pkg/notifier"
+)
+
+func TestUpdateChannelTypeRejectsMaskedSecrets(t *testing.T) {
+	db := openTestDB(t)
+	user := registerUser(t, db, "alice")
+	channelService := NewChannelService(db)
+
+	channel := &model.Channel{Name: "ops", Type: "telegram", Credentials: model.JSON{"botToken": "123:secret", "chatId": "42"}}
+	if err := channelService.CreateChannel(channel, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		credentials model.JSON
+		want        string
+	}{
+		{"密钥字段为掩码", model.JSON{"url": "https://attacker.example/hook", "signingSecret": notifier.MaskedValue}, "signingSecret"},
+		{"请求头为掩码", model.JSON{"url": "https://attacker.example/hook", "headers": map[string]interface{}{"Authorization": notifier.MaskedValue}}, "headers.Authorization"},
+		{"沿用旧类型的字段名", model.JSON{"url": "https://attacker.example/hook", "botToken": notifier.MaskedValue}, "botToken"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update := &model.Channel{ID: channel.ID, Name: "ops", Type: "webhook", Credentials: tt.credentials}
+			err := channelService.UpdateChannel(update, user.ID)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("UpdateChannel() error = %v, want mention of %q", err, tt.want)
+			}
+
+			stored, err := channelService.GetChannelByID(channel.ID, user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Type != "telegram" || stored.Credentials["botToken"] != "123:secret" {
+				t.Fatalf("channel changed after rejected update: %s %v", stored.Type, stored.Credentials)
+			}
+		})
+	}
+
+	// 类型不变时掩码沿用已保存的值
+	update := &model.Channel{ID: channel.ID, Name: "ops", Type: "telegram", Credentials: model.JSON{"botToken": notifier.MaskedValue, "chatId": "43"}}
+	if err := channelService.UpdateChannel(update, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := channelService.GetChannelByID(channel.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Credentials["botToken"] != "123:secret" || stored.Credentials["chatId"] != "43" {
+		t.Fatalf("credentials after same-type update = %v", stored.Credentials)
+	}
+}
+
+func TestCreateChannelRejectsMaskedSecrets(t *testing.T) {
+	db := openTestDB(t)
+	user := registerUser(t, db, "alice")
+
+	channel := &model.Channel{Name: "copy", Type: "telegram", Credentials: model.JSON{"botToken": notifier.MaskedValue, "chatId": "42"}}
+	if err := NewChannelService(db).CreateChannel(channel, user.ID); err == nil || !strings.Contains(err.Error(), "botToken") {
+		t.Fatalf("CreateChannel() error = %v, want masked botToken rejected", err)
+	}
+}
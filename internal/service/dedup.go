@@ -0,0 +1,100 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"synapse/internal/model"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	defaultDedupWindow = 3600  // 秒
+	maxDedupWindow     = 86400 // 秒
+)
+
+// normalizeDedup 填充主题去重窗口默认值并校验去重表达式
+func normalizeDedup(topic *model.Topic) error {
+	if topic.DedupWindow <= 0 {
+		topic.DedupWindow = defaultDedupWindow
+	}
+	if topic.DedupWindow > maxDedupWindow {
+		return errors.New("去重时间窗口不能超过86400秒")
+	}
+	_, err := parseDedupExpression(topic.DedupExpression)
+	return err
+}
+
+// dedupWindow 返回主题的去重时间窗口
+func dedupWindow(topic *model.Topic) time.Duration {
+	window := topic.DedupWindow
+	if window <= 0 {
+		window = defaultDedupWindow
+	}
+	return time.Duration(window) * time.Second
+}
+
+// parseDedupExpression 解析去重表达式，返回参与去重的gjson路径
+// 表达式为单个gjson路径，或 hash(path1, path2, ...) 对多个字段联合去重
+func parseDedupExpression(expr string) ([]string, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(expr, "hash(") {
+		return []string{expr}, nil
+	}
+	if !strings.HasSuffix(expr, ")") {
+		return nil, errors.New("去重表达式格式错误，应为 hash(path1, path2, ...)")
+	}
+
+	var paths []string
+	for _, path := range strings.Split(expr[len("hash("):len(expr)-1], ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return nil, errors.New("去重表达式中的字段路径不能为空")
+		}
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("去重表达式至少需要一个字段")
+	}
+	return paths, nil
+}
+
+// DedupKey 计算消息去重键，优先使用请求的Idempotency-Key，其次使用主题的去重表达式
+// 返回空字符串表示不去重（未配置或字段全部为空）
+func DedupKey(topic *model.Topic, idempotencyKey string, content []byte) string {
+	if idempotencyKey != "" {
+		return hashDedupKey("idempotency-key", idempotencyKey)
+	}
+
+	paths, err := parseDedupExpression(topic.DedupExpression)
+	if err != nil || len(paths) == 0 {
+		return ""
+	}
+
+	values := make([]string, len(paths))
+	empty := true
+	for i, path := range paths {
+		result := gjson.GetBytes(content, path)
+		if result.Exists() {
+			values[i] = result.String()
+			empty = false
+		}
+	}
+	if empty {
+		return ""
+	}
+	return hashDedupKey(append([]string{"expression"}, values...)...)
+}
+
+// hashDedupKey 将去重键各部分哈希为定长字符串
+func hashDedupKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
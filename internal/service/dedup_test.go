@@ -0,0 +1,95 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"synapse/internal/model"
+)
+
+func TestCreateMessageDedup(t *testing.T) {
+	db := openTestDB(t)
+	s := NewMessageService(db)
+	topic, _ := createTopic(t, db, "all")
+	topic.DedupWindow = 60
+
+	first := &model.Message{TopicID: topic.ID, Content: model.JSON{"n": 1}, Status: "pending"}
+	if _, err := s.CreateMessage(topic, first, "key"); err != nil {
+		t.Fatal(err)
+	}
+
+	second := &model.Message{TopicID: topic.ID, Content: model.JSON{"n": 2}, Status: "pending"}
+	original, err := s.CreateMessage(topic, second, "key")
+	if !errors.Is(err, ErrDuplicateMessage) || original.ID != first.ID {
+		t.Fatalf("CreateMessage = %v, %v, want duplicate of %d", original, err, first.ID)
+	}
+
+	// 没有去重键的消息不去重
+	for i := 0; i < 2; i++ {
+		if _, err := s.CreateMessage(topic, &model.Message{TopicID: topic.ID, Content: model.JSON{}, Status: "pending"}, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 去重窗口已过，旧消息释放去重键
+	db.Model(&model.Message{}).Where("id = ?", first.ID).Update("created_at", time.Now().Add(-2*time.Minute))
+	third := &model.Message{TopicID: topic.ID, Content: model.JSON{"n": 3}, Status: "pending"}
+	if _, err := s.CreateMessage(topic, third, "key"); err != nil {
+		t.Fatalf("去重窗口外应创建新消息: %v", err)
+	}
+	if reloaded, _ := s.GetMessageByID(first.ID); reloaded.DedupKey != nil {
+		t.Error("旧消息的去重键应被释放")
+	}
+}
+
+func TestCreateMessageDedupConcurrent(t *testing.T) {
+	db := openTestDB(t)
+	s := NewMessageService(db)
+	topic, _ := createTopic(t, db, "all")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created, duplicates := 0, 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.CreateMessage(topic, &model.Message{TopicID: topic.ID, Content: model.JSON{}, Status: "pending"}, "same")
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				created++
+			case errors.Is(err, ErrDuplicateMessage):
+				duplicates++
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if created != 1 || duplicates != 9 {
+		t.Errorf("created = %d, duplicates = %d, want 1, 9", created, duplicates)
+	}
+
+	// 唯一索引在数据库层拒绝相同的去重键
+	key := "same"
+	if err := db.Create(&model.Message{TopicID: topic.ID, Content: model.JSON{}, DedupKey: &key}).Error; err == nil {
+		t.Error("相同主题和去重键的消息不应插入成功")
+	}
+}
+
+func TestCreateMessageReturnsDBErrors(t *testing.T) {
+	db := openTestDB(t)
+	s := NewMessageService(db)
+	topic, _ := createTopic(t, db, "all")
+
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	_, err := s.CreateMessage(topic, &model.Message{TopicID: topic.ID, Content: model.JSON{}}, "key")
+	if err == nil || errors.Is(err, ErrDuplicateMessage) {
+		t.Errorf("err = %v, want database error", err)
+	}
+}
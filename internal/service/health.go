@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"synapse/internal/config"
+	"synapse/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// HealthCheck 单项检查结果
+type HealthCheck struct {
+	Status string `json:"status"` // ok / error
+	Detail string `json:"detail,omitempty"`
+}
+
+type HealthService struct {
+	db          *gorm.DB
+	messageRepo *repository.MessageRepository
+	maxBacklog  int
+}
+
+func NewHealthService(db *gorm.DB, cfg config.HealthConfig) *HealthService {
+	return &HealthService{
+		db:          db,
+		messageRepo: repository.NewMessageRepository(db),
+		maxBacklog:  cfg.MaxBacklog,
+	}
+}
+
+// CheckReadiness 检查数据库连接和待处理消息积压，全部通过时ready为true
+func (s *HealthService) CheckReadiness(ctx context.Context) (checks map[string]HealthCheck, ready bool) {
+	checks = make(map[string]HealthCheck)
+	ready = true
+
+	sqlDB, err := s.db.DB()
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+	if err != nil {
+		checks["database"] = HealthCheck{Status: "error", Detail: err.Error()}
+		// 数据库不可用时无法统计积压
+		return checks, false
+	}
+	checks["database"] = HealthCheck{Status: "ok"}
+
+	pending, err := s.messageRepo.CountDue()
+	switch {
+	case err != nil:
+		checks["backlog"] = HealthCheck{Status: "error", Detail: err.Error()}
+		ready = false
+	case s.maxBacklog > 0 && pending > int64(s.maxBacklog):
+		checks["backlog"] = HealthCheck{Status: "error", Detail: fmt.Sprintf("待处理消息%d条，超过上限%d", pending, s.maxBacklog)}
+		ready = false
+	default:
+		checks["backlog"] = HealthCheck{Status: "ok", Detail: fmt.Sprintf("待处理消息%d条", pending)}
+	}
+	return checks, ready
+}
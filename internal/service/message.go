@@ -1,40 +1,83 @@
 package service
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
-	"html/template"
+	"fmt"
 	"sort"
+	"strings"
+	"synapse/internal/metrics"
 	"synapse/internal/model"
 	"synapse/internal/repository"
+	"synapse/pkg/condition"
 	"synapse/pkg/notifier"
+	"time"
+	"unicode/utf8"
 
-	"github.com/tidwall/gjson"
 	"gorm.io/gorm"
 )
 
 type MessageService struct {
-	messageRepo  *repository.MessageRepository
-	topicRepo    *repository.TopicRepository
-	routingRepo  *repository.RoutingRepository
-	channelRepo  *repository.ChannelRepository
-	deliveryRepo *repository.DeliveryRepository
+	messageRepo   *repository.MessageRepository
+	topicRepo     *repository.TopicRepository
+	routingRepo   *repository.RoutingRepository
+	channelRepo   *repository.ChannelRepository
+	deliveryRepo  *repository.DeliveryRepository
+	renderService *RenderService
+	authz         *Authorizer
 }
 
 func NewMessageService(db *gorm.DB) *MessageService {
 	return &MessageService{
-		messageRepo:  repository.NewMessageRepository(db),
-		topicRepo:    repository.NewTopicRepository(db),
-		routingRepo:  repository.NewRoutingRepository(db),
-		channelRepo:  repository.NewChannelRepository(db),
-		deliveryRepo: repository.NewDeliveryRepository(db),
+		messageRepo:   repository.NewMessageRepository(db),
+		topicRepo:     repository.NewTopicRepository(db),
+		routingRepo:   repository.NewRoutingRepository(db),
+		channelRepo:   repository.NewChannelRepository(db),
+		deliveryRepo:  repository.NewDeliveryRepository(db),
+		renderService: NewRenderService(db),
+		authz:         NewAuthorizer(db),
 	}
 }
 
-// CreateMessage 创建消息
-func (s *MessageService) CreateMessage(message *model.Message) error {
-	return s.messageRepo.Create(message)
+// ErrDuplicateMessage 去重窗口内已有相同去重键的消息
+var ErrDuplicateMessage = errors.New("重复的消息")
+
+// maxCreateAttempts 创建去重消息时插入冲突后重新查询的次数
+const maxCreateAttempts = 3
+
+// CreateMessage 创建消息，dedupKey不为空时在主题的去重窗口内去重，已有相同去重键的消息时返回该消息和 ErrDuplicateMessage
+//
+// (topic_id, dedup_key) 上的唯一索引保证并发的相同请求只有一个能插入，插入冲突后重新查询即得到原消息；
+// 去重窗口已过或已删除的旧消息先释放去重键再插入
+func (s *MessageService) CreateMessage(topic *model.Topic, message *model.Message, dedupKey string) (*model.Message, error) {
+	if dedupKey == "" {
+		return nil, s.messageRepo.Create(message)
+	}
+	message.DedupKey = &dedupKey
+	since := time.Now().Add(-dedupWindow(topic))
+
+	var err error
+	for i := 0; i < maxCreateAttempts; i++ {
+		existing, findErr := s.messageRepo.FindByDedupKey(topic.ID, dedupKey)
+		switch {
+		case findErr == nil:
+			if !existing.DeletedAt.Valid && !existing.CreatedAt.Before(since) {
+				return existing, ErrDuplicateMessage
+			}
+			if err := s.messageRepo.ReleaseDedupKey(existing.ID, dedupKey); err != nil {
+				return nil, err
+			}
+		case !errors.Is(findErr, gorm.ErrRecordNotFound):
+			return nil, findErr
+		}
+
+		// 插入失败可能是并发的相同请求先插入了，重新查询
+		if err = s.messageRepo.Create(message); err == nil {
+			return nil, nil
+		}
+	}
+	return nil, err
 }
 
 // GetMessageByID 根据ID获取消息
@@ -58,266 +101,532 @@ func (s *MessageService) GetMessagesByTopicID(topicID uint64, page, pageSize int
 	return messages, total, nil
 }
 
-// ProcessMessage 处理消息
-func (s *MessageService) ProcessMessage(messageID uint64) error {
+// GetDeadMessages 获取用户可访问主题下的死信消息列表
+func (s *MessageService) GetDeadMessages(userID uint64, page, pageSize int) ([]model.Message, int64, error) {
+	return s.GetMessages(userID, repository.MessageFilter{Status: "dead"}, page, pageSize)
+}
+
+// ErrMessageConflict 消息的状态已被其他请求或分发器改变，不能重放或重新投递
+var ErrMessageConflict = errors.New("消息状态已变化，请刷新后重试")
+
+// RedriveMessage 将死信消息重新置为待处理，由分发器在新的投递批次中重新投递
+func (s *MessageService) RedriveMessage(id uint64, userID uint64) (*model.Message, error) {
+	message, err := s.getAuthorizedMessage(id, userID, ActionWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.Status != "dead" {
+		return nil, errors.New("只能重新投递死信消息")
+	}
+
+	requeued, err := s.messageRepo.Requeue([]uint64{id}, []string{"dead"})
+	if err != nil {
+		return nil, err
+	}
+	if requeued == 0 {
+		return nil, ErrMessageConflict
+	}
+	message.Status = "pending"
+	message.Run++
+
+	return message, nil
+}
+
+// MessageDetail 消息及其投递日志
+type MessageDetail struct {
+	*model.Message
+	DeliveryLogs []model.MessageDeliveryLog `json:"deliveryLogs"`
+}
+
+// maxReplayBatch 批量重放的最大消息数
+const maxReplayBatch = 1000
+
+// GetMessages 按条件获取用户可访问主题下的消息列表
+func (s *MessageService) GetMessages(userID uint64, filter repository.MessageFilter, page, pageSize int) ([]model.Message, int64, error) {
+	orgIDs, err := s.authz.OrgIDs(userID, ActionRead)
+	if err != nil {
+		return nil, 0, err
+	}
+	filter.OrgIDs = orgIDs
+
+	offset := (page - 1) * pageSize
+	messages, err := s.messageRepo.FindByFilter(filter, pageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.messageRepo.CountByFilter(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return messages, total, nil
+}
+
+// GetMessageDetail 获取消息及其投递日志
+func (s *MessageService) GetMessageDetail(id uint64, userID uint64) (*MessageDetail, error) {
+	message, err := s.getAuthorizedMessage(id, userID, ActionRead)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := s.deliveryRepo.FindByMessageID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MessageDetail{Message: message, DeliveryLogs: logs}, nil
+}
+
+// ReplayMessage 将已处理的消息重新置为待处理，由分发器按当前路由在新的投递批次中重新投递所有通道
+func (s *MessageService) ReplayMessage(id uint64, userID uint64) (*model.Message, error) {
+	message, err := s.getAuthorizedMessage(id, userID, ActionWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.Status == "pending" || message.Status == "processing" {
+		return nil, fmt.Errorf("%w: 消息正在处理中，不能重放", ErrMessageConflict)
+	}
+
+	requeued, err := s.messageRepo.Requeue([]uint64{id}, repository.FinishedStatuses)
+	if err != nil {
+		return nil, err
+	}
+	if requeued == 0 {
+		return nil, ErrMessageConflict
+	}
+	message.Status = "pending"
+	message.Run++
+
+	return message, nil
+}
+
+// ReplayMessages 批量重放用户有编辑权限的主题下满足条件的已处理消息，最多处理limit条
+func (s *MessageService) ReplayMessages(userID uint64, filter repository.MessageFilter, limit int) ([]model.Message, error) {
+	orgIDs, err := s.authz.OrgIDs(userID, ActionWrite)
+	if err != nil {
+		return nil, err
+	}
+	filter.OrgIDs = orgIDs
+
+	if limit <= 0 || limit > maxReplayBatch {
+		limit = maxReplayBatch
+	}
+
+	messages, err := s.messageRepo.FindReplayable(filter, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return messages, nil
+	}
+
+	// 逐条更新，跳过查询之后被其他请求重放或被分发器抢占的消息
+	requeued := messages[:0]
+	for _, message := range messages {
+		n, err := s.messageRepo.Requeue([]uint64{message.ID}, repository.FinishedStatuses)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			continue
+		}
+		message.Status = "pending"
+		message.Run++
+		requeued = append(requeued, message)
+	}
+
+	return requeued, nil
+}
+
+// getAuthorizedMessage 获取消息并按主题所属组织检查权限
+func (s *MessageService) getAuthorizedMessage(id uint64, userID uint64, action Action) (*model.Message, error) {
+	message, err := s.messageRepo.FindByID(id)
+	if err != nil {
+		return nil, errors.New("消息不存在")
+	}
+
+	topic, err := s.topicRepo.FindByID(message.TopicID)
+	if err != nil {
+		return nil, errors.New("主题不存在")
+	}
+	if err := s.authz.Authorize(userID, topic.OrgID, action); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// ErrDeliveryFailed 所有匹配的通道都发送失败，消息进入死信状态
+var ErrDeliveryFailed = errors.New("所有通道发送失败")
+
+// ProcessMessage 处理消息的一轮投递，返回处理后的消息
+//
+// 每轮中每个通道最多发送一次。可重试的失败记录计划的重试时间，消息重新置为待处理并设置 NextAttemptAt，
+// 由分发器在到期后再次处理，工作协程不等待重试。再次处理同一投递批次时，已有成功、失败或跳过日志的通道不再发送，
+// 等待重试的通道到期后才发送。所有匹配的通道都失败时消息进入死信状态并返回 ErrDeliveryFailed。
+// ctx取消时中断投递并返回ctx.Err()，消息保持处理中状态，由调用方重置为待处理。
+func (s *MessageService) ProcessMessage(ctx context.Context, messageID uint64) (*model.Message, error) {
 	// 获取消息
 	message, err := s.messageRepo.FindByID(messageID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// 更新消息状态为处理中
-	if err := s.messageRepo.UpdateStatus(messageID, "processing"); err != nil {
-		return err
+	if err := s.messageRepo.StartAttempt(messageID); err != nil {
+		return nil, err
 	}
+	message.Status = "processing"
+	message.Attempt++
 
 	// 获取主题
 	topic, err := s.topicRepo.FindByID(message.TopicID)
 	if err != nil {
-		s.messageRepo.UpdateStatus(messageID, "failed")
-		return err
+		s.finishMessage(message, "failed")
+		return message, err
 	}
 
 	// 获取路由规则
 	routings, err := s.routingRepo.FindByTopicID(message.TopicID)
 	if err != nil {
-		s.messageRepo.UpdateStatus(messageID, "failed")
-		return err
+		s.finishMessage(message, "failed")
+		return message, err
 	}
 
 	if len(routings) == 0 {
 		// 没有路由规则，标记为完成
-		s.messageRepo.UpdateStatus(messageID, "completed")
-		return nil
+		s.finishMessage(message, "completed")
+		return message, nil
+	}
+
+	// 本批次中各通道的投递进度，消息被中断或等待重试后再次处理时据此继续
+	states, err := s.channelStates(message)
+	if err != nil {
+		s.finishMessage(message, "failed")
+		return message, err
 	}
 
 	// 根据发送策略处理消息
 	switch topic.SendingStrategy {
 	case "all":
-		return s.processAllStrategy(message, routings)
+		return message, s.processAllStrategy(ctx, message, routings, states)
 	case "failover":
-		return s.processFailoverStrategy(message, routings)
+		return message, s.processFailoverStrategy(ctx, message, routings, states)
 	default:
-		s.messageRepo.UpdateStatus(messageID, "failed")
-		return errors.New("不支持的发送策略")
+		s.finishMessage(message, "failed")
+		return message, errors.New("不支持的发送策略")
+	}
+}
+
+// channelState 通道在当前投递批次中的进度，取自最后一条投递日志
+type channelState struct {
+	status        string // 为空表示还没有发送，否则为success、retrying、failed或skipped
+	attempt       int
+	nextAttemptAt time.Time
+}
+
+// channelStates 返回消息在当前投递批次中各通道的进度
+func (s *MessageService) channelStates(message *model.Message) (map[uint64]channelState, error) {
+	logs, err := s.deliveryRepo.FindByMessageRun(message.ID, message.Run)
+	if err != nil {
+		return nil, err
 	}
+	states := make(map[uint64]channelState, len(logs))
+	for _, deliveryLog := range logs {
+		state := channelState{status: deliveryLog.Status, attempt: deliveryLog.Attempt}
+		if deliveryLog.NextAttemptAt != nil {
+			state.nextAttemptAt = *deliveryLog.NextAttemptAt
+		}
+		states[deliveryLog.ChannelID] = state
+	}
+	return states, nil
 }
 
 // processAllStrategy 处理"发送给所有"策略
-func (s *MessageService) processAllStrategy(message *model.Message, routings []model.Routing) error {
+func (s *MessageService) processAllStrategy(ctx context.Context, message *model.Message, routings []model.Routing, states map[uint64]channelState) error {
 	successCount := 0
-	totalCount := len(routings)
+	totalCount := 0
+	var retryAt time.Time
 
 	for _, routing := range routings {
-		if err := s.sendToChannel(message, &routing); err != nil {
-			// 记录失败日志，但继续处理其他通道
-			s.logDeliveryFailure(message.ID, routing.ChannelID, err.Error())
-		} else {
+		state := states[routing.ChannelID]
+		if state.status == "skipped" || (state.status == "" && !s.matchRouting(message, &routing)) {
+			continue
+		}
+		totalCount++
+
+		// 失败的通道已记录日志，继续处理其他通道
+		status, nextAttemptAt, err := s.deliver(ctx, message, &routing, state)
+		if err != nil {
+			return err
+		}
+		switch status {
+		case "success":
 			successCount++
-			s.logDeliverySuccess(message.ID, routing.ChannelID)
+		case "retrying":
+			if retryAt.IsZero() || nextAttemptAt.Before(retryAt) {
+				retryAt = nextAttemptAt
+			}
 		}
 	}
 
-	// 更新消息状态
-	if successCount == 0 {
-		s.messageRepo.UpdateStatus(message.ID, "failed")
+	// 还有通道等待重试时，到最早的重试时间再处理
+	if !retryAt.IsZero() {
+		return s.scheduleRetry(message, retryAt)
+	}
+
+	// 更新消息状态，所有通道重试耗尽后进入死信状态
+	if totalCount > 0 && successCount == 0 {
+		s.finishMessage(message, "dead")
+		return ErrDeliveryFailed
 	} else if successCount == totalCount {
-		s.messageRepo.UpdateStatus(message.ID, "completed")
+		s.finishMessage(message, "completed")
 	} else {
-		s.messageRepo.UpdateStatus(message.ID, "partial")
+		s.finishMessage(message, "partial")
 	}
 
 	return nil
 }
 
 // processFailoverStrategy 处理"故障转移"策略
-func (s *MessageService) processFailoverStrategy(message *model.Message, routings []model.Routing) error {
+func (s *MessageService) processFailoverStrategy(ctx context.Context, message *model.Message, routings []model.Routing, states map[uint64]channelState) error {
 	// sort routings by priority
 	sort.Slice(routings, func(i, j int) bool {
 		return routings[i].Priority > routings[j].Priority
 	})
 
+	matched := false
 	for _, routing := range routings {
-		if err := s.sendToChannel(message, &routing); err != nil {
-			// 记录失败日志，继续尝试下一个通道
-			s.logDeliveryFailure(message.ID, routing.ChannelID, err.Error())
+		state := states[routing.ChannelID]
+		if state.status == "skipped" || (state.status == "" && !s.matchRouting(message, &routing)) {
 			continue
-		} else {
-			// 发送成功，记录日志并结束
-			s.logDeliverySuccess(message.ID, routing.ChannelID)
-			s.messageRepo.UpdateStatus(message.ID, "completed")
+		}
+		matched = true
+
+		status, nextAttemptAt, err := s.deliver(ctx, message, &routing, state)
+		if err != nil {
+			return err
+		}
+		switch status {
+		case "success":
+			// 发送成功，结束
+			s.finishMessage(message, "completed")
 			return nil
+		case "retrying":
+			// 等待当前通道重试，重试耗尽后再尝试下一个通道
+			return s.scheduleRetry(message, nextAttemptAt)
 		}
 	}
 
-	// 所有通道都失败了
-	s.messageRepo.UpdateStatus(message.ID, "failed")
-	return errors.New("所有通道发送失败")
+	// 没有满足条件的路由，标记为完成
+	if !matched {
+		s.finishMessage(message, "completed")
+		return nil
+	}
+
+	// 所有通道都失败了，进入死信状态
+	s.finishMessage(message, "dead")
+	return ErrDeliveryFailed
 }
 
-// sendToChannel 发送消息到指定通道
-func (s *MessageService) sendToChannel(message *model.Message, routing *model.Routing) error {
-	// 获取通道信息
-	channel, err := s.channelRepo.FindByID(routing.ChannelID)
-	if err != nil {
+// scheduleRetry 将消息重新置为待处理，到达重试时间后由分发器再次处理
+func (s *MessageService) scheduleRetry(message *model.Message, at time.Time) error {
+	if err := s.messageRepo.ScheduleRetry(message.ID, at); err != nil {
 		return err
 	}
+	message.Status = "pending"
+	message.NextAttemptAt = &at
+	return nil
+}
 
-	// 根据通道类型发送消息
-	switch channel.Type {
-	case "telegram":
-		return s.sendToTelegram(message, channel, routing)
-	case "email":
-		return s.sendToEmail(message, channel, routing)
-	case "slack":
-		return s.sendToSlack(message, channel, routing)
-	case "webhook":
-		return s.sendToWebhook(message, channel, routing)
-	default:
-		return errors.New("不支持的通道类型")
-	}
+// finishMessage 更新消息的最终状态并记录指标
+func (s *MessageService) finishMessage(message *model.Message, status string) {
+	s.messageRepo.Finish(message.ID, status)
+	message.Status = status
+	message.NextAttemptAt = nil
+	metrics.MessageFinished(message.TopicID, status)
 }
 
-// sendToTelegram 发送到Telegram
-func (s *MessageService) sendToTelegram(message *model.Message, channel *model.Channel, routing *model.Routing) error {
-	var cfg model.TelegramConfig
-	b, _ := json.Marshal(channel.Credentials)
-	if err := json.Unmarshal(b, &cfg); err != nil {
-		return err
+// matchRouting 判断消息是否满足路由条件，不满足时记录跳过日志
+func (s *MessageService) matchRouting(message *model.Message, routing *model.Routing) bool {
+	if strings.TrimSpace(routing.Condition) == "" {
+		return true
+	}
+
+	expr, err := condition.Compile(routing.Condition)
+	if err != nil {
+		s.logDeliverySkipped(message, routing.ChannelID, err.Error())
+		return false
 	}
 	contentBytes, _ := json.Marshal(message.Content)
+	if !expr.Match(contentBytes) {
+		s.logDeliverySkipped(message, routing.ChannelID, "不满足路由条件: "+routing.Condition)
+		return false
+	}
+	return true
+}
 
-	variables := make(map[string]interface{})
-	for name, path := range routing.VariableMappings {
-		pathStr, ok := path.(string)
-		if !ok {
-			continue
+// deliver 按通道在本批次中的进度发送一次消息并记录投递日志，返回通道的投递状态（success、retrying或failed）
+// 已有结果的通道直接返回其状态；等待重试且未到期的通道不发送，返回计划的重试时间；
+// 可重试的失败按路由的重试策略计算下次重试时间，永久性错误（配置错误、模板错误、4xx响应等）和重试耗尽时返回failed。
+// ctx取消时返回ctx.Err()，不记录日志，消息恢复后重新发送
+func (s *MessageService) deliver(ctx context.Context, message *model.Message, routing *model.Routing, state channelState) (string, time.Time, error) {
+	switch state.status {
+	case "success", "failed":
+		return state.status, time.Time{}, nil
+	case "retrying":
+		if time.Now().Before(state.nextAttemptAt) {
+			return state.status, state.nextAttemptAt, nil
 		}
-		variables[name] = gjson.GetBytes(contentBytes, pathStr).Value()
 	}
-	tmpl, err := template.New("message").Parse(routing.MessageTemplate)
-	if err != nil {
-		return err
-	}
-	var renderedMessage bytes.Buffer
-	if err := tmpl.Execute(&renderedMessage, variables); err != nil {
-		return err
+
+	maxAttempts := routing.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
 	}
+	attempt := state.attempt + 1
 
-	return notifier.SendTelegramMessage(model.TelegramConfig{
-		BotToken:  cfg.BotToken,
-		ChatID:    cfg.ChatID,
-		Proxy:     cfg.Proxy,
-		ParseMode: cfg.ParseMode,
-	}, string(renderedMessage.Bytes()))
-}
+	res, latency, err := s.sendToChannel(ctx, message, routing)
+	if err == nil {
+		s.logDeliverySuccess(message, routing.ChannelID, attempt, res, latency)
+		return "success", time.Time{}, nil
+	}
 
-// sendToEmail 发送到Email
-func (s *MessageService) sendToEmail(message *model.Message, channel *model.Channel, routing *model.Routing) error {
-	var cfg struct {
-		SMTPHost     string `json:"smtpHost"`
-		SMTPPort     int    `json:"smtpPort"`
-		SMTPUsername string `json:"smtpUsername"`
-		SMTPPassword string `json:"smtpPassword"`
-		Sender       string `json:"sender"`
-		To           string `json:"to"`
-		Proxy        string `json:"proxy"`
-	}
-	b, _ := json.Marshal(channel.Credentials)
-	if err := json.Unmarshal(b, &cfg); err != nil {
-		return err
+	// 服务停止导致的中断不计为失败，消息恢复后重新投递
+	if ctx.Err() != nil {
+		return "", time.Time{}, ctx.Err()
 	}
-	contentBytes, _ := json.Marshal(message.Content)
 
-	variables := make(map[string]interface{})
-	for name, path := range routing.VariableMappings {
-		pathStr, ok := path.(string)
-		if !ok {
-			continue
-		}
-		variables[name] = gjson.GetBytes(contentBytes, pathStr).Value()
+	if attempt >= maxAttempts || !notifier.IsRetryable(err) {
+		s.logDeliveryFailure(message, routing.ChannelID, attempt, res, latency, err)
+		return "failed", time.Time{}, nil
 	}
-	tmpl, err := template.New("message").Parse(routing.MessageTemplate)
+
+	delay := retryDelay(routing, attempt)
+	nextAttemptAt := time.Now().Add(delay)
+	s.logDeliveryRetry(message, routing.ChannelID, attempt, res, latency, err, delay, nextAttemptAt)
+	return "retrying", nextAttemptAt, nil
+}
+
+// sendToChannel 发送消息到指定通道，返回发送结果和通道调用耗时
+func (s *MessageService) sendToChannel(ctx context.Context, message *model.Message, routing *model.Routing) (*notifier.Result, time.Duration, error) {
+	// 获取通道信息
+	channel, err := s.channelRepo.FindByID(routing.ChannelID)
 	if err != nil {
-		return err
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, notifier.Permanent(errors.New("通道不存在"))
+		}
+		return nil, 0, err
 	}
-	var renderedMessage bytes.Buffer
-	if err := tmpl.Execute(&renderedMessage, variables); err != nil {
-		return err
+
+	// 根据通道类型获取发送实现
+	n, ok := notifier.Get(channel.Type)
+	if !ok {
+		return nil, 0, notifier.Permanent(errors.New("不支持的通道类型"))
 	}
-	subjectTmpl, err := template.New("subject").Parse(routing.SubjectTemplate)
+
+	rendered, err := s.renderService.Render(channel, routing, message.Content)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
-	var renderedSubject bytes.Buffer
-	if err := subjectTmpl.Execute(&renderedSubject, variables); err != nil {
-		return err
+	if err := rendered.Err(); err != nil {
+		metrics.TemplateFailure(channel.Type)
+		return nil, 0, notifier.Permanent(err)
+	}
+	msg := &notifier.Message{
+		ChannelID: channel.ID,
+		Subject:   rendered.Subject,
+		Body:      rendered.Body,
+		HTMLBody:  rendered.HTML,
+		Payload:   message.Content,
+		Variables: rendered.Variables,
+		Options:   routing.Options,
 	}
-	return notifier.SendEmail(notifier.EmailConfig{
-		Host:     cfg.SMTPHost,
-		Port:     cfg.SMTPPort,
-		Username: cfg.SMTPUsername,
-		Password: cfg.SMTPPassword,
-		From:     cfg.Sender,
-		To:       cfg.To,
-		Proxy:    cfg.Proxy,
-	}, renderedSubject.String(), renderedMessage.String())
-}
 
-// sendToSlack 发送到Slack
-func (s *MessageService) sendToSlack(message *model.Message, channel *model.Channel, routing *model.Routing) error {
-	// TODO: 实现Slack发送逻辑
-	// 1. 解析Slack配置
-	// 2. 渲染消息模板
-	// 3. 调用Slack API
-	return nil
+	start := time.Now()
+	res, err := n.Send(ctx, notifier.Credentials(channel.Credentials), msg)
+	latency := time.Since(start)
+	metrics.DeliveryAttempt(channel.Type, channel.ID, err, latency)
+	return res, latency, err
 }
 
-// sendToWebhook 发送到Webhook
-func (s *MessageService) sendToWebhook(message *model.Message, channel *model.Channel, routing *model.Routing) error {
-	var cfg struct {
-		URL     string            `json:"url"`
-		Method  string            `json:"method"`
-		Headers map[string]string `json:"headers"`
-		Proxy   string            `json:"proxy"`
+// maxResponseLength 投递日志中保存的响应内容最大字节数
+const maxResponseLength = 2048
+
+// newDeliveryLog 构造投递日志，填充状态码、耗时、响应和服务方消息ID
+func newDeliveryLog(message *model.Message, channelID uint64, attempt int, status string, res *notifier.Result, latency time.Duration, response string) *model.MessageDeliveryLog {
+	deliveryLog := &model.MessageDeliveryLog{
+		MessageID: message.ID,
+		ChannelID: channelID,
+		Run:       message.Run,
+		Attempt:   attempt,
+		Status:    status,
+		LatencyMs: latency.Milliseconds(),
 	}
-	b, _ := json.Marshal(channel.Credentials)
-	if err := json.Unmarshal(b, &cfg); err != nil {
-		return err
+	if res != nil {
+		deliveryLog.StatusCode = res.StatusCode
+		deliveryLog.ProviderMessageID = truncate(res.ProviderMessageID, 255)
+		if res.Response != "" {
+			if response != "" {
+				response += "\n"
+			}
+			response += res.Response
+		}
 	}
-	contentBytes, _ := json.Marshal(message.Content)
+	deliveryLog.Response = truncate(response, maxResponseLength)
+	return deliveryLog
+}
 
-	resp, err := notifier.SendWebhook(notifier.WebhookConfig{
-		URL:     cfg.URL,
-		Method:  cfg.Method,
-		Headers: cfg.Headers,
-		Proxy:   cfg.Proxy,
-	}, contentBytes)
-	if err != nil {
-		return err
+// truncate 按字节截断字符串，不截断UTF-8字符
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
 	}
-	_ = resp // 可记录响应内容
-	return nil
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
 }
 
 // logDeliverySuccess 记录发送成功日志
-func (s *MessageService) logDeliverySuccess(messageID, channelID uint64) {
-	deliveryLog := &model.MessageDeliveryLog{
-		MessageID: messageID,
-		ChannelID: channelID,
-		Status:    "success",
-		Response:  "发送成功",
+func (s *MessageService) logDeliverySuccess(message *model.Message, channelID uint64, attempt int, res *notifier.Result, latency time.Duration) {
+	deliveryLog := newDeliveryLog(message, channelID, attempt, "success", res, latency, "")
+	if deliveryLog.Response == "" {
+		deliveryLog.Response = "发送成功"
+	}
+	s.deliveryRepo.Create(deliveryLog)
+}
+
+// logDeliveryRetry 记录发送失败且将重试的日志
+func (s *MessageService) logDeliveryRetry(message *model.Message, channelID uint64, attempt int, res *notifier.Result, latency time.Duration, err error, delay time.Duration, nextAttemptAt time.Time) {
+	deliveryLog := newDeliveryLog(message, channelID, attempt, "retrying", res, latency, fmt.Sprintf("%s（%s后重试）", err.Error(), delay.Round(time.Millisecond)))
+	deliveryLog.NextAttemptAt = &nextAttemptAt
+	if deliveryLog.StatusCode == 0 {
+		deliveryLog.StatusCode = notifier.StatusCodeOf(err)
 	}
 	s.deliveryRepo.Create(deliveryLog)
 }
 
 // logDeliveryFailure 记录发送失败日志
-func (s *MessageService) logDeliveryFailure(messageID, channelID uint64, errorMsg string) {
+func (s *MessageService) logDeliveryFailure(message *model.Message, channelID uint64, attempt int, res *notifier.Result, latency time.Duration, err error) {
+	deliveryLog := newDeliveryLog(message, channelID, attempt, "failed", res, latency, err.Error())
+	if deliveryLog.StatusCode == 0 {
+		deliveryLog.StatusCode = notifier.StatusCodeOf(err)
+	}
+	s.deliveryRepo.Create(deliveryLog)
+}
+
+// logDeliverySkipped 记录路由跳过日志
+func (s *MessageService) logDeliverySkipped(message *model.Message, channelID uint64, reason string) {
 	deliveryLog := &model.MessageDeliveryLog{
-		MessageID: messageID,
+		MessageID: message.ID,
 		ChannelID: channelID,
-		Status:    "failed",
-		Response:  errorMsg,
+		Run:       message.Run,
+		Status:    "skipped",
+		Response:  reason,
 	}
 	s.deliveryRepo.Create(deliveryLog)
 }
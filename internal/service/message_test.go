@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"synapse/internal/model"
+	"synapse/internal/repository"
+	"synapse/pkg/notifier"
+)
+
+func TestProcessMessageSkipsDeliveredChannels(t *testing.T) {
+	db := openTestDB(t)
+	s := NewMessageService(db)
+	topic, channels := createTopic(t, db, "all", model.Routing{}, model.Routing{}, model.Routing{})
+	message := createMessage(t, db, topic)
+
+	// 模拟上次处理被中断：第一个通道已发送成功，第二个通道已失败，第三个通道还没有发送
+	db.Create(&model.MessageDeliveryLog{MessageID: message.ID, ChannelID: channels[0], Attempt: 1, Status: "success"})
+	db.Create(&model.MessageDeliveryLog{MessageID: message.ID, ChannelID: channels[1], Attempt: 1, Status: "failed"})
+
+	recorder.reset(nil)
+	if _, err := s.ProcessMessage(context.Background(), message.ID); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []int{0, 0, 1} {
+		if got := recorder.sent(channels[i]); got != want {
+			t.Errorf("通道%d发送%d次, want %d", i, got, want)
+		}
+	}
+	if got, _ := s.GetMessageByID(message.ID); got.Status != "partial" {
+		t.Errorf("status = %s, want partial", got.Status)
+	}
+}
+
+func TestProcessMessageFailoverResumes(t *testing.T) {
+	db := openTestDB(t)
+	s := NewMessageService(db)
+	topic, channels := createTopic(t, db, "failover", model.Routing{Priority: 2}, model.Routing{Priority: 1})
+
+	// 高优先级通道已成功，不再发送任何通道
+	message := createMessage(t, db, topic)
+	db.Create(&model.MessageDeliveryLog{MessageID: message.ID, ChannelID: channels[0], Attempt: 1, Status: "success"})
+	recorder.reset(nil)
+	if _, err := s.ProcessMessage(context.Background(), message.ID); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.sent(channels[0])+recorder.sent(channels[1]) != 0 {
+		t.Error("已成功的消息不应再次发送")
+	}
+
+	// 高优先级通道已失败，从下一个通道继续
+	message = createMessage(t, db, topic)
+	db.Create(&model.MessageDeliveryLog{MessageID: message.ID, ChannelID: channels[0], Attempt: 1, Status: "failed"})
+	recorder.reset(nil)
+	if _, err := s.ProcessMessage(context.Background(), message.ID); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.sent(channels[0]) != 0 || recorder.sent(channels[1]) != 1 {
+		t.Errorf("sends = %d, %d, want 0, 1", recorder.sent(channels[0]), recorder.sent(channels[1]))
+	}
+}
+
+func TestReplayStartsNewRun(t *testing.T) {
+	db := openTestDB(t)
+	s := NewMessageService(db)
+	topic, channels := createTopic(t, db, "all", model.Routing{}, model.Routing{})
+	message := createMessage(t, db, topic)
+
+	recorder.reset(nil)
+	if _, err := s.ProcessMessage(context.Background(), message.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.messageRepo.Requeue([]uint64{message.ID}, repository.FinishedStatuses); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ProcessMessage(context.Background(), message.ID); err != nil {
+		t.Fatal(err)
+	}
+	for i := range channels {
+		if got := recorder.sent(channels[i]); got != 2 {
+			t.Errorf("通道%d发送%d次, want 2", i, got)
+		}
+	}
+
+	logs, _ := s.deliveryRepo.FindByMessageRun(message.ID, 1)
+	if len(logs) != len(channels) {
+		t.Errorf("第二批次的投递日志 %d 条, want %d", len(logs), len(channels))
+	}
+}
+
+func TestReplayDoesNotRequeueInFlightMessages(t *testing.T) {
+	db := openTestDB(t)
+	s := NewMessageService(db)
+	user := registerUser(t, db, "alice")
+	topic, _ := createTopic(t, db, "all", model.Routing{})
+	message := createMessage(t, db, topic)
+
+	recorder.reset(nil)
+	if _, err := s.ProcessMessage(context.Background(), message.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// 连续两次重放，第二次的消息已是待处理状态
+	if _, err := s.ReplayMessage(message.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReplayMessage(message.ID, user.ID); !errors.Is(err, ErrMessageConflict) {
+		t.Fatalf("second ReplayMessage() = %v, want ErrMessageConflict", err)
+	}
+
+	// 读取状态后消息被分发器抢占，更新时按状态检查不再生效
+	if ok, err := s.messageRepo.ClaimPending(message.ID); err != nil || !ok {
+		t.Fatalf("ClaimPending() = %v, %v", ok, err)
+	}
+	if n, err := s.messageRepo.Requeue([]uint64{message.ID}, repository.FinishedStatuses); err != nil || n != 0 {
+		t.Fatalf("Requeue() on processing message = %d, %v, want 0", n, err)
+	}
+	if _, err := s.messageRepo.Requeue([]uint64{message.ID}, []string{"dead"}); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, _ := s.messageRepo.FindByID(message.ID)
+	if reloaded.Status != "processing" || reloaded.Run != 1 {
+		t.Fatalf("status = %s, run = %d, want processing, 1", reloaded.Status, reloaded.Run)
+	}
+
+	// 批量重放只返回实际重新置为待处理的消息
+	db.Model(&model.Message{}).Where("id = ?", message.ID).Update("status", "dead")
+	replayed, err := s.ReplayMessages(user.ID, repository.MessageFilter{}, 0)
+	if err != nil || len(replayed) != 1 || replayed[0].Run != 2 {
+		t.Fatalf("ReplayMessages() = %v, %v", replayed, err)
+	}
+	if replayed, err := s.ReplayMessages(user.ID, repository.MessageFilter{}, 0); err != nil || len(replayed) != 0 {
+		t.Fatalf("second ReplayMessages() = %v, %v", replayed, err)
+	}
+}
+
+func TestProcessMessageSchedulesRetry(t *testing.T) {
+	db := openTestDB(t)
+	s := NewMessageService(db)
+	topic, channels := createTopic(t, db, "all",
+		model.Routing{MaxAttempts: 3, RetryInitialDelay: 60000, RetryMaxDelay: 60000},
+		model.Routing{})
+	message := createMessage(t, db, topic)
+
+	recorder.reset(map[uint64][]error{channels[0]: {notifier.StatusError(503, errors.New("unavailable"))}})
+	got, err := s.ProcessMessage(context.Background(), message.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != "pending" || got.NextAttemptAt == nil || !got.NextAttemptAt.After(time.Now()) {
+		t.Fatalf("失败后应等待重试: status=%s nextAttemptAt=%v", got.Status, got.NextAttemptAt)
+	}
+	if pending, _ := s.messageRepo.FindPending(nil, nil, 10); len(pending) != 0 {
+		t.Error("未到重试时间的消息不应被拉取")
+	}
+
+	// 未到重试时间再次处理时不发送
+	if _, err := s.ProcessMessage(context.Background(), message.ID); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.sent(channels[0]) != 1 || recorder.sent(channels[1]) != 1 {
+		t.Fatalf("sends = %d, %d, want 1, 1", recorder.sent(channels[0]), recorder.sent(channels[1]))
+	}
+
+	// 到期后只重试失败的通道
+	past := time.Now().Add(-time.Second)
+	db.Model(&model.MessageDeliveryLog{}).Where("message_id = ? AND status = ?", message.ID, "retrying").Update("next_attempt_at", past)
+	db.Model(&model.Message{}).Where("id = ?", message.ID).Update("next_attempt_at", past)
+	if pending, _ := s.messageRepo.FindPending(nil, nil, 10); len(pending) != 1 {
+		t.Error("到期的消息应被拉取")
+	}
+	got, err = s.ProcessMessage(context.Background(), message.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != "completed" || got.NextAttemptAt != nil {
+		t.Errorf("status = %s, nextAttemptAt = %v, want completed", got.Status, got.NextAttemptAt)
+	}
+	if recorder.sent(channels[0]) != 2 || recorder.sent(channels[1]) != 1 {
+		t.Errorf("sends = %d, %d, want 2, 1", recorder.sent(channels[0]), recorder.sent(channels[1]))
+	}
+
+	logs, _ := s.deliveryRepo.FindByMessageRun(message.ID, 0)
+	var attempts []int
+	for _, l := range logs {
+		if l.ChannelID == channels[0] {
+			attempts = append(attempts, l.Attempt)
+		}
+	}
+	if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 2 {
+		t.Errorf("attempts = %v, want [1 2]", attempts)
+	}
+}
+
+func TestProcessMessageDeadLetter(t *testing.T) {
+	for _, strategy := range []string{"all", "failover"} {
+		t.Run(strategy, func(t *testing.T) {
+			db := openTestDB(t)
+			s := NewMessageService(db)
+			topic, channels := createTopic(t, db, strategy, model.Routing{MaxAttempts: 3}, model.Routing{MaxAttempts: 3})
+			message := createMessage(t, db, topic)
+
+			recorder.reset(map[uint64][]error{
+				channels[0]: {notifier.StatusError(400, errors.New("bad request"))},
+				channels[1]: {notifier.Permanent(errors.New("invalid"))},
+			})
+			got, err := s.ProcessMessage(context.Background(), message.ID)
+			if !errors.Is(err, ErrDeliveryFailed) {
+				t.Errorf("err = %v, want ErrDeliveryFailed", err)
+			}
+			if got.Status != "dead" {
+				t.Errorf("status = %s, want dead", got.Status)
+			}
+		})
+	}
+}
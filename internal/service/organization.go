@@ -0,0 +1,221 @@
+package service
+
+import (
+	"errors"
+	"synapse/internal/model"
+	"synapse/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type OrganizationService struct {
+	orgRepo  *repository.OrganizationRepository
+	userRepo *repository.UserRepository
+	authz    *Authorizer
+}
+
+func NewOrganizationService(db *gorm.DB) *OrganizationService {
+	return &OrganizationService{
+		orgRepo:  repository.NewOrganizationRepository(db),
+		userRepo: repository.NewUserRepository(db),
+		authz:    NewAuthorizer(db),
+	}
+}
+
+// OrganizationInfo 组织及当前用户的角色
+type OrganizationInfo struct {
+	model.Organization
+	Role string `json:"role"`
+}
+
+// OrganizationDetail 组织详情及成员列表
+type OrganizationDetail struct {
+	OrganizationInfo
+	Members []model.OrganizationMember `json:"members"`
+}
+
+// CreateOrganization 创建组织，创建者成为所有者
+func (s *OrganizationService) CreateOrganization(org *model.Organization, userID uint64) error {
+	org.Personal = false
+	return s.orgRepo.CreateWithOwner(org, userID)
+}
+
+// GetOrganizations 获取用户所属的所有组织，首次访问时创建个人组织
+func (s *OrganizationService) GetOrganizations(userID uint64) ([]OrganizationInfo, error) {
+	if _, err := s.authz.PersonalOrgID(userID); err != nil {
+		return nil, err
+	}
+
+	orgs, err := s.orgRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]OrganizationInfo, 0, len(orgs))
+	for _, org := range orgs {
+		role, err := s.authz.Role(userID, org.ID)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, OrganizationInfo{Organization: org, Role: role})
+	}
+	return infos, nil
+}
+
+// GetOrganization 获取组织详情及成员
+func (s *OrganizationService) GetOrganization(id uint64, userID uint64) (*OrganizationDetail, error) {
+	role, err := s.authz.Role(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := s.orgRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.orgRepo.FindMembers(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OrganizationDetail{
+		OrganizationInfo: OrganizationInfo{Organization: *org, Role: role},
+		Members:          members,
+	}, nil
+}
+
+// UpdateOrganization 更新组织名称
+func (s *OrganizationService) UpdateOrganization(org *model.Organization, userID uint64) error {
+	if err := s.authz.Authorize(userID, org.ID, ActionManage); err != nil {
+		return err
+	}
+
+	existingOrg, err := s.orgRepo.FindByID(org.ID)
+	if err != nil {
+		return err
+	}
+
+	existingOrg.Name = org.Name
+	if err := s.orgRepo.Update(existingOrg); err != nil {
+		return err
+	}
+	*org = *existingOrg
+	return nil
+}
+
+// DeleteOrganization 删除组织，个人组织和仍有主题或通道的组织不能删除
+func (s *OrganizationService) DeleteOrganization(id uint64, userID uint64) error {
+	if err := s.authz.Authorize(userID, id, ActionOwn); err != nil {
+		return err
+	}
+
+	org, err := s.orgRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if org.Personal {
+		return errors.New("个人组织不能删除")
+	}
+
+	count, err := s.orgRepo.CountResources(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("组织下仍有主题或通道，请先删除")
+	}
+
+	return s.orgRepo.Delete(id)
+}
+
+// AddMember 按用户名添加组织成员
+func (s *OrganizationService) AddMember(orgID uint64, username, role string, userID uint64) (*model.OrganizationMember, error) {
+	if !IsValidRole(role) {
+		return nil, errors.New("不支持的角色")
+	}
+	if err := s.authorizeRoleChange(orgID, userID, role, ""); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByUsername(username)
+	if err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	if _, err := s.orgRepo.FindMember(orgID, user.ID); err == nil {
+		return nil, errors.New("用户已是组织成员")
+	}
+
+	member := &model.OrganizationMember{OrgID: orgID, UserID: user.ID, Role: role}
+	if err := s.orgRepo.SaveMember(member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// UpdateMember 修改成员角色
+func (s *OrganizationService) UpdateMember(orgID, memberID uint64, role string, userID uint64) (*model.OrganizationMember, error) {
+	if !IsValidRole(role) {
+		return nil, errors.New("不支持的角色")
+	}
+
+	member, err := s.orgRepo.FindMember(orgID, memberID)
+	if err != nil {
+		return nil, errors.New("成员不存在")
+	}
+	if err := s.authorizeRoleChange(orgID, userID, role, member.Role); err != nil {
+		return nil, err
+	}
+	if member.Role == model.RoleOwner && role != model.RoleOwner {
+		if err := s.ensureAnotherOwner(orgID); err != nil {
+			return nil, err
+		}
+	}
+
+	member.Role = role
+	if err := s.orgRepo.SaveMember(member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveMember 移除成员，成员可以自行退出组织
+func (s *OrganizationService) RemoveMember(orgID, memberID uint64, userID uint64) error {
+	member, err := s.orgRepo.FindMember(orgID, memberID)
+	if err != nil {
+		return errors.New("成员不存在")
+	}
+	if memberID != userID {
+		if err := s.authorizeRoleChange(orgID, userID, "", member.Role); err != nil {
+			return err
+		}
+	}
+	if member.Role == model.RoleOwner {
+		if err := s.ensureAnotherOwner(orgID); err != nil {
+			return err
+		}
+	}
+
+	return s.orgRepo.DeleteMember(orgID, memberID)
+}
+
+// authorizeRoleChange 管理成员需要管理员权限，授予或变更所有者角色需要所有者权限
+func (s *OrganizationService) authorizeRoleChange(orgID, userID uint64, newRole, oldRole string) error {
+	action := ActionManage
+	if newRole == model.RoleOwner || oldRole == model.RoleOwner {
+		action = ActionOwn
+	}
+	return s.authz.Authorize(userID, orgID, action)
+}
+
+// ensureAnotherOwner 确保组织在变更后仍至少有一个所有者
+func (s *OrganizationService) ensureAnotherOwner(orgID uint64) error {
+	count, err := s.orgRepo.CountOwners(orgID)
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return errors.New("组织至少需要一个所有者")
+	}
+	return nil
+}
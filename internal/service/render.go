@@ -0,0 +1,146 @@
+package service
+
+import (
+	"encoding/json"
+	"synapse/internal/model"
+	"synapse/internal/repository"
+	"synapse/pkg/notifier"
+	"synapse/pkg/render"
+
+	"github.com/tidwall/gjson"
+	"gorm.io/gorm"
+)
+
+// RenderService 按通道选择模板引擎并渲染路由的主题和正文
+//
+// 模板数据为变量映射的结果和完整的原始消息内容 .Payload，可通过 {{template "名称" .}} 引用组织的模板片段。
+// 通道实现 notifier.TemplateEngine 时使用其返回的引擎，否则使用text引擎；
+// text引擎下变量先经通道的 notifier.Escaper 转义，html引擎下由模板按上下文转义。
+// .Payload 始终为原始值，需要时使用 escapeMarkdownV2 等函数自行转义。
+// HTML正文模板（邮件）始终使用html引擎和未转义的变量。
+type RenderService struct {
+	partialRepo *repository.TemplatePartialRepository
+}
+
+func NewRenderService(db *gorm.DB) *RenderService {
+	return &RenderService{
+		partialRepo: repository.NewTemplatePartialRepository(db),
+	}
+}
+
+// RenderedMessage 渲染结果，主题和正文的错误分别记录
+type RenderedMessage struct {
+	Variables  map[string]interface{} // 变量映射解析出的变量（未转义）
+	Subject    string
+	Body       string
+	HTML       string
+	SubjectErr error
+	BodyErr    error
+	HTMLErr    error
+}
+
+// Err 返回第一个渲染错误
+func (m *RenderedMessage) Err() error {
+	if m.BodyErr != nil {
+		return m.BodyErr
+	}
+	if m.HTMLErr != nil {
+		return m.HTMLErr
+	}
+	return m.SubjectErr
+}
+
+// Render 使用路由的变量映射和模板渲染消息内容，只有加载模板片段失败时返回错误
+func (s *RenderService) Render(channel *model.Channel, routing *model.Routing, payload map[string]interface{}) (*RenderedMessage, error) {
+	partials, err := s.partials(routing.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	engine := channelEngine(channel, routing)
+	renderer := render.New(engine, partials)
+
+	credentials := notifier.Credentials(channel.Credentials)
+	var escaper notifier.Escaper
+	if n, ok := notifier.Get(channel.Type); ok && engine == render.EngineText {
+		escaper, _ = n.(notifier.Escaper)
+	}
+	raw, escaped := resolveVariables(payload, routing, escaper, credentials)
+	data := render.Data(escaped, payload)
+
+	rendered := &RenderedMessage{Variables: raw}
+	rendered.Subject, rendered.SubjectErr = renderer.Render("subject", routing.SubjectTemplate, data)
+	rendered.Body, rendered.BodyErr = renderer.Render("message", routing.MessageTemplate, data)
+	rendered.HTML, rendered.HTMLErr = render.New(render.EngineHTML, partials).Render("html", routing.HTMLTemplate, render.Data(raw, payload))
+	return rendered, nil
+}
+
+// Validate 使用通道对应的引擎和组织的模板片段检查路由模板语法
+func (s *RenderService) Validate(channel *model.Channel, routing *model.Routing) error {
+	partials, err := s.partials(routing.OrgID)
+	if err != nil {
+		return err
+	}
+	renderer := render.New(channelEngine(channel, routing), partials)
+	if err := renderer.Parse("subject", routing.SubjectTemplate); err != nil {
+		return err
+	}
+	if err := renderer.Parse("message", routing.MessageTemplate); err != nil {
+		return err
+	}
+	return render.New(render.EngineHTML, partials).Parse("html", routing.HTMLTemplate)
+}
+
+// partials 加载组织的模板片段
+func (s *RenderService) partials(orgID uint64) (map[string]string, error) {
+	partials, err := s.partialRepo.FindByOrgIDs([]uint64{orgID})
+	if err != nil {
+		return nil, err
+	}
+	contents := make(map[string]string, len(partials))
+	for _, partial := range partials {
+		contents[partial.Name] = partial.Content
+	}
+	return contents, nil
+}
+
+// channelEngine 返回通道渲染主题和正文使用的模板引擎
+func channelEngine(channel *model.Channel, routing *model.Routing) string {
+	n, _ := notifier.Get(channel.Type)
+	if te, ok := n.(notifier.TemplateEngine); ok {
+		if engine := te.TemplateEngine(notifier.Credentials(channel.Credentials), routing.Options); render.IsValidEngine(engine) {
+			return engine
+		}
+	}
+	return render.EngineText
+}
+
+// resolveVariables 按路由的变量映射从消息内容中提取变量，返回原始值和经通道转义后用于模板的值
+// 通道需要转义时，字符串、数字和布尔值都经过转义，对象和数组保持原样
+func resolveVariables(content map[string]interface{}, routing *model.Routing, escaper notifier.Escaper, credentials notifier.Credentials) (raw, escaped map[string]interface{}) {
+	contentBytes, _ := json.Marshal(content)
+
+	raw = make(map[string]interface{})
+	escaped = make(map[string]interface{})
+	for name, path := range routing.VariableMappings {
+		pathStr, ok := path.(string)
+		if !ok {
+			continue
+		}
+		result := gjson.GetBytes(contentBytes, pathStr)
+		value := result.Value()
+		raw[name] = value
+		if escaper != nil {
+			switch result.Type {
+			case gjson.String:
+				value = escaper.Escape(result.Str, credentials, routing.Options)
+			case gjson.Number, gjson.True, gjson.False:
+				// 按JSON中的写法转义，如MarkdownV2中数字的 "." 和 "-"；无需转义时保持原类型，模板中仍可比较大小
+				if text := escaper.Escape(result.Raw, credentials, routing.Options); text != result.Raw {
+					value = text
+				}
+			}
+		}
+		escaped[name] = value
+	}
+	return raw, escaped
+}
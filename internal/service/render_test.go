@@ -0,0 +1,62 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"synapse/internal/model"
+	"synapse/pkg/notifier"
+)
+
+func TestResolveVariablesEscapesScalars(t *testing.T) {
+	n, _ := notifier.Get("telegram")
+	escaper := n.(notifier.Escaper)
+	credentials := notifier.Credentials{"parseMode": "MarkdownV2"}
+
+	payload := map[string]interface{}{
+		"title":   "v1.2-rc",
+		"cpu":     97.5,
+		"delta":   float64(-3),
+		"count":   float64(42),
+		"firing":  true,
+		"labels":  map[string]interface{}{"env": "prod"},
+		"missing": nil,
+	}
+	routing := &model.Routing{VariableMappings: model.JSON{
+		"title":   "title",
+		"cpu":     "cpu",
+		"delta":   "delta",
+		"count":   "count",
+		"firing":  "firing",
+		"labels":  "labels",
+		"missing": "missing",
+		"absent":  "no.such.path",
+	}}
+
+	raw, escaped := resolveVariables(payload, routing, escaper, credentials)
+
+	wantEscaped := map[string]interface{}{
+		"title":   `v1\.2\-rc`,
+		"cpu":     `97\.5`,
+		"delta":   `\-3`,
+		"count":   float64(42), // 无需转义的数字保持原类型
+		"firing":  true,
+		"labels":  map[string]interface{}{"env": "prod"},
+		"missing": nil,
+		"absent":  nil,
+	}
+	if !reflect.DeepEqual(escaped, wantEscaped) {
+		t.Fatalf("escaped = %#v, want %#v", escaped, wantEscaped)
+	}
+	if raw["cpu"] != 97.5 || raw["delta"] != float64(-3) || raw["title"] != "v1.2-rc" {
+		t.Fatalf("raw values changed: %#v", raw)
+	}
+}
+
+func TestResolveVariablesWithoutEscaper(t *testing.T) {
+	routing := &model.Routing{VariableMappings: model.JSON{"cpu": "cpu", "title": "title"}}
+	_, escaped := resolveVariables(map[string]interface{}{"cpu": 97.5, "title": "a.b"}, routing, nil, nil)
+	if escaped["cpu"] != 97.5 || escaped["title"] != "a.b" {
+		t.Fatalf("escaped = %#v, want raw values", escaped)
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"synapse/internal/model"
+	"time"
+)
+
+const (
+	defaultRetryInitialDelay = 1000  // 毫秒
+	defaultRetryMaxDelay     = 60000 // 毫秒
+	defaultRetryMultiplier   = 2.0
+	maxRetryAttempts         = 10
+)
+
+// DefaultRetryJitter 未填写抖动比例时的默认值，0表示不抖动，因此不能由 normalizeRetryPolicy 填充
+const DefaultRetryJitter = 0.2
+
+// normalizeRetryPolicy 填充路由重试策略的默认值并校验取值范围
+func normalizeRetryPolicy(routing *model.Routing) error {
+	if routing.MaxAttempts <= 0 {
+		routing.MaxAttempts = 1
+	}
+	if routing.MaxAttempts > maxRetryAttempts {
+		return errors.New("最大尝试次数不能超过10次")
+	}
+	if routing.RetryInitialDelay <= 0 {
+		routing.RetryInitialDelay = defaultRetryInitialDelay
+	}
+	if routing.RetryMaxDelay <= 0 {
+		routing.RetryMaxDelay = defaultRetryMaxDelay
+	}
+	if routing.RetryMaxDelay < routing.RetryInitialDelay {
+		return errors.New("最大重试延迟不能小于首次重试延迟")
+	}
+	if routing.RetryMultiplier == 0 {
+		routing.RetryMultiplier = defaultRetryMultiplier
+	}
+	if routing.RetryMultiplier < 1 {
+		return errors.New("重试延迟倍数不能小于1")
+	}
+	if routing.RetryJitter < 0 || routing.RetryJitter > 1 {
+		return errors.New("重试延迟抖动比例必须在0到1之间")
+	}
+	return nil
+}
+
+// retryDelay 计算第attempt次失败后的等待时间（指数退避 + 随机抖动）
+func retryDelay(routing *model.Routing, attempt int) time.Duration {
+	initial := routing.RetryInitialDelay
+	if initial <= 0 {
+		initial = defaultRetryInitialDelay
+	}
+	maxDelay := routing.RetryMaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+	multiplier := routing.RetryMultiplier
+	if multiplier < 1 {
+		multiplier = defaultRetryMultiplier
+	}
+
+	delay := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	if delay > float64(maxDelay) {
+		delay = float64(maxDelay)
+	}
+	if routing.RetryJitter > 0 {
+		delay += delay * routing.RetryJitter * (rand.Float64()*2 - 1)
+	}
+	return time.Duration(delay) * time.Millisecond
+}
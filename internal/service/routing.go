@@ -1,46 +1,46 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"synapse/internal/model"
 	"synapse/internal/repository"
+	"synapse/pkg/condition"
+	"synapse/pkg/notifier"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type RoutingService struct {
-	routingRepo *repository.RoutingRepository
-	topicRepo   *repository.TopicRepository
-	channelRepo *repository.ChannelRepository
+	routingRepo   *repository.RoutingRepository
+	topicRepo     *repository.TopicRepository
+	channelRepo   *repository.ChannelRepository
+	renderService *RenderService
+	authz         *Authorizer
 }
 
 func NewRoutingService(db *gorm.DB) *RoutingService {
 	return &RoutingService{
-		routingRepo: repository.NewRoutingRepository(db),
-		topicRepo:   repository.NewTopicRepository(db),
-		channelRepo: repository.NewChannelRepository(db),
+		routingRepo:   repository.NewRoutingRepository(db),
+		topicRepo:     repository.NewTopicRepository(db),
+		channelRepo:   repository.NewChannelRepository(db),
+		renderService: NewRenderService(db),
+		authz:         NewAuthorizer(db),
 	}
 }
 
 // CreateRouting 创建路由
 func (s *RoutingService) CreateRouting(routing *model.Routing, userID uint64) error {
-	// 验证主题所有权
-	topic, err := s.topicRepo.FindByID(routing.TopicID)
+	topic, channel, err := s.authorizeRouting(routing.TopicID, routing.ChannelID, userID)
 	if err != nil {
-		return errors.New("主题不存在")
-	}
-	if topic.UserID != userID {
-		return errors.New("无权访问此主题")
+		return err
 	}
 
-	// 验证通道所有权
-	channel, err := s.channelRepo.FindByID(routing.ChannelID)
-	if err != nil {
-		return errors.New("通道不存在")
-	}
-	if channel.UserID != userID {
-		return errors.New("无权访问此通道")
-	}
+	routing.OrgID = topic.OrgID
 
 	// 检查路由是否已存在
 	existingRouting, err := s.routingRepo.FindByTopicAndChannel(routing.TopicID, routing.ChannelID)
@@ -48,18 +48,37 @@ func (s *RoutingService) CreateRouting(routing *model.Routing, userID uint64) er
 		return errors.New("路由已存在")
 	}
 
+	// 校验通道选项
+	if err := validateRoutingOptions(channel.Type, routing.Options); err != nil {
+		return err
+	}
+
+	// 校验路由条件
+	if err := validateRoutingCondition(routing.Condition); err != nil {
+		return err
+	}
+
+	// 校验模板语法
+	if err := s.renderService.Validate(channel, routing); err != nil {
+		return fmt.Errorf("模板错误: %v", err)
+	}
+
+	// 校验重试策略
+	if err := normalizeRetryPolicy(routing); err != nil {
+		return err
+	}
+
 	return s.routingRepo.Create(routing)
 }
 
 // GetRoutingsByTopicID 获取主题的所有路由
 func (s *RoutingService) GetRoutingsByTopicID(topicID uint64, userID uint64) ([]model.Routing, error) {
-	// 验证主题所有权
 	topic, err := s.topicRepo.FindByID(topicID)
 	if err != nil {
 		return nil, errors.New("主题不存在")
 	}
-	if topic.UserID != userID {
-		return nil, errors.New("无权访问此主题")
+	if err := s.authz.Authorize(userID, topic.OrgID, ActionRead); err != nil {
+		return nil, err
 	}
 
 	return s.routingRepo.FindByTopicID(topicID)
@@ -67,13 +86,12 @@ func (s *RoutingService) GetRoutingsByTopicID(topicID uint64, userID uint64) ([]
 
 // GetRoutingsByChannelID 获取通道的所有路由
 func (s *RoutingService) GetRoutingsByChannelID(channelID uint64, userID uint64) ([]model.Routing, error) {
-	// 验证通道所有权
 	channel, err := s.channelRepo.FindByID(channelID)
 	if err != nil {
 		return nil, errors.New("通道不存在")
 	}
-	if channel.UserID != userID {
-		return nil, errors.New("无权访问此通道")
+	if err := s.authz.Authorize(userID, channel.OrgID, ActionRead); err != nil {
+		return nil, err
 	}
 
 	return s.routingRepo.FindByChannelID(channelID)
@@ -81,22 +99,9 @@ func (s *RoutingService) GetRoutingsByChannelID(channelID uint64, userID uint64)
 
 // UpdateRouting 更新路由
 func (s *RoutingService) UpdateRouting(routing *model.Routing, userID uint64) error {
-	// 验证主题所有权
-	topic, err := s.topicRepo.FindByID(routing.TopicID)
+	topic, channel, err := s.authorizeRouting(routing.TopicID, routing.ChannelID, userID)
 	if err != nil {
-		return errors.New("主题不存在")
-	}
-	if topic.UserID != userID {
-		return errors.New("无权访问此主题")
-	}
-
-	// 验证通道所有权
-	channel, err := s.channelRepo.FindByID(routing.ChannelID)
-	if err != nil {
-		return errors.New("通道不存在")
-	}
-	if channel.UserID != userID {
-		return errors.New("无权访问此通道")
+		return err
 	}
 
 	// 检查路由是否存在
@@ -105,6 +110,29 @@ func (s *RoutingService) UpdateRouting(routing *model.Routing, userID uint64) er
 		return errors.New("路由不存在")
 	}
 
+	// 所属组织与主题一致
+	routing.OrgID = topic.OrgID
+
+	// 校验通道选项
+	if err := validateRoutingOptions(channel.Type, routing.Options); err != nil {
+		return err
+	}
+
+	// 校验路由条件
+	if err := validateRoutingCondition(routing.Condition); err != nil {
+		return err
+	}
+
+	// 校验模板语法
+	if err := s.renderService.Validate(channel, routing); err != nil {
+		return fmt.Errorf("模板错误: %v", err)
+	}
+
+	// 校验重试策略
+	if err := normalizeRetryPolicy(routing); err != nil {
+		return err
+	}
+
 	// 保持创建时间不变
 	routing.CreatedAt = existingRouting.CreatedAt
 
@@ -113,23 +141,185 @@ func (s *RoutingService) UpdateRouting(routing *model.Routing, userID uint64) er
 
 // DeleteRouting 删除路由
 func (s *RoutingService) DeleteRouting(topicID, channelID uint64, userID uint64) error {
-	// 验证主题所有权
+	if _, _, err := s.authorizeRouting(topicID, channelID, userID); err != nil {
+		return err
+	}
+
+	return s.routingRepo.Delete(topicID, channelID)
+}
+
+// authorizeRouting 检查主题和通道属于同一组织，且用户在该组织有编辑权限
+func (s *RoutingService) authorizeRouting(topicID, channelID, userID uint64) (*model.Topic, *model.Channel, error) {
+	topic, err := s.topicRepo.FindByID(topicID)
+	if err != nil {
+		return nil, nil, errors.New("主题不存在")
+	}
+	channel, err := s.channelRepo.FindByID(channelID)
+	if err != nil {
+		return nil, nil, errors.New("通道不存在")
+	}
+	if topic.OrgID != channel.OrgID {
+		return nil, nil, errors.New("主题和通道不属于同一组织")
+	}
+	if err := s.authz.Authorize(userID, topic.OrgID, ActionWrite); err != nil {
+		return nil, nil, err
+	}
+	return topic, channel, nil
+}
+
+// validateRoutingOptions 由通道类型实现校验路由的通道扩展选项
+func validateRoutingOptions(channelType string, options model.JSON) error {
+	n, ok := notifier.Get(channelType)
+	if !ok {
+		return errors.New("不支持的通道类型")
+	}
+	if v, ok := n.(notifier.OptionsValidator); ok {
+		return v.ValidateOptions(options)
+	}
+	return nil
+}
+
+// validateRoutingCondition 校验路由条件表达式，为空表示无条件发送
+func validateRoutingCondition(expr string) error {
+	if strings.TrimSpace(expr) == "" {
+		return nil
+	}
+	_, err := condition.Compile(expr)
+	return err
+}
+
+// previewSendTimeout 预览时实际发送的超时时间
+const previewSendTimeout = 30 * time.Second
+
+// RoutingPreviewRequest 路由预览参数，模板相关字段为空时使用已保存的路由配置
+type RoutingPreviewRequest struct {
+	Payload          map[string]interface{}
+	VariableMappings model.JSON
+	MessageTemplate  *string
+	SubjectTemplate  *string
+	HTMLTemplate     *string
+	Options          model.JSON
+	Send             bool // 是否使用通道实际发送
+}
+
+// RoutingPreview 路由预览结果
+type RoutingPreview struct {
+	Matched           bool                   `json:"matched"`   // 示例内容是否满足路由条件
+	Variables         map[string]interface{} `json:"variables"` // 变量映射解析出的变量
+	Subject           string                 `json:"subject"`
+	Body              string                 `json:"body"`
+	HTML              string                 `json:"html,omitempty"`
+	Errors            map[string]string      `json:"errors,omitempty"` // condition、options、subject、body、html、send 对应的错误
+	Sent              bool                   `json:"sent"`
+	StatusCode        int                    `json:"statusCode,omitempty"`
+	Response          string                 `json:"response,omitempty"`
+	ProviderMessageID string                 `json:"providerMessageId,omitempty"`
+}
+
+// PreviewRouting 使用示例内容渲染路由的主题和正文，send为true时通过通道实际发送（需要编辑权限）
+// 实际发送不受路由条件限制，也不记录消息和投递日志
+func (s *RoutingService) PreviewRouting(ctx context.Context, topicID, channelID, userID uint64, req *RoutingPreviewRequest) (*RoutingPreview, error) {
 	topic, err := s.topicRepo.FindByID(topicID)
 	if err != nil {
-		return errors.New("主题不存在")
+		return nil, errors.New("主题不存在")
+	}
+	action := ActionRead
+	if req.Send {
+		action = ActionWrite
 	}
-	if topic.UserID != userID {
-		return errors.New("无权访问此主题")
+	if err := s.authz.Authorize(userID, topic.OrgID, action); err != nil {
+		return nil, err
 	}
 
-	// 验证通道所有权
+	routing, err := s.routingRepo.FindByTopicAndChannel(topicID, channelID)
+	if err != nil {
+		return nil, errors.New("路由不存在")
+	}
 	channel, err := s.channelRepo.FindByID(channelID)
 	if err != nil {
-		return errors.New("通道不存在")
+		return nil, errors.New("通道不存在")
 	}
-	if channel.UserID != userID {
-		return errors.New("无权访问此通道")
+	n, ok := notifier.Get(channel.Type)
+	if !ok {
+		return nil, errors.New("不支持的通道类型")
 	}
 
-	return s.routingRepo.Delete(topicID, channelID)
+	if req.VariableMappings != nil {
+		routing.VariableMappings = req.VariableMappings
+	}
+	if req.MessageTemplate != nil {
+		routing.MessageTemplate = *req.MessageTemplate
+	}
+	if req.SubjectTemplate != nil {
+		routing.SubjectTemplate = *req.SubjectTemplate
+	}
+	if req.HTMLTemplate != nil {
+		routing.HTMLTemplate = *req.HTMLTemplate
+	}
+	if req.Options != nil {
+		routing.Options = req.Options
+	}
+
+	preview := &RoutingPreview{Matched: true, Errors: make(map[string]string)}
+	if err := validateRoutingOptions(channel.Type, routing.Options); err != nil {
+		preview.Errors["options"] = err.Error()
+	}
+	if strings.TrimSpace(routing.Condition) != "" {
+		expr, err := condition.Compile(routing.Condition)
+		if err != nil {
+			preview.Matched = false
+			preview.Errors["condition"] = err.Error()
+		} else {
+			payload, _ := json.Marshal(req.Payload)
+			preview.Matched = expr.Match(payload)
+		}
+	}
+
+	rendered, err := s.renderService.Render(channel, routing, req.Payload)
+	if err != nil {
+		return nil, err
+	}
+	preview.Variables = rendered.Variables
+	preview.Subject = rendered.Subject
+	preview.Body = rendered.Body
+	preview.HTML = rendered.HTML
+	if rendered.SubjectErr != nil {
+		preview.Errors["subject"] = rendered.SubjectErr.Error()
+	}
+	if rendered.BodyErr != nil {
+		preview.Errors["body"] = rendered.BodyErr.Error()
+	}
+	if rendered.HTMLErr != nil {
+		preview.Errors["html"] = rendered.HTMLErr.Error()
+	}
+
+	if req.Send {
+		if rendered.Err() != nil {
+			preview.Errors["send"] = "模板渲染失败，未发送"
+			return preview, nil
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, previewSendTimeout)
+		defer cancel()
+		res, err := n.Send(sendCtx, notifier.Credentials(channel.Credentials), &notifier.Message{
+			ChannelID: channel.ID,
+			Subject:   preview.Subject,
+			Body:      preview.Body,
+			HTMLBody:  preview.HTML,
+			Payload:   req.Payload,
+			Variables: rendered.Variables,
+			Options:   routing.Options,
+		})
+		if res != nil {
+			preview.StatusCode = res.StatusCode
+			preview.Response = res.Response
+			preview.ProviderMessageID = res.ProviderMessageID
+		}
+		if err != nil {
+			preview.Errors["send"] = err.Error()
+		} else {
+			preview.Sent = true
+		}
+	}
+	return preview, nil
 }
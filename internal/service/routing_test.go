@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+
+	"synapse/internal/model"
+)
+
+func TestRoutingRetryJitterZeroIsStored(t *testing.T) {
+	db := openTestDB(t)
+	s := NewRoutingService(db)
+	user := registerUser(t, db, "alice")
+	topic, _ := createTopic(t, db, "all")
+	channel := &model.Channel{UserID: user.ID, OrgID: topic.OrgID, Name: "recorder", Type: recorderType, Credentials: model.JSON{}}
+	if err := db.Create(channel).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	jitter := func() float64 {
+		t.Helper()
+		var routing model.Routing
+		if err := db.Where("topic_id = ? AND channel_id = ?", topic.ID, channel.ID).First(&routing).Error; err != nil {
+			t.Fatal(err)
+		}
+		return routing.RetryJitter
+	}
+
+	// 创建和更新时0都表示不抖动，不被数据库默认值替换
+	routing := &model.Routing{TopicID: topic.ID, ChannelID: channel.ID, MessageTemplate: "{{.title}}", RetryJitter: 0}
+	if err := s.CreateRouting(routing, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := jitter(); got != 0 {
+		t.Fatalf("created jitter = %v, want 0", got)
+	}
+
+	routing.RetryJitter = 0.5
+	if err := s.UpdateRouting(routing, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	routing.RetryJitter = 0
+	if err := s.UpdateRouting(routing, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := jitter(); got != 0 {
+		t.Fatalf("updated jitter = %v, want 0", got)
+	}
+}
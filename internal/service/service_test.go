@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"synapse/internal/migration"
+	"synapse/internal/model"
+	"synapse/pkg/notifier"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB 打开执行过全部迁移的内存SQLite数据库
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if _, err := migration.New(db).Up(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// recorderType 测试使用的通道类型，记录发送的消息，按通道ID返回预设的错误
+const recorderType = "test-recorder"
+
+type recorderNotifier struct {
+	mu    sync.Mutex
+	sends map[uint64]int
+	errs  map[uint64][]error // 按顺序返回的错误，用完后发送成功
+}
+
+var recorder = &recorderNotifier{}
+
+func init() {
+	notifier.Register(recorder)
+}
+
+// reset 清空记录并设置各通道依次返回的错误
+func (n *recorderNotifier) reset(errs map[uint64][]error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sends = make(map[uint64]int)
+	n.errs = errs
+}
+
+func (n *recorderNotifier) sent(channelID uint64) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.sends[channelID]
+}
+
+func (n *recorderNotifier) Schema() notifier.Schema {
+	return notifier.Schema{Type: recorderType, Label: "Recorder"}
+}
+
+func (n *recorderNotifier) Validate(credentials notifier.Credentials) error {
+	return nil
+}
+
+func (n *recorderNotifier) Send(ctx context.Context, credentials notifier.Credentials, msg *notifier.Message) (*notifier.Result, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sends[msg.ChannelID]++
+	if errs := n.errs[msg.ChannelID]; len(errs) > 0 {
+		n.errs[msg.ChannelID] = errs[1:]
+		return nil, errs[0]
+	}
+	return &notifier.Result{StatusCode: 200}, nil
+}
+
+func (n *recorderNotifier) Test(ctx context.Context, credentials notifier.Credentials, subject, content string) (*notifier.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+// createTopic 创建主题及发往recorder通道的路由，返回主题和各通道ID
+func createTopic(t *testing.T, db *gorm.DB, strategy string, routings ...model.Routing) (*model.Topic, []uint64) {
+	t.Helper()
+	topic := &model.Topic{UserID: 1, OrgID: 1, Name: "test", WebhookKey: "key-" + t.Name(), SendingStrategy: strategy, ExecutionMode: "async"}
+	if err := db.Create(topic).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var channelIDs []uint64
+	for _, routing := range routings {
+		channel := &model.Channel{UserID: 1, OrgID: 1, Name: "recorder", Type: recorderType, Credentials: model.JSON{}}
+		if err := db.Create(channel).Error; err != nil {
+			t.Fatal(err)
+		}
+		routing.TopicID = topic.ID
+		routing.ChannelID = channel.ID
+		routing.OrgID = 1
+		if err := db.Create(&routing).Error; err != nil {
+			t.Fatal(err)
+		}
+		channelIDs = append(channelIDs, channel.ID)
+	}
+	return topic, channelIDs
+}
+
+// createMessage 创建待处理消息
+func createMessage(t *testing.T, db *gorm.DB, topic *model.Topic) *model.Message {
+	t.Helper()
+	message := &model.Message{TopicID: topic.ID, Content: model.JSON{"text": "hello"}, Status: "pending"}
+	if err := db.Create(message).Error; err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"synapse/internal/model"
+	"synapse/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// SlackThreadStore 基于数据库的Slack线程存储，实现 notifier.ThreadStore
+type SlackThreadStore struct {
+	threadRepo *repository.SlackThreadRepository
+}
+
+func NewSlackThreadStore(db *gorm.DB) *SlackThreadStore {
+	return &SlackThreadStore{
+		threadRepo: repository.NewSlackThreadRepository(db),
+	}
+}
+
+// GetThread 查找线程根消息ts
+func (s *SlackThreadStore) GetThread(channelID uint64, slackChannel, threadKey string) (string, bool) {
+	thread, err := s.threadRepo.FindByKey(channelID, slackChannel, threadKey)
+	if err != nil {
+		return "", false
+	}
+	return thread.TS, true
+}
+
+// SaveThread 保存线程根消息ts
+func (s *SlackThreadStore) SaveThread(channelID uint64, slackChannel, threadKey, ts string) error {
+	return s.threadRepo.Create(&model.SlackThread{
+		ChannelID:    channelID,
+		SlackChannel: slackChannel,
+		ThreadKey:    threadKey,
+		TS:           ts,
+	})
+}
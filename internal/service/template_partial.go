@@ -0,0 +1,126 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"synapse/internal/model"
+	"synapse/internal/repository"
+	"synapse/pkg/render"
+
+	"gorm.io/gorm"
+)
+
+// partialNamePattern 片段名称格式
+var partialNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,100}$`)
+
+type TemplatePartialService struct {
+	partialRepo *repository.TemplatePartialRepository
+	authz       *Authorizer
+}
+
+func NewTemplatePartialService(db *gorm.DB) *TemplatePartialService {
+	return &TemplatePartialService{
+		partialRepo: repository.NewTemplatePartialRepository(db),
+		authz:       NewAuthorizer(db),
+	}
+}
+
+// CreatePartial 在用户有编辑权限的组织中创建模板片段，未指定组织时使用个人组织
+func (s *TemplatePartialService) CreatePartial(partial *model.TemplatePartial, userID uint64) error {
+	orgID, err := s.authz.ResolveOrgID(userID, partial.OrgID, ActionWrite)
+	if err != nil {
+		return err
+	}
+	partial.OrgID = orgID
+	partial.UserID = userID
+
+	if err := s.validatePartial(partial); err != nil {
+		return err
+	}
+	return s.partialRepo.Create(partial)
+}
+
+// GetPartials 获取用户可访问的模板片段，orgID不为0时只返回该组织的片段
+func (s *TemplatePartialService) GetPartials(userID, orgID uint64) ([]model.TemplatePartial, error) {
+	orgIDs := []uint64{orgID}
+	if orgID == 0 {
+		var err error
+		if orgIDs, err = s.authz.OrgIDs(userID, ActionRead); err != nil {
+			return nil, err
+		}
+	} else if err := s.authz.Authorize(userID, orgID, ActionRead); err != nil {
+		return nil, err
+	}
+	return s.partialRepo.FindByOrgIDs(orgIDs)
+}
+
+// GetPartialByID 根据ID获取模板片段
+func (s *TemplatePartialService) GetPartialByID(id, userID uint64) (*model.TemplatePartial, error) {
+	partial, err := s.partialRepo.FindByID(id)
+	if err != nil {
+		return nil, errors.New("模板片段不存在")
+	}
+	if err := s.authz.Authorize(userID, partial.OrgID, ActionRead); err != nil {
+		return nil, err
+	}
+	return partial, nil
+}
+
+// UpdatePartial 更新模板片段的名称和内容
+// 重命名后引用旧名称的路由模板将渲染失败
+func (s *TemplatePartialService) UpdatePartial(partial *model.TemplatePartial, userID uint64) error {
+	existing, err := s.partialRepo.FindByID(partial.ID)
+	if err != nil {
+		return errors.New("模板片段不存在")
+	}
+	if err := s.authz.Authorize(userID, existing.OrgID, ActionWrite); err != nil {
+		return err
+	}
+
+	partial.OrgID = existing.OrgID // 片段不能移动到其他组织
+	partial.UserID = existing.UserID
+	partial.CreatedAt = existing.CreatedAt
+	if err := s.validatePartial(partial); err != nil {
+		return err
+	}
+	return s.partialRepo.Update(partial)
+}
+
+// DeletePartial 删除模板片段
+func (s *TemplatePartialService) DeletePartial(id, userID uint64) error {
+	partial, err := s.partialRepo.FindByID(id)
+	if err != nil {
+		return errors.New("模板片段不存在")
+	}
+	if err := s.authz.Authorize(userID, partial.OrgID, ActionWrite); err != nil {
+		return err
+	}
+	return s.partialRepo.Delete(id)
+}
+
+// validatePartial 校验片段名称、组织内名称唯一和内容语法
+func (s *TemplatePartialService) validatePartial(partial *model.TemplatePartial) error {
+	if !partialNamePattern.MatchString(partial.Name) {
+		return errors.New("片段名称只能包含字母、数字、下划线、点和短横线，长度不超过100")
+	}
+	if existing, err := s.partialRepo.FindByOrgAndName(partial.OrgID, partial.Name); err == nil && existing.ID != partial.ID {
+		return errors.New("组织内已存在同名片段")
+	}
+
+	// 片段可以引用组织内的其他片段
+	others, err := s.partialRepo.FindByOrgIDs([]uint64{partial.OrgID})
+	if err != nil {
+		return err
+	}
+	partials := make(map[string]string, len(others))
+	for _, other := range others {
+		if other.ID != partial.ID {
+			partials[other.Name] = other.Content
+		}
+	}
+	if err := render.New(render.EngineText, partials).Parse(partial.Name, partial.Content); err != nil {
+		return fmt.Errorf("片段模板错误: %v", err)
+	}
+	return nil
+}
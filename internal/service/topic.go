@@ -4,22 +4,33 @@ import (
 	"errors"
 	"synapse/internal/model"
 	"synapse/internal/repository"
+	"synapse/pkg/keyring"
+	"synapse/pkg/signature"
 
 	"gorm.io/gorm"
 )
 
 type TopicService struct {
 	topicRepo *repository.TopicRepository
+	authz     *Authorizer
 }
 
 func NewTopicService(db *gorm.DB) *TopicService {
 	return &TopicService{
 		topicRepo: repository.NewTopicRepository(db),
+		authz:     NewAuthorizer(db),
 	}
 }
 
-// CreateTopic 创建主题
-func (s *TopicService) CreateTopic(topic *model.Topic) error {
+// CreateTopic 在用户有编辑权限的组织中创建主题，未指定组织时使用个人组织
+func (s *TopicService) CreateTopic(topic *model.Topic, userID uint64) error {
+	orgID, err := s.authz.ResolveOrgID(userID, topic.OrgID, ActionWrite)
+	if err != nil {
+		return err
+	}
+	topic.OrgID = orgID
+	topic.UserID = userID
+
 	// 验证发送策略
 	if !s.isValidSendingStrategy(topic.SendingStrategy) {
 		return errors.New("不支持的发送策略")
@@ -30,6 +41,16 @@ func (s *TopicService) CreateTopic(topic *model.Topic) error {
 		return errors.New("不支持的执行模式")
 	}
 
+	// 验证去重配置
+	if err := normalizeDedup(topic); err != nil {
+		return err
+	}
+
+	// 验证签名配置
+	if err := s.validateSigning(topic); err != nil {
+		return err
+	}
+
 	return s.topicRepo.Create(topic)
 }
 
@@ -40,17 +61,25 @@ func (s *TopicService) GetTopicByID(id uint64, userID uint64) (*model.Topic, err
 		return nil, err
 	}
 
-	// 验证主题所有权
-	if topic.UserID != userID {
-		return nil, errors.New("无权访问此主题")
+	if err := s.authz.Authorize(userID, topic.OrgID, ActionRead); err != nil {
+		return nil, err
 	}
 
 	return topic, nil
 }
 
-// GetTopicsByUserID 获取用户的所有主题
-func (s *TopicService) GetTopicsByUserID(userID uint64) ([]model.Topic, error) {
-	return s.topicRepo.FindByUserID(userID)
+// GetTopics 获取用户可访问的主题，orgID不为0时只返回该组织的主题
+func (s *TopicService) GetTopics(userID, orgID uint64) ([]model.Topic, error) {
+	orgIDs := []uint64{orgID}
+	if orgID == 0 {
+		var err error
+		if orgIDs, err = s.authz.OrgIDs(userID, ActionRead); err != nil {
+			return nil, err
+		}
+	} else if err := s.authz.Authorize(userID, orgID, ActionRead); err != nil {
+		return nil, err
+	}
+	return s.topicRepo.FindByOrgIDs(orgIDs)
 }
 
 // GetTopicByWebhookKey 根据Webhook Key获取主题
@@ -60,14 +89,13 @@ func (s *TopicService) GetTopicByWebhookKey(webhookKey string) (*model.Topic, er
 
 // UpdateTopic 更新主题
 func (s *TopicService) UpdateTopic(topic *model.Topic, userID uint64) error {
-	// 验证主题所有权
 	existingTopic, err := s.topicRepo.FindByID(topic.ID)
 	if err != nil {
 		return err
 	}
 
-	if existingTopic.UserID != userID {
-		return errors.New("无权修改此主题")
+	if err := s.authz.Authorize(userID, existingTopic.OrgID, ActionWrite); err != nil {
+		return err
 	}
 
 	// 验证发送策略
@@ -80,22 +108,57 @@ func (s *TopicService) UpdateTopic(topic *model.Topic, userID uint64) error {
 		return errors.New("不支持的执行模式")
 	}
 
-	topic.UserID = userID                       // 确保用户ID不被修改
+	// 验证去重配置
+	if err := normalizeDedup(topic); err != nil {
+		return err
+	}
+
+	// 未提交签名密钥时保留原密钥
+	if topic.SigningScheme != "" && topic.SigningSecret == "" {
+		topic.SigningSecret = existingTopic.SigningSecret
+	}
+	if err := s.validateSigning(topic); err != nil {
+		return err
+	}
+
+	topic.UserID = existingTopic.UserID         // 保持创建者不变
+	topic.OrgID = existingTopic.OrgID           // 主题不能移动到其他组织
 	topic.WebhookKey = existingTopic.WebhookKey // 保持Webhook Key不变
 	topic.CreatedAt = existingTopic.CreatedAt
 	return s.topicRepo.Update(topic)
 }
 
+// ReencryptSecrets 使用当前主密钥重新加密所有主题的签名密钥，返回处理的主题数
+func (s *TopicService) ReencryptSecrets() (int, error) {
+	if keyring.Default() == nil {
+		return 0, errors.New("未配置主密钥")
+	}
+
+	count := 0
+	err := s.topicRepo.FindInBatches(100, func(topics []model.Topic) error {
+		for i := range topics {
+			if topics[i].SigningSecret == "" {
+				continue
+			}
+			if err := s.topicRepo.SaveUnscoped(&topics[i]); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
 // DeleteTopic 删除主题
 func (s *TopicService) DeleteTopic(id uint64, userID uint64) error {
-	// 验证主题所有权
 	topic, err := s.topicRepo.FindByID(id)
 	if err != nil {
 		return err
 	}
 
-	if topic.UserID != userID {
-		return errors.New("无权删除此主题")
+	if err := s.authz.Authorize(userID, topic.OrgID, ActionWrite); err != nil {
+		return err
 	}
 
 	return s.topicRepo.Delete(id)
@@ -103,14 +166,13 @@ func (s *TopicService) DeleteTopic(id uint64, userID uint64) error {
 
 // RegenerateWebhookKey 重新生成Webhook Key
 func (s *TopicService) RegenerateWebhookKey(id uint64, userID uint64) (*model.Topic, error) {
-	// 验证主题所有权
 	topic, err := s.topicRepo.FindByID(id)
 	if err != nil {
 		return nil, err
 	}
 
-	if topic.UserID != userID {
-		return nil, errors.New("无权修改此主题")
+	if err := s.authz.Authorize(userID, topic.OrgID, ActionWrite); err != nil {
+		return nil, err
 	}
 
 	// 生成新的Webhook Key
@@ -123,6 +185,21 @@ func (s *TopicService) RegenerateWebhookKey(id uint64, userID uint64) (*model.To
 	return topic, nil
 }
 
+// validateSigning 验证入站签名配置，未设置签名方案时清空密钥
+func (s *TopicService) validateSigning(topic *model.Topic) error {
+	if topic.SigningScheme == "" {
+		topic.SigningSecret = ""
+		return nil
+	}
+	if !signature.IsValidScheme(topic.SigningScheme) {
+		return errors.New("不支持的签名方案")
+	}
+	if topic.SigningSecret == "" {
+		return errors.New("启用签名校验时必须设置签名密钥")
+	}
+	return nil
+}
+
 // isValidSendingStrategy 验证发送策略是否有效
 func (s *TopicService) isValidSendingStrategy(strategy string) bool {
 	validStrategies := []string{"all", "failover"}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"synapse/internal/model"
+	"synapse/internal/repository"
+	"synapse/pkg/keyring"
+
+	"gorm.io/gorm"
+)
+
+// useTestKeyring 在测试期间设置全局密钥环
+func useTestKeyring(t *testing.T, keys map[int][]byte, active int) {
+	t.Helper()
+	k, err := keyring.New(keys, active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring.SetDefault(k)
+	t.Cleanup(func() { keyring.SetDefault(nil) })
+}
+
+// storedSigningSecret 读取数据库中保存的签名密钥原始值
+func storedSigningSecret(t *testing.T, db *gorm.DB, topicID uint64) string {
+	t.Helper()
+	var stored string
+	if err := db.Raw("SELECT signing_secret FROM topics WHERE id = ?", topicID).Scan(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+func TestTopicSigningSecretEncrypted(t *testing.T) {
+	db := openTestDB(t)
+	useTestKeyring(t, map[int][]byte{1: bytes.Repeat([]byte{1}, 32)}, 0)
+
+	topic := &model.Topic{UserID: 1, OrgID: 1, Name: "signed", SigningScheme: "github", SigningSecret: "s3cret"}
+	if err := repository.NewTopicRepository(db).Create(topic); err != nil {
+		t.Fatal(err)
+	}
+	if topic.SigningSecret != "s3cret" {
+		t.Fatalf("secret after save = %q, want plaintext", topic.SigningSecret)
+	}
+
+	stored := storedSigningSecret(t, db, topic.ID)
+	if strings.Contains(stored, "s3cret") || model.SigningSecretKeyVersion(stored) != 1 {
+		t.Fatalf("stored secret = %q, want encrypted with key 1", stored)
+	}
+
+	found, err := repository.NewTopicRepository(db).FindByID(topic.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.SigningSecret != "s3cret" {
+		t.Fatalf("loaded secret = %q, want s3cret", found.SigningSecret)
+	}
+}
+
+func TestReencryptSigningSecrets(t *testing.T) {
+	db := openTestDB(t)
+
+	// 未配置主密钥时保存的明文密钥
+	plain := &model.Topic{UserID: 1, OrgID: 1, Name: "plain", WebhookKey: "plain", SigningScheme: "gitlab", SigningSecret: "token"}
+	unsigned := &model.Topic{UserID: 1, OrgID: 1, Name: "unsigned", WebhookKey: "unsigned"}
+	for _, topic := range []*model.Topic{plain, unsigned} {
+		if err := db.Create(topic).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stored := storedSigningSecret(t, db, plain.ID); stored != "token" {
+		t.Fatalf("stored secret without keyring = %q, want plaintext", stored)
+	}
+
+	useTestKeyring(t, map[int][]byte{1: bytes.Repeat([]byte{1}, 32), 2: bytes.Repeat([]byte{2}, 32)}, 2)
+	count, err := NewTopicService(db).ReencryptSecrets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("ReencryptSecrets() = %d, want 1", count)
+	}
+	if version := model.SigningSecretKeyVersion(storedSigningSecret(t, db, plain.ID)); version != 2 {
+		t.Fatalf("key version = %d, want 2", version)
+	}
+	if stored := storedSigningSecret(t, db, unsigned.ID); stored != "" {
+		t.Fatalf("unsigned topic secret = %q, want empty", stored)
+	}
+}
+
+func TestWebhookNonceRepository(t *testing.T) {
+	db := openTestDB(t)
+	// 两个仓库共享数据库，模拟多个实例
+	first := repository.NewWebhookNonceRepository(db)
+	second := repository.NewWebhookNonceRepository(db)
+
+	if ok, err := first.Remember("key", time.Hour); err != nil || !ok {
+		t.Fatalf("first Remember() = %v, %v", ok, err)
+	}
+	if ok, err := second.Remember("key", time.Hour); err != nil || ok {
+		t.Fatalf("replayed Remember() = %v, %v, want false", ok, err)
+	}
+	if ok, err := second.Remember("other", time.Hour); err != nil || !ok {
+		t.Fatalf("other Remember() = %v, %v", ok, err)
+	}
+
+	// 过期的记录可以重新使用
+	if err := db.Model(&model.WebhookNonce{}).Where("nonce_key = ?", "key").
+		Update("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := second.Remember("key", time.Hour); err != nil || !ok {
+		t.Fatalf("Remember() after expiry = %v, %v", ok, err)
+	}
+}
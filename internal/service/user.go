@@ -1,6 +1,9 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"synapse/internal/model"
 	"synapse/internal/repository"
 	"synapse/internal/utils"
@@ -9,17 +12,30 @@ import (
 )
 
 type UserService struct {
+	db       *gorm.DB
 	userRepo *repository.UserRepository
+	orgRepo  *repository.OrganizationRepository
 }
 
+// ErrSoleOwner 用户是共享组织的唯一所有者，不能删除
+var ErrSoleOwner = errors.New("用户是组织的唯一所有者，请先转让所有权或删除组织")
+
 func NewUserService(db *gorm.DB) *UserService {
 	return &UserService{
+		db:       db,
 		userRepo: repository.NewUserRepository(db),
+		orgRepo:  repository.NewOrganizationRepository(db),
 	}
 }
 
+// Register 注册用户并创建其个人组织，两者在同一事务中完成
 func (s *UserService) Register(user *model.User) error {
-	return s.userRepo.Create(user)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := repository.NewUserRepository(tx).Create(user); err != nil {
+			return err
+		}
+		return repository.NewOrganizationRepository(tx).CreateWithOwner(&model.Organization{Name: user.Username, Personal: true}, user.ID)
+	})
 }
 
 func (s *UserService) Login(username, password string) (*model.User, error) {
@@ -44,6 +60,29 @@ func (s *UserService) UpdateUser(user *model.User) error {
 	return s.userRepo.Update(user)
 }
 
+// DeleteUser 删除用户及其成员关系和API密钥，用户是共享组织的唯一所有者时返回 ErrSoleOwner
 func (s *UserService) DeleteUser(id uint64) error {
-	return s.userRepo.Delete(id)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		orgRepo := repository.NewOrganizationRepository(tx)
+		orgs, err := orgRepo.FindSoleOwnedShared(id)
+		if err != nil {
+			return err
+		}
+		if len(orgs) > 0 {
+			names := make([]string, len(orgs))
+			for i, org := range orgs {
+				names[i] = org.Name
+			}
+			return fmt.Errorf("%w: %s", ErrSoleOwner, strings.Join(names, ", "))
+		}
+
+		if err := orgRepo.DeleteMembersByUserID(id); err != nil {
+			return err
+		}
+		// 吊销用户的API密钥，删除用户后密钥不能再通过认证
+		if err := repository.NewAPIKeyRepository(tx).DeleteByUserID(id); err != nil {
+			return err
+		}
+		return repository.NewUserRepository(tx).Delete(id)
+	})
 }
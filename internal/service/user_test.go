@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"synapse/internal/model"
+	"synapse/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// registerUser 注册用户，同时创建个人组织
+func registerUser(t *testing.T, db *gorm.DB, username string) *model.User {
+	t.Helper()
+	user := &model.User{Username: username, Password: "password", Email: username + "@example.com"}
+	if err := NewUserService(db).Register(user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestRegisterCreatesPersonalOrg(t *testing.T) {
+	db := openTestDB(t)
+	user := registerUser(t, db, "alice")
+
+	org, err := repository.NewOrganizationRepository(db).FindPersonal(user.ID)
+	if err != nil {
+		t.Fatalf("personal org not created: %v", err)
+	}
+	if org.Name != "alice" {
+		t.Fatalf("personal org name = %q, want alice", org.Name)
+	}
+}
+
+func TestRegisterRollsBack(t *testing.T) {
+	db := openTestDB(t)
+	// 创建个人组织失败时不应留下用户
+	if err := db.Migrator().DropTable("organization_members"); err != nil {
+		t.Fatal(err)
+	}
+
+	user := &model.User{Username: "alice", Password: "password", Email: "alice@example.com"}
+	if err := NewUserService(db).Register(user); err == nil {
+		t.Fatal("Register() succeeded without organization_members table")
+	}
+	var users, orgs int64
+	db.Model(&model.User{}).Count(&users)
+	db.Model(&model.Organization{}).Count(&orgs)
+	if users != 0 || orgs != 0 {
+		t.Fatalf("after failed Register() users = %d, orgs = %d, want 0", users, orgs)
+	}
+}
+
+func TestDeleteUserSoleOwner(t *testing.T) {
+	db := openTestDB(t)
+	alice := registerUser(t, db, "alice")
+	bob := registerUser(t, db, "bob")
+	orgService := NewOrganizationService(db)
+
+	shared := &model.Organization{Name: "shared"}
+	if err := orgService.CreateOrganization(shared, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orgService.AddMember(shared.ID, "bob", model.RoleAdmin, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// alice是共享组织唯一的所有者，不能删除
+	err := NewUserService(db).DeleteUser(alice.ID)
+	if !errors.Is(err, ErrSoleOwner) {
+		t.Fatalf("DeleteUser() = %v, want ErrSoleOwner", err)
+	}
+	if _, err := repository.NewUserRepository(db).FindByID(alice.ID); err != nil {
+		t.Fatalf("user deleted despite error: %v", err)
+	}
+	if count, _ := repository.NewOrganizationRepository(db).CountOwners(shared.ID); count != 1 {
+		t.Fatalf("owners = %d, want 1", count)
+	}
+
+	// 转让所有权后可以删除
+	if _, err := orgService.UpdateMember(shared.ID, bob.ID, model.RoleOwner, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewUserService(db).DeleteUser(alice.ID); err != nil {
+		t.Fatalf("DeleteUser() with co-owner = %v", err)
+	}
+	if _, err := repository.NewOrganizationRepository(db).FindMember(shared.ID, alice.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("membership not removed: %v", err)
+	}
+	if count, _ := repository.NewOrganizationRepository(db).CountOwners(shared.ID); count != 1 {
+		t.Fatalf("owners = %d, want 1", count)
+	}
+}
+
+func TestDeleteUserPersonalOrgOnly(t *testing.T) {
+	db := openTestDB(t)
+	alice := registerUser(t, db, "alice")
+
+	// 个人组织不阻止删除用户
+	if err := NewUserService(db).DeleteUser(alice.ID); err != nil {
+		t.Fatalf("DeleteUser() = %v", err)
+	}
+	if _, err := repository.NewUserRepository(db).FindByID(alice.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("user not deleted: %v", err)
+	}
+}
@@ -1,17 +1,27 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"synapse/internal/config"
-	"synapse/internal/model"
+	"synapse/internal/database"
+	"synapse/internal/dispatcher"
+	"synapse/internal/metrics"
+	"synapse/internal/migration"
 	"synapse/internal/router"
+	"synapse/internal/service"
+	"synapse/pkg/keyring"
 	"synapse/pkg/logger"
+	"synapse/pkg/notifier"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
@@ -23,31 +33,58 @@ func main() {
 	// 2. 初始化日志
 	logger.InitLogger(cfg.Log.Level, cfg.Log.Path)
 	defer logger.Logger.Sync()
-	zap.L().Info("配置加载完成", zap.Any("config", cfg))
+	zap.L().Info("配置加载完成")
+
+	// 初始化凭证加密密钥
+	k, err := keyring.Load(cfg.Security.MasterKeys, cfg.Security.KeyFile, cfg.Security.ActiveKey)
+	if err != nil {
+		log.Fatalf("主密钥加载失败: %v", err)
+	}
+	if k == nil {
+		zap.L().Warn("未配置主密钥，通道凭证将以明文保存")
+	} else {
+		keyring.SetDefault(k)
+		zap.L().Info("主密钥加载完成", zap.Int("activeVersion", k.ActiveVersion()))
+	}
+
+	// 初始化指标，数据库查询耗时通过日志适配器的钩子统计
+	metrics.Init(cfg.Metrics)
+	if cfg.Metrics.Enabled && cfg.Metrics.Token == "" {
+		zap.L().Warn("指标接口未设置token，任何人都可以访问", zap.String("path", cfg.Metrics.Path))
+	}
+	gormLogger := logger.NewZapGormLogger(zap.L(), cfg.Log.Level)
+	gormLogger.Hook = metrics.ObserveQuery
 
 	// 3. 初始化数据库
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=Local",
-		cfg.Database.User,
-		cfg.Database.Password,
-		cfg.Database.Host,
-		cfg.Database.Port,
-		cfg.Database.DBName,
-		cfg.Database.Charset,
-	)
-
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: logger.NewZapGormLogger(zap.L(), cfg.Log.Level),
+	db, err := database.Open(cfg.Database, &gorm.Config{
+		Logger: gormLogger,
 	})
 	if err != nil {
 		log.Fatalf("数据库连接失败: %v", err)
 	}
-	zap.L().Info("数据库连接成功")
+	zap.L().Info("数据库连接成功", zap.String("driver", cfg.Database.Driver))
 
-	// 4. 自动迁移数据模型
-	if err := db.AutoMigrate(&model.User{}); err != nil {
-		log.Fatalf("数据库迁移失败: %v", err)
+	// 命令行子命令：migrate、reencrypt
+	if len(os.Args) > 1 {
+		if err := runCommand(db, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// 4. 执行数据库迁移
+	migrator := migration.New(db)
+	if cfg.Database.AutoMigrate {
+		done, err := migrator.Up()
+		if err != nil {
+			log.Fatalf("数据库迁移失败: %v", err)
+		}
+		zap.L().Info("数据库迁移完成", zap.Int("applied", len(done)))
+	} else if pending, err := migrator.Pending(); err != nil {
+		log.Fatalf("检查数据库迁移失败: %v", err)
+	} else if len(pending) > 0 {
+		zap.L().Warn("存在未执行的数据库迁移，请执行 synapse migrate up", zap.Int("pending", len(pending)))
 	}
-	zap.L().Info("数据库迁移完成")
 
 	// 5. 设置Gin模式
 	if cfg.Server.Mode == "release" {
@@ -56,10 +93,22 @@ func main() {
 		gin.SetMode(gin.DebugMode)
 	}
 
-	// 6. 初始化路由
-	r := router.SetupRouter(db)
+	// 6. 启动消息分发器
+	notifier.SetThreadStore(service.NewSlackThreadStore(db))
+	d := dispatcher.NewDispatcher(db, cfg.Dispatcher)
+	metrics.RegisterDispatcher(d.QueueDepth, d.Processing)
+	if err := d.Start(); err != nil {
+		log.Fatalf("消息分发器启动失败: %v", err)
+	}
+
+	// 通道凭证定时检查
+	channelHealth := service.NewChannelHealthService(db, cfg.Health)
+	channelHealth.Start()
+
+	// 7. 初始化路由
+	r := router.SetupRouter(db, d, channelHealth)
 
-	// 7. 启动服务器
+	// 8. 启动服务器
 	serverAddr := fmt.Sprintf(":%d", cfg.Server.Port)
 	zap.L().Info("服务器启动中", zap.String("address", serverAddr))
 
@@ -68,7 +117,41 @@ func main() {
 		Handler: r,
 	}
 
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		zap.L().Fatal("服务器启动失败", zap.Error(err))
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			zap.L().Fatal("服务器启动失败", zap.Error(err))
+		}
+	}()
+
+	// 9. 优雅停止：停止接收请求，等待处理中的消息投递完成，超时后将其重置为待处理
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+	zap.L().Info("服务器停止中", zap.String("signal", sig.String()))
+
+	// HTTP请求和消息投递各自使用独立的期限，同步Webhook耗尽请求期限不会挤占分发器的时间
+	serverCtx, cancelServer := context.WithTimeout(context.Background(), shutdownTimeout(cfg.Server.ShutdownTimeout))
+	defer cancelServer()
+	if err := srv.Shutdown(serverCtx); err != nil {
+		zap.L().Warn("等待请求处理完成超时", zap.Error(err))
+	}
+
+	dispatcherCtx, cancelDispatcher := context.WithTimeout(context.Background(), shutdownTimeout(cfg.Dispatcher.ShutdownTimeout))
+	defer cancelDispatcher()
+	if err := d.Stop(dispatcherCtx); err != nil {
+		zap.L().Warn("消息分发器停止超时", zap.Error(err))
+	}
+	channelHealth.Stop()
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+	zap.L().Info("服务器已停止")
+}
+
+// shutdownTimeout 将配置的停止期限（秒）转换为时长，未配置时为30秒
+func shutdownTimeout(seconds int) time.Duration {
+	if seconds <= 0 {
+		return 30 * time.Second
 	}
+	return time.Duration(seconds) * time.Second
 }
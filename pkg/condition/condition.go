@@ -0,0 +1,595 @@
+// Package condition 实现路由条件表达式
+//
+// 表达式示例：severity == "critical" && labels.env == "prod"
+//
+// 支持的语法：
+//   - 字段：gjson路径（如 labels.env、alerts.#.status），包含特殊字符的路径可用反引号包裹
+//   - 字面量：字符串（"..." 或 '...'）、数字、true、false、null；字符串中 \\、\"、\' 和 \n、\t、\r 为转义，
+//     其他反斜杠原样保留，正则可直接写作 "\d+"
+//   - 比较：== != > >= < <=，=~ 和 !~ 为正则匹配，in 判断是否在列表中（如 level in ["error", "fatal"]）
+//   - 逻辑：&& || ! 以及括号
+//
+// 单独的字段表示字段存在且不为false、null、空字符串或0。
+package condition
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Expr 编译后的条件表达式
+type Expr struct {
+	source string
+	root   node
+}
+
+// Compile 编译条件表达式
+func Compile(source string) (*Expr, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, fmt.Errorf("条件表达式在位置%d处有多余的内容: %s", p.peek().pos, p.peek().text)
+	}
+	return &Expr{source: source, root: root}, nil
+}
+
+// String 返回表达式原文
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Match 对JSON内容求值
+func (e *Expr) Match(content []byte) bool {
+	return e.root.eval(content).truthy()
+}
+
+// ---- 求值 ----
+
+type valueKind int
+
+const (
+	kindNull valueKind = iota
+	kindBool
+	kindNumber
+	kindString
+	kindJSON
+)
+
+type value struct {
+	kind valueKind
+	b    bool
+	n    float64
+	s    string
+}
+
+func fromResult(r gjson.Result) value {
+	switch r.Type {
+	case gjson.True:
+		return value{kind: kindBool, b: true}
+	case gjson.False:
+		return value{kind: kindBool, b: false}
+	case gjson.Number:
+		return value{kind: kindNumber, n: r.Num}
+	case gjson.String:
+		return value{kind: kindString, s: r.Str}
+	case gjson.JSON:
+		return value{kind: kindJSON, s: r.Raw}
+	default:
+		return value{kind: kindNull}
+	}
+}
+
+func (v value) truthy() bool {
+	switch v.kind {
+	case kindBool:
+		return v.b
+	case kindNumber:
+		return v.n != 0
+	case kindString:
+		return v.s != ""
+	case kindJSON:
+		return true
+	default:
+		return false
+	}
+}
+
+// text 用于字符串比较和正则匹配
+func (v value) text() string {
+	switch v.kind {
+	case kindBool:
+		return strconv.FormatBool(v.b)
+	case kindNumber:
+		return strconv.FormatFloat(v.n, 'f', -1, 64)
+	case kindNull:
+		return ""
+	default:
+		return v.s
+	}
+}
+
+func equal(a, b value) bool {
+	if a.kind == kindNull || b.kind == kindNull {
+		return a.kind == b.kind
+	}
+	if a.kind == kindBool || b.kind == kindBool {
+		return a.kind == b.kind && a.b == b.b
+	}
+	if a.kind == kindNumber && b.kind == kindNumber {
+		return a.n == b.n
+	}
+	// 数字与字符串比较时，字符串可解析为数字则按数字比较
+	if a.kind == kindNumber || b.kind == kindNumber {
+		if an, bn, ok := numbers(a, b); ok {
+			return an == bn
+		}
+	}
+	return a.text() == b.text()
+}
+
+func numbers(a, b value) (float64, float64, bool) {
+	an, ok := toNumber(a)
+	if !ok {
+		return 0, 0, false
+	}
+	bn, ok := toNumber(b)
+	if !ok {
+		return 0, 0, false
+	}
+	return an, bn, true
+}
+
+func toNumber(v value) (float64, bool) {
+	switch v.kind {
+	case kindNumber:
+		return v.n, true
+	case kindString:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v.s), 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// compare 比较大小，类型不可比较时返回false
+func compare(op string, a, b value) bool {
+	var c int
+	if an, bn, ok := numbers(a, b); ok {
+		switch {
+		case an < bn:
+			c = -1
+		case an > bn:
+			c = 1
+		}
+	} else if a.kind == kindString && b.kind == kindString {
+		c = strings.Compare(a.s, b.s)
+	} else {
+		return false
+	}
+
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+type node interface {
+	eval(content []byte) value
+}
+
+type literalNode struct{ v value }
+
+func (n *literalNode) eval([]byte) value { return n.v }
+
+type pathNode struct{ path string }
+
+func (n *pathNode) eval(content []byte) value {
+	return fromResult(gjson.GetBytes(content, n.path))
+}
+
+type notNode struct{ x node }
+
+func (n *notNode) eval(content []byte) value {
+	return value{kind: kindBool, b: !n.x.eval(content).truthy()}
+}
+
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func (n *logicalNode) eval(content []byte) value {
+	left := n.left.eval(content).truthy()
+	if n.op == "&&" {
+		return value{kind: kindBool, b: left && n.right.eval(content).truthy()}
+	}
+	return value{kind: kindBool, b: left || n.right.eval(content).truthy()}
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) eval(content []byte) value {
+	a, b := n.left.eval(content), n.right.eval(content)
+	var result bool
+	switch n.op {
+	case "==":
+		result = equal(a, b)
+	case "!=":
+		result = !equal(a, b)
+	default:
+		result = compare(n.op, a, b)
+	}
+	return value{kind: kindBool, b: result}
+}
+
+type matchNode struct {
+	negate bool
+	left   node
+	re     *regexp.Regexp
+}
+
+func (n *matchNode) eval(content []byte) value {
+	v := n.left.eval(content)
+	matched := v.kind != kindNull && n.re.MatchString(v.text())
+	return value{kind: kindBool, b: matched != n.negate}
+}
+
+type inNode struct {
+	left node
+	list []value
+}
+
+func (n *inNode) eval(content []byte) value {
+	v := n.left.eval(content)
+	for _, item := range n.list {
+		if equal(v, item) {
+			return value{kind: kindBool, b: true}
+		}
+	}
+	return value{kind: kindBool, b: false}
+}
+
+// ---- 词法分析 ----
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPath
+	tokString
+	tokNumber
+	tokKeyword
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "(", ")", "[", "]", ","}
+
+func isPathStart(c byte) bool {
+	return c == '_' || c == '@' || c == '#' || c == '*' || c == '?' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isPathChar(c byte) bool {
+	return isPathStart(c) || c == '.' || c == '-' || c == '\\' || (c >= '0' && c <= '9')
+}
+
+func lex(source string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(source) {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '"' || c == '\'':
+			start := i
+			i++
+			var sb strings.Builder
+			closed := false
+			for i < len(source) {
+				if source[i] == '\\' && i+1 < len(source) {
+					sb.WriteString(unescape(source[i : i+2]))
+					i += 2
+					continue
+				}
+				if source[i] == c {
+					closed = true
+					i++
+					break
+				}
+				sb.WriteByte(source[i])
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("条件表达式在位置%d处的字符串未闭合", start)
+			}
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), pos: start})
+
+		case c == '`':
+			start := i
+			end := strings.IndexByte(source[i+1:], '`')
+			if end < 0 {
+				return nil, fmt.Errorf("条件表达式在位置%d处的字段路径未闭合", start)
+			}
+			tokens = append(tokens, token{kind: tokPath, text: source[i+1 : i+1+end], pos: start})
+			i += end + 2
+
+		case (c >= '0' && c <= '9') || (c == '-' && i+1 < len(source) && source[i+1] >= '0' && source[i+1] <= '9'):
+			start := i
+			i++
+			for i < len(source) && (source[i] >= '0' && source[i] <= '9' || source[i] == '.' || source[i] == 'e' || source[i] == 'E') {
+				i++
+			}
+			if _, err := strconv.ParseFloat(source[start:i], 64); err != nil {
+				return nil, fmt.Errorf("条件表达式在位置%d处的数字格式错误: %s", start, source[start:i])
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: source[start:i], pos: start})
+
+		case isPathStart(c):
+			start := i
+			for i < len(source) && isPathChar(source[i]) {
+				// 反斜杠转义路径中的特殊字符
+				if source[i] == '\\' && i+1 < len(source) {
+					i += 2
+					continue
+				}
+				i++
+			}
+			text := source[start:i]
+			kind := tokPath
+			if text == "true" || text == "false" || text == "null" || text == "in" {
+				kind = tokKeyword
+			}
+			tokens = append(tokens, token{kind: kind, text: text, pos: start})
+
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("条件表达式在位置%d处有无法识别的字符: %q", i, c)
+			}
+		}
+	}
+	tokens = append(tokens, token{kind: tokEOF, pos: len(source)})
+	return tokens, nil
+}
+
+// unescape 返回字符串中反斜杠转义（如 \n）的内容，未知的转义原样保留，以免改变正则表达式
+func unescape(escape string) string {
+	switch escape[1] {
+	case '\\', '"', '\'':
+		return escape[1:]
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	default:
+		return escape
+	}
+}
+
+// ---- 语法分析 ----
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOp(text string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == text
+}
+
+func (p *parser) expectOp(text string) error {
+	if !p.isOp(text) {
+		return p.errorf("缺少 %s", text)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	t := p.peek()
+	if t.kind == tokEOF {
+		return fmt.Errorf("条件表达式不完整: "+format, args...)
+	}
+	return fmt.Errorf("条件表达式在位置%d处错误: "+format, append([]interface{}{t.pos}, args...)...)
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&&") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("!") {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{x: x}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	if p.isOp("(") {
+		p.next()
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+		return x, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	switch {
+	case t.kind == tokOp && (t.text == "==" || t.text == "!=" || t.text == "<" || t.text == "<=" || t.text == ">" || t.text == ">="):
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: t.text, left: left, right: right}, nil
+
+	case t.kind == tokOp && (t.text == "=~" || t.text == "!~"):
+		p.next()
+		pattern := p.next()
+		if pattern.kind != tokString {
+			return nil, fmt.Errorf("条件表达式在位置%d处错误: 正则匹配的右侧必须是字符串", pattern.pos)
+		}
+		re, err := regexp.Compile(pattern.text)
+		if err != nil {
+			return nil, fmt.Errorf("条件表达式在位置%d处的正则表达式错误: %v", pattern.pos, err)
+		}
+		return &matchNode{negate: t.text == "!~", left: left, re: re}, nil
+
+	case t.kind == tokKeyword && t.text == "in":
+		p.next()
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return &inNode{left: left, list: list}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseList() ([]value, error) {
+	if err := p.expectOp("["); err != nil {
+		return nil, err
+	}
+	var list []value
+	for !p.isOp("]") {
+		operand, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		lit, ok := operand.(*literalNode)
+		if !ok {
+			return nil, p.errorf("列表中只能包含字面量")
+		}
+		list = append(list, lit.v)
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expectOp("]"); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokPath:
+		if t.text == "" {
+			return nil, p.errorf("字段路径不能为空")
+		}
+		p.next()
+		return &pathNode{path: t.text}, nil
+	case tokString:
+		p.next()
+		return &literalNode{v: value{kind: kindString, s: t.text}}, nil
+	case tokNumber:
+		p.next()
+		n, _ := strconv.ParseFloat(t.text, 64)
+		return &literalNode{v: value{kind: kindNumber, n: n}}, nil
+	case tokKeyword:
+		switch t.text {
+		case "true", "false":
+			p.next()
+			return &literalNode{v: value{kind: kindBool, b: t.text == "true"}}, nil
+		case "null":
+			p.next()
+			return &literalNode{v: value{kind: kindNull}}, nil
+		}
+	}
+	return nil, p.errorf("需要字段或字面量")
+}
@@ -0,0 +1,186 @@
+package condition
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		source string
+		want   []token
+	}{
+		{`a.b == "x"`, []token{{tokPath, "a.b", 0}, {tokOp, "==", 4}, {tokString, "x", 7}, {tokEOF, "", 10}}},
+		{`n >= -1.5e3`, []token{{tokPath, "n", 0}, {tokOp, ">=", 2}, {tokNumber, "-1.5e3", 5}, {tokEOF, "", 11}}},
+		{"`a b`!=null", []token{{tokPath, "a b", 0}, {tokOp, "!=", 5}, {tokKeyword, "null", 7}, {tokEOF, "", 11}}},
+		{`x in ['a',"b"]`, []token{{tokPath, "x", 0}, {tokKeyword, "in", 2}, {tokOp, "[", 5}, {tokString, "a", 6}, {tokOp, ",", 9}, {tokString, "b", 10}, {tokOp, "]", 13}, {tokEOF, "", 14}}},
+		{`!(a||b)&&c`, []token{{tokOp, "!", 0}, {tokOp, "(", 1}, {tokPath, "a", 2}, {tokOp, "||", 3}, {tokPath, "b", 5}, {tokOp, ")", 6}, {tokOp, "&&", 7}, {tokPath, "c", 9}, {tokEOF, "", 10}}},
+		{`a\.b`, []token{{tokPath, `a\.b`, 0}, {tokEOF, "", 4}}},
+		{`alerts.#.status`, []token{{tokPath, "alerts.#.status", 0}, {tokEOF, "", 15}}},
+	}
+	for _, tt := range tests {
+		got, err := lex(tt.source)
+		if err != nil {
+			t.Errorf("lex(%q) error: %v", tt.source, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lex(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestLexStringEscapes(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`"plain"`, "plain"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`'it\'s'`, "it's"},
+		{`"a\\b"`, `a\b`},
+		{`"line\nbreak\ttab\r"`, "line\nbreak\ttab\r"},
+		// 未知的转义原样保留
+		{`"\d+"`, `\d+`},
+		{`"\w+\.example\.com"`, `\w+\.example\.com`},
+		{`"\中"`, `\中`},
+	}
+	for _, tt := range tests {
+		tokens, err := lex(tt.source)
+		if err != nil {
+			t.Errorf("lex(%q) error: %v", tt.source, err)
+			continue
+		}
+		if tokens[0].kind != tokString || tokens[0].text != tt.want {
+			t.Errorf("lex(%q) = %q, want %q", tt.source, tokens[0].text, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	content := []byte(`{
+		"severity": "critical",
+		"count": 3,
+		"ratio": "0.5",
+		"code": "007",
+		"enabled": true,
+		"disabled": false,
+		"empty": "",
+		"zero": 0,
+		"nothing": null,
+		"labels": {"env": "prod", "team": "db"},
+		"alerts": [{"status": "firing"}, {"status": "resolved"}],
+		"message": "disk usage 95% on db-01",
+		"a.b": "dotted"
+	}`)
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// 比较
+		{`severity == "critical"`, true},
+		{`severity != "critical"`, false},
+		{`labels.env == 'prod'`, true},
+		{`count > 2`, true},
+		{`count >= 3`, true},
+		{`count < 3`, false},
+		{`count <= 2.5`, false},
+		{`severity > "alpha"`, true},
+
+		// 类型转换
+		{`count == "3"`, true},
+		{`ratio == 0.5`, true},
+		{`ratio < 1`, true},
+		{`code == 7`, true},
+		{`code == "7"`, false},
+		{`enabled == true`, true},
+		{`enabled == "true"`, false},
+		{`zero == false`, false},
+		{`nothing == null`, true},
+		{`missing == null`, true},
+		{`empty == null`, false},
+		{`severity > 1`, false},
+		{`labels == null`, false},
+
+		// 真值
+		{`enabled`, true},
+		{`disabled`, false},
+		{`empty`, false},
+		{`zero`, false},
+		{`nothing`, false},
+		{`missing`, false},
+		{`labels`, true},
+		{`!missing`, true},
+
+		// 优先级：! 高于 &&，&& 高于 ||
+		{`severity == "info" && count > 0 || enabled`, true},
+		{`enabled || severity == "info" && count > 100`, true},
+		{`(enabled || severity == "info") && count > 100`, false},
+		{`!disabled && enabled`, true},
+		{`!(disabled || enabled)`, false},
+		{`!!enabled`, true},
+		{`severity == "info" || count == 1 || labels.team == "db"`, true},
+
+		// in
+		{`severity in ["critical", "error"]`, true},
+		{`severity in ["warning"]`, false},
+		{`count in [1, 2, 3]`, true},
+		{`count in ["3"]`, true},
+		{`nothing in [null]`, true},
+		{`severity in []`, false},
+		{`!(severity in ["info"])`, true},
+
+		// 正则
+		{`message =~ "\d+%"`, true},
+		{`message =~ "^disk"`, true},
+		{`message =~ "db-\d{2}$"`, true},
+		{`message !~ "cpu"`, true},
+		{`labels.env =~ "^(prod|staging)$"`, true},
+		{`count =~ "^3$"`, true},
+		{`missing =~ ".*"`, false},
+		{`missing !~ "x"`, true},
+		{`alerts.#.status =~ "firing"`, true},
+
+		// 路径
+		{"`a\\.b` == \"dotted\"", true},
+		{`a\.b == "dotted"`, true},
+		{`alerts.0.status == "firing"`, true},
+		{`alerts.# == 2`, true},
+	}
+	for _, tt := range tests {
+		expr, err := Compile(tt.expr)
+		if err != nil {
+			t.Errorf("Compile(%s) error: %v", tt.expr, err)
+			continue
+		}
+		if got := expr.Match(content); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`severity ==`,
+		`severity == "critical`,
+		"`labels.env",
+		`(a && b`,
+		`a && b)`,
+		`a == 1 2`,
+		`a =~ 1`,
+		`a =~ "("`,
+		`a in "x"`,
+		`a in [b]`,
+		`a in ["x"`,
+		`a $ b`,
+		`1.2.3 == a`,
+		"`` == 1",
+	}
+	for _, source := range tests {
+		if _, err := Compile(source); err == nil {
+			t.Errorf("Compile(%q) should fail", source)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Package keyring 实现凭证的信封加密
+//
+// 每次加密生成随机的数据密钥（DEK），用AES-256-GCM加密数据，再用指定版本的主密钥加密DEK。
+// 密文记录主密钥版本，轮换主密钥时旧版本密钥需保留到所有数据重新加密完成。
+package keyring
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Envelope 加密结果
+type Envelope struct {
+	Version int    `json:"v"`     // 主密钥版本
+	DEK     string `json:"dek"`   // 主密钥加密的数据密钥，base64
+	Nonce   string `json:"nonce"` // 数据加密的nonce，base64
+	Data    string `json:"data"`  // 密文，base64
+}
+
+// Keyring 主密钥集合
+type Keyring struct {
+	keys   map[int][]byte
+	active int
+}
+
+// New 创建密钥环，keys 为版本号到32字节主密钥的映射，active 为加密使用的版本（0表示最大版本）
+func New(keys map[int][]byte, active int) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("未配置主密钥")
+	}
+	for version, key := range keys {
+		if version <= 0 {
+			return nil, fmt.Errorf("主密钥版本必须为正整数: %d", version)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("主密钥版本%d长度必须为32字节", version)
+		}
+	}
+	if active == 0 {
+		for version := range keys {
+			if version > active {
+				active = version
+			}
+		}
+	}
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("未找到当前主密钥版本%d", active)
+	}
+	return &Keyring{keys: keys, active: active}, nil
+}
+
+// ActiveVersion 返回加密使用的主密钥版本
+func (k *Keyring) ActiveVersion() int {
+	return k.active
+}
+
+// Encrypt 使用当前版本的主密钥加密
+func (k *Keyring) Encrypt(plaintext []byte) (*Envelope, error) {
+	dek := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return nil, err
+	}
+
+	nonce, data, err := seal(dek, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	dekNonce, wrapped, err := seal(k.keys[k.active], dek)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Envelope{
+		Version: k.active,
+		DEK:     base64.StdEncoding.EncodeToString(append(dekNonce, wrapped...)),
+		Nonce:   base64.StdEncoding.EncodeToString(nonce),
+		Data:    base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+// Decrypt 使用密文记录的主密钥版本解密
+func (k *Keyring) Decrypt(env *Envelope) ([]byte, error) {
+	key, ok := k.keys[env.Version]
+	if !ok {
+		return nil, fmt.Errorf("缺少主密钥版本%d", env.Version)
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(env.DEK)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := base64.StdEncoding.DecodeString(env.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(env.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < gcm.NonceSize() {
+		return nil, errors.New("数据密钥格式错误")
+	}
+	dek, err := gcm.Open(nil, wrapped[:gcm.NonceSize()], wrapped[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("数据密钥解密失败")
+	}
+
+	gcm, err = newGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, errors.New("数据解密失败")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(key, plaintext []byte) ([]byte, []byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, gcm.Seal(nil, nonce, plaintext, nil), nil
+}
+
+// Load 从配置的主密钥和密钥文件创建密钥环，都未配置时返回nil
+func Load(encoded map[string]string, keyFile string, active int) (*Keyring, error) {
+	merged := make(map[string]string, len(encoded))
+	for v, key := range encoded {
+		merged[v] = key
+	}
+	if keyFile != "" {
+		fromFile, err := LoadKeyFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		for v, key := range fromFile {
+			merged[v] = key
+		}
+	}
+	if len(merged) == 0 {
+		return nil, nil
+	}
+
+	keys, err := ParseKeys(merged)
+	if err != nil {
+		return nil, err
+	}
+	return New(keys, active)
+}
+
+// ParseKeys 解析 "版本号 -> base64密钥" 形式的主密钥配置
+func ParseKeys(encoded map[string]string) (map[int][]byte, error) {
+	keys := make(map[int][]byte, len(encoded))
+	for v, encodedKey := range encoded {
+		version, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("主密钥版本格式错误: %s", v)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+		if err != nil {
+			return nil, fmt.Errorf("主密钥版本%d不是合法的base64: %v", version, err)
+		}
+		keys[version] = key
+	}
+	return keys, nil
+}
+
+// LoadKeyFile 读取密钥文件，每行格式为 "版本号:base64密钥"，#开头为注释
+func LoadKeyFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	encoded := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		version, key, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("密钥文件格式错误: %s", path)
+		}
+		encoded[strings.TrimSpace(version)] = strings.TrimSpace(key)
+	}
+	return encoded, scanner.Err()
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultKeyring *Keyring
+)
+
+// SetDefault 设置全局密钥环，未设置时凭证以明文保存
+func SetDefault(k *Keyring) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultKeyring = k
+}
+
+// Default 返回全局密钥环，可能为nil
+func Default() *Keyring {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultKeyring
+}
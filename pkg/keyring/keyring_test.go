@@ -0,0 +1,164 @@
+package keyring
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func key(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	k, err := New(map[int][]byte{1: key(1)}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := k.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Version != 1 {
+		t.Fatalf("Version = %d, want 1", env.Version)
+	}
+	plaintext, err := k.Decrypt(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != "secret" {
+		t.Fatalf("Decrypt() = %q, want secret", plaintext)
+	}
+
+	// 每次加密使用新的数据密钥和nonce
+	again, err := k.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Data == env.Data || again.DEK == env.DEK {
+		t.Fatal("Encrypt() reused the data key")
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	k, err := New(map[int][]byte{1: key(1)}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := k.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := base64.StdEncoding.DecodeString(env.Data)
+	data[0] ^= 0xff
+	tampered := *env
+	tampered.Data = base64.StdEncoding.EncodeToString(data)
+	if _, err := k.Decrypt(&tampered); err == nil {
+		t.Fatal("Decrypt() of tampered data succeeded")
+	}
+
+	other, err := New(map[int][]byte{1: key(2)}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Decrypt(env); err == nil {
+		t.Fatal("Decrypt() with wrong master key succeeded")
+	}
+}
+
+func TestRotation(t *testing.T) {
+	old, err := New(map[int][]byte{1: key(1)}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := old.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// active为0时使用最大版本，旧版本密文仍可解密
+	rotated, err := New(map[int][]byte{1: key(1), 2: key(2)}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.ActiveVersion() != 2 {
+		t.Fatalf("ActiveVersion() = %d, want 2", rotated.ActiveVersion())
+	}
+	if plaintext, err := rotated.Decrypt(env); err != nil || string(plaintext) != "secret" {
+		t.Fatalf("Decrypt() = %q, %v", plaintext, err)
+	}
+	reencrypted, err := rotated.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reencrypted.Version != 2 {
+		t.Fatalf("Version = %d, want 2", reencrypted.Version)
+	}
+
+	// 移除旧版本后无法解密旧密文
+	removed, err := New(map[int][]byte{2: key(2)}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := removed.Decrypt(env); err == nil {
+		t.Fatal("Decrypt() without key version 1 succeeded")
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   map[int][]byte
+		active int
+	}{
+		{"未配置", nil, 0},
+		{"版本非正数", map[int][]byte{0: key(1)}, 0},
+		{"长度错误", map[int][]byte{1: key(1)[:16]}, 0},
+		{"当前版本不存在", map[int][]byte{1: key(1)}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.keys, tt.active); err == nil {
+				t.Fatal("New() succeeded")
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	k, err := Load(nil, "", 0)
+	if err != nil || k != nil {
+		t.Fatalf("Load() without keys = %v, %v, want nil", k, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keys")
+	content := "# 主密钥\n2:" + base64.StdEncoding.EncodeToString(key(2)) + "\n\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	k, err = Load(map[string]string{"1": base64.StdEncoding.EncodeToString(key(1))}, path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.ActiveVersion() != 2 {
+		t.Fatalf("ActiveVersion() = %d, want 2", k.ActiveVersion())
+	}
+
+	if _, err := Load(map[string]string{"x": base64.StdEncoding.EncodeToString(key(1))}, "", 0); err == nil {
+		t.Fatal("Load() with invalid version succeeded")
+	}
+	if _, err := Load(map[string]string{"1": "not base64!"}, "", 0); err == nil {
+		t.Fatal("Load() with invalid key succeeded")
+	}
+	bad := filepath.Join(t.TempDir(), "bad")
+	if err := os.WriteFile(bad, []byte("no separator\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(nil, bad, 0); err == nil {
+		t.Fatal("Load() with malformed key file succeeded")
+	}
+}
@@ -11,12 +11,16 @@ import (
 	"gorm.io/gorm/logger"
 )
 
+// QueryHook 每条SQL执行后调用，可用于统计查询耗时
+type QueryHook func(ctx context.Context, begin time.Time, fc func() (string, int64), err error)
+
 type ZapGormLogger struct {
 	ZapLogger                 *zap.Logger
 	LogLevel                  logger.LogLevel
 	SlowThreshold             time.Duration
 	SkipCallerLookup          bool
 	IgnoreRecordNotFoundError bool
+	Hook                      QueryHook // 不受日志级别影响
 }
 
 // NewZapGormLogger 创建新的GORM-Zap日志适配器
@@ -73,6 +77,9 @@ func (l *ZapGormLogger) Error(ctx context.Context, msg string, data ...interface
 
 // Trace 打印SQL跟踪
 func (l *ZapGormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.Hook != nil {
+		l.Hook(ctx, begin, fc, err)
+	}
 	if l.LogLevel <= logger.Silent {
 		return
 	}
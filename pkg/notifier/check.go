@@ -0,0 +1,59 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// newHTTPClient 创建发送请求使用的HTTP客户端，proxy为空时不使用代理
+// http.Transport 原生支持HTTP、HTTPS和SOCKS5代理（包括地址中的用户名和密码）
+func newHTTPClient(proxy string) (*http.Client, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	proxyURL, err := parseProxy(proxy)
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	if proxyURL != nil {
+		client.Transport = &http.Transport{Proxy: http.ProxyURL(proxyURL)}
+	}
+	return client, nil
+}
+
+// checkURL 发送HEAD请求检查地址是否可达
+// 目标通常只接受POST，很多服务对HEAD返回404或405，因此仅认证失败、已失效（410）和服务端错误视为不可用
+func checkURL(ctx context.Context, client *http.Client, target string, headers map[string]string) (*Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, stripURL(err)
+	}
+	resp.Body.Close()
+
+	result := &Result{StatusCode: resp.StatusCode, Response: resp.Status}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden,
+		resp.StatusCode == http.StatusGone, resp.StatusCode >= 500:
+		return result, StatusError(resp.StatusCode, fmt.Errorf("响应失败: %s", resp.Status))
+	}
+	return result, nil
+}
+
+// stripURL 去掉请求错误中的URL，避免Webhook地址或URL中的Token出现在检查结果中
+func stripURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -1,73 +1,371 @@
 package notifier
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
+	"net/mail"
 	"net/smtp"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
+func init() {
+	Register(&emailNotifier{})
+}
+
 type EmailConfig struct {
-	Host     string
-	Port     int
-	Username string
-	Password string
-	From     string
-	To       string
-	Proxy    string // 可选，标准库 net/smtp 不支持代理
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	From       string // 发件人，支持 "名称 <地址>" 形式
+	To         string // 收件人，多个收件人以逗号分隔
+	Cc         string // 可选，抄送，多个收件人以逗号分隔
+	Bcc        string // 可选，密送，不写入邮件头
+	Security   string // tls/starttls/none，为空时587和25端口使用STARTTLS，其余端口使用隐式TLS
+	AuthMethod string // plain/login/cram-md5/none，为空时有用户名则使用PLAIN
+	CACert     string // 可选，PEM格式的CA证书，服务器证书始终会被校验
+	Proxy      string // 可选，SOCKS5或HTTP代理，SMTP连接通过SOCKS5或HTTP CONNECT建立
+}
+
+// emailCredentials Email通道凭证
+type emailCredentials struct {
+	SMTPHost     string `json:"smtpHost"`
+	SMTPPort     int    `json:"smtpPort"`
+	SMTPUsername string `json:"smtpUsername"`
+	SMTPPassword string `json:"smtpPassword"`
+	Sender       string `json:"sender"`
+	To           string `json:"to"`
+	Cc           string `json:"cc"`
+	Bcc          string `json:"bcc"`
+	Security     string `json:"security"`   // tls/starttls/none，为空时按端口选择
+	AuthMethod   string `json:"authMethod"` // plain/login/cram-md5/none，为空时有用户名则使用plain
+	CACert       string `json:"caCert"`     // 可选，PEM格式的CA证书，用于校验自签名证书
+	Proxy        string `json:"proxy"`
 }
 
-func SendEmail(cfg EmailConfig, subject, body string) error {
-	if cfg.Host == "" || cfg.Port == 0 || cfg.Username == "" || cfg.Password == "" || cfg.From == "" || cfg.To == "" {
-		return errors.New("邮件配置不完整")
+type emailNotifier struct{}
+
+func (n *emailNotifier) Schema() Schema {
+	return Schema{
+		Type:  "email",
+		Label: "Email",
+		Fields: []Field{
+			{Name: "smtpHost", Label: "SMTP服务器", Type: "string", Required: true, Endpoint: true},
+			{Name: "smtpPort", Label: "SMTP端口", Type: "int", Required: true, Endpoint: true},
+			{Name: "security", Label: "加密方式", Type: "string", Endpoint: true},
+			{Name: "authMethod", Label: "认证方式", Type: "string"},
+			{Name: "smtpUsername", Label: "用户名", Type: "string"},
+			{Name: "smtpPassword", Label: "密码", Type: "string", Secret: true},
+			{Name: "caCert", Label: "CA证书", Type: "string", Endpoint: true},
+			{Name: "sender", Label: "发件人", Type: "string", Required: true},
+			{Name: "to", Label: "收件人", Type: "string", Required: true},
+			{Name: "cc", Label: "抄送", Type: "string"},
+			{Name: "bcc", Label: "密送", Type: "string"},
+			{Name: "proxy", Label: "代理", Type: "string", Endpoint: true},
+			allowedRecipientsSchemaField,
+		},
 	}
+}
 
-	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
-	header := make(map[string]string)
-	header["From"] = cfg.From
-	header["To"] = cfg.To
-	header["Subject"] = subject
-	header["MIME-Version"] = "1.0"
-	header["Content-Type"] = "text/plain; charset=\"utf-8\""
+func (n *emailNotifier) Validate(credentials Credentials) error {
+	var config emailCredentials
+	if err := credentials.Decode(&config); err != nil {
+		return errors.New("Email配置格式错误")
+	}
+	if config.SMTPHost == "" {
+		return errors.New("Email配置不完整")
+	}
+	if err := validateProxy(config.Proxy); err != nil {
+		return err
+	}
+	return newEmailConfig(config).validateSecurity()
+}
 
-	var msg strings.Builder
-	for k, v := range header {
-		msg.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
+// newEmailConfig 将通道凭证转换为发送配置
+func newEmailConfig(config emailCredentials) EmailConfig {
+	return EmailConfig{
+		Host:       config.SMTPHost,
+		Port:       config.SMTPPort,
+		Username:   config.SMTPUsername,
+		Password:   config.SMTPPassword,
+		From:       config.Sender,
+		To:         config.To,
+		Cc:         config.Cc,
+		Bcc:        config.Bcc,
+		Security:   config.Security,
+		AuthMethod: config.AuthMethod,
+		CACert:     config.CACert,
+		Proxy:      config.Proxy,
 	}
-	msg.WriteString("\r\n" + body)
+}
 
-	tlsconfig := &tls.Config{
-		InsecureSkipVerify: true,
-		ServerName:         cfg.Host,
+// emailRecipientFields 路由可以指定的收件人字段，均支持逗号分隔的多个地址
+var emailRecipientFields = recipientFields{"to": true, "cc": true, "bcc": true}
+
+// ValidateOptions 校验路由选项：recipients 只能指定 to、cc、bcc，attachments 为附件列表
+func (n *emailNotifier) ValidateOptions(options map[string]interface{}) error {
+	if _, err := recipientTemplates(options, emailRecipientFields); err != nil {
+		return err
 	}
+	_, err := parseAttachmentSpecs(options)
+	return err
+}
 
-	conn, err := tls.Dial("tcp", addr, tlsconfig)
+// Send 发送邮件
+// 正文为纯文本，路由配置了HTML模板时同时发送HTML正文；路由选项 recipients 中的 to、cc、bcc 覆盖通道的收件人，
+// attachments 中的附件从消息内容的base64字段或URL读取
+func (n *emailNotifier) Send(ctx context.Context, credentials Credentials, msg *Message) (*Result, error) {
+	var config emailCredentials
+	if err := credentials.Decode(&config); err != nil {
+		return nil, Permanent(err)
+	}
+	recipients, err := resolveRecipients(credentials, msg, emailRecipientFields)
 	if err != nil {
-		return err
+		return nil, Permanent(err)
 	}
-	c, err := smtp.NewClient(conn, cfg.Host)
+	cfg := newEmailConfig(config)
+	for field, dst := range map[string]*string{"to": &cfg.To, "cc": &cfg.Cc, "bcc": &cfg.Bcc} {
+		if v, ok := recipients[field]; ok {
+			*dst = v
+		}
+	}
+
+	attachments, err := resolveAttachments(ctx, msg, config.Proxy)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return SendEmail(ctx, cfg, &Email{
+		Subject:     msg.Subject,
+		Text:        msg.Body,
+		HTML:        msg.HTMLBody,
+		Attachments: attachments,
+	})
+}
+
+func (n *emailNotifier) Test(ctx context.Context, credentials Credentials, subject, content string) (*Result, error) {
+	return n.Send(ctx, credentials, &Message{Subject: subject, Body: content})
+}
+
+// Check 连接SMTP服务器并完成EHLO、STARTTLS和认证，不发送邮件
+func (n *emailNotifier) Check(ctx context.Context, credentials Credentials) (*Result, error) {
+	var config emailCredentials
+	if err := credentials.Decode(&config); err != nil {
+		return nil, Permanent(err)
+	}
+	if config.SMTPHost == "" || config.SMTPPort == 0 {
+		return nil, Permanent(errors.New("邮件配置不完整"))
+	}
+
+	cfg := newEmailConfig(config)
+	c, err := dialSMTP(ctx, cfg)
+	if err != nil {
+		return nil, smtpContextError(ctx, err)
 	}
 	defer c.Quit()
+	if cfg.authMethod() == smtpAuthNone {
+		return &Result{Response: "连接成功"}, nil
+	}
+	return &Result{Response: "认证成功"}, nil
+}
 
-	if err = c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
-		return err
+// SendEmail 发送邮件，成功时结果中包含服务器返回的队列ID
+func SendEmail(ctx context.Context, cfg EmailConfig, email *Email) (*Result, error) {
+	if cfg.Host == "" || cfg.Port == 0 || cfg.From == "" {
+		return nil, Permanent(errors.New("邮件配置不完整"))
 	}
-	if err = c.Mail(cfg.From); err != nil {
-		return err
+
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, Permanent(fmt.Errorf("发件人格式错误: %s", cfg.From))
 	}
-	if err = c.Rcpt(cfg.To); err != nil {
-		return err
+	var to, cc, bcc []*mail.Address
+	for _, list := range []struct {
+		dst   *[]*mail.Address
+		value string
+	}{{&to, cfg.To}, {&cc, cfg.Cc}, {&bcc, cfg.Bcc}} {
+		if *list.dst, err = parseAddresses(list.value); err != nil {
+			return nil, Permanent(err)
+		}
+	}
+	rcpts := append(append(append([]*mail.Address{}, to...), cc...), bcc...)
+	if len(rcpts) == 0 {
+		return nil, Permanent(errors.New("邮件没有收件人"))
 	}
-	w, err := c.Data()
+
+	msg, err := buildEmail(from, to, cc, email, time.Now())
 	if err != nil {
-		return err
+		return nil, Permanent(err)
 	}
-	_, err = w.Write([]byte(msg.String()))
+
+	c, err := dialSMTP(ctx, cfg)
 	if err != nil {
-		return err
+		return nil, smtpContextError(ctx, err)
+	}
+	defer c.Quit()
+
+	result, err := smtpSend(c, from.Address, rcpts, msg)
+	if err != nil {
+		return nil, smtpContextError(ctx, err)
+	}
+	return result, nil
+}
+
+// smtpSend 发送信封和邮件内容
+func smtpSend(c *smtp.Client, from string, rcpts []*mail.Address, msg []byte) (*Result, error) {
+	if err := c.Mail(from); err != nil {
+		return nil, err
+	}
+	for _, rcpt := range rcpts {
+		if err := c.Rcpt(rcpt.Address); err != nil {
+			return nil, err
+		}
+	}
+	return smtpData(c, msg)
+}
+
+// smtpContextError ctx取消导致连接关闭时返回ctx的错误，而不是关闭连接产生的读写错误
+func smtpContextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// envelopeAddress 返回收件人实际投递的地址，与发送时 mail.ParseAddress 解析的RCPT TO地址一致，
+// 如 "名称 <地址>" 取地址部分、"地址 (注释)" 忽略注释；不是邮件地址时原样返回
+func envelopeAddress(recipient string) string {
+	if address, err := mail.ParseAddress(recipient); err == nil {
+		return address.Address
+	}
+	return recipient
+}
+
+// smtpTimeout 一次SMTP会话（从连接到发送完成）的最长时间，ctx的截止时间更早时以ctx为准；测试中可以调小
+var smtpTimeout = 2 * time.Minute
+
+// smtpConn 在ctx取消时关闭的连接，避免停止分发器时等待无响应的SMTP服务器
+type smtpConn struct {
+	net.Conn
+	stop func() bool
+}
+
+// newSMTPConn 为连接设置会话的截止时间，并在ctx取消时关闭连接
+func newSMTPConn(ctx context.Context, conn net.Conn) net.Conn {
+	deadline := time.Now().Add(smtpTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	return &smtpConn{Conn: conn, stop: context.AfterFunc(ctx, func() { conn.Close() })}
+}
+
+func (c *smtpConn) Close() error {
+	c.stop()
+	return c.Conn.Close()
+}
+
+// dialSMTP 按加密方式建立连接（配置了代理时经过代理）并完成EHLO、STARTTLS和认证，始终校验服务器证书
+func dialSMTP(ctx context.Context, cfg EmailConfig) (*smtp.Client, error) {
+	if err := cfg.validateSecurity(); err != nil {
+		return nil, Permanent(err)
+	}
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	security := cfg.security()
+
+	dial, err := newProxyDialer(cfg.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+	conn, err := dial(dialCtx, "tcp", net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)))
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	conn = newSMTPConn(ctx, conn)
+	if security == smtpSecurityTLS {
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if security == smtpSecurityStartTLS {
+		// 服务器不支持STARTTLS时不降级为明文
+		if err = c.Hello("localhost"); err != nil {
+			c.Close()
+			return nil, err
+		}
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			c.Close()
+			return nil, Permanent(errors.New("SMTP服务器不支持STARTTLS"))
+		}
+		if err = c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if auth := cfg.auth(); auth != nil {
+		if err = c.Auth(auth); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// smtpData 发送DATA命令和邮件内容，返回服务器的最终响应
+// smtp.Client.Data 不返回响应文本，这里直接使用底层连接以获取队列ID
+func smtpData(c *smtp.Client, msg []byte) (*Result, error) {
+	id, err := c.Text.Cmd("DATA")
+	if err != nil {
+		return nil, err
+	}
+	c.Text.StartResponse(id)
+	_, _, err = c.Text.ReadResponse(354)
+	c.Text.EndResponse(id)
+	if err != nil {
+		return nil, err
+	}
+
+	w := c.Text.DotWriter()
+	if _, err := w.Write(msg); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	code, reply, err := c.Text.ReadResponse(250)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{StatusCode: code, Response: reply, ProviderMessageID: smtpQueueID(reply)}, nil
+}
+
+// smtpQueueIDPattern 匹配常见MTA响应中的队列ID，如 "Ok: queued as 4F3A21C0"（Postfix）、"id=1abcDE-0003"（Exim）
+var smtpQueueIDPattern = regexp.MustCompile(`(?i)(?:queued as|id=)\s*<?([A-Za-z0-9._@-]+)`)
+
+// smtpQueueID 从DATA响应中提取队列ID，无法识别时返回整行响应
+func smtpQueueID(reply string) string {
+	if m := smtpQueueIDPattern.FindStringSubmatch(reply); m != nil {
+		return m[1]
 	}
-	return w.Close()
+	return strings.TrimSpace(reply)
 }
@@ -0,0 +1,262 @@
+package notifier
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"synapse/pkg/render"
+)
+
+const (
+	// attachmentsOption 路由选项中附件列表的键
+	attachmentsOption = "attachments"
+	// maxAttachmentSize 单个附件的最大字节数
+	maxAttachmentSize = 10 << 20
+	// maxAttachmentsTotal 所有附件的最大总字节数
+	maxAttachmentsTotal = 25 << 20
+)
+
+// attachmentSpec 路由选项 attachments 中的一项，各字段均为模板
+//
+//	{"filename": "chart.png", "url": "{{.Payload.chart_url}}", "contentId": "chart"}
+//	{"filename": "{{.Payload.report.name}}", "data": "{{.Payload.report.base64}}", "contentType": "application/pdf"}
+//
+// url 和 data 二选一，渲染结果都为空时忽略该附件；设置了 contentId 时作为内嵌图片，HTML正文中通过 cid:chart 引用
+type attachmentSpec struct {
+	Filename    string
+	URL         string
+	Data        string
+	ContentType string
+	ContentID   string
+}
+
+// attachmentFields 附件选项支持的字段
+var attachmentFields = []string{"filename", "url", "data", "contentType", "contentId"}
+
+// parseAttachmentSpecs 读取路由选项中的附件列表并校验模板语法
+func parseAttachmentSpecs(options map[string]interface{}) ([]attachmentSpec, error) {
+	raw, ok := options[attachmentsOption]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, errors.New("attachments必须是数组")
+	}
+
+	specs := make([]attachmentSpec, 0, len(items))
+	for i, item := range items {
+		spec, err := parseAttachmentSpec(fmt.Sprintf("attachments[%d]", i), item, attachmentFields)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// parseAttachmentSpec 解析一个附件对象，fields 为允许的字段，name 用于错误信息
+func parseAttachmentSpec(name string, item interface{}, fields []string) (attachmentSpec, error) {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return attachmentSpec{}, fmt.Errorf("%s必须是对象", name)
+	}
+	values := make(map[string]string, len(m))
+	for key, v := range m {
+		if !contains(fields, key) {
+			return attachmentSpec{}, fmt.Errorf("%s不支持的字段: %s", name, key)
+		}
+		text, ok := v.(string)
+		if !ok {
+			return attachmentSpec{}, fmt.Errorf("%s.%s必须是字符串", name, key)
+		}
+		if err := render.New(render.EngineText, nil).Parse(key, text); err != nil {
+			return attachmentSpec{}, fmt.Errorf("%s.%s模板错误: %v", name, key, err)
+		}
+		values[key] = text
+	}
+	if values["url"] == "" && values["data"] == "" {
+		return attachmentSpec{}, fmt.Errorf("%s需要url或data", name)
+	}
+	if values["url"] != "" && values["data"] != "" {
+		return attachmentSpec{}, fmt.Errorf("%s的url和data只能设置一个", name)
+	}
+	return attachmentSpec{
+		Filename:    values["filename"],
+		URL:         values["url"],
+		Data:        values["data"],
+		ContentType: values["contentType"],
+		ContentID:   values["contentId"],
+	}, nil
+}
+
+// render 使用模板数据渲染附件的各个字段
+func (spec attachmentSpec) render(data interface{}) (attachmentSpec, error) {
+	var rendered attachmentSpec
+	for _, field := range []struct {
+		dst  *string
+		text string
+	}{
+		{&rendered.Filename, spec.Filename},
+		{&rendered.URL, spec.URL},
+		{&rendered.Data, spec.Data},
+		{&rendered.ContentType, spec.ContentType},
+		{&rendered.ContentID, spec.ContentID},
+	} {
+		value, err := render.Text(field.text, data)
+		if err != nil {
+			return attachmentSpec{}, err
+		}
+		*field.dst = strings.TrimSpace(value)
+	}
+	return rendered, nil
+}
+
+// resolveAttachments 渲染附件选项并读取内容，url 通过通道的代理下载
+func resolveAttachments(ctx context.Context, msg *Message, proxy string) ([]Attachment, error) {
+	specs, err := parseAttachmentSpecs(msg.Options)
+	if err != nil || len(specs) == 0 {
+		return nil, Permanent(err)
+	}
+
+	data := msg.TemplateData()
+	var attachments []Attachment
+	total := 0
+	for i, spec := range specs {
+		rendered, err := spec.render(data)
+		if err != nil {
+			return nil, Permanent(fmt.Errorf("attachments[%d]渲染失败: %v", i, err))
+		}
+		attachment := Attachment{Filename: rendered.Filename, ContentType: rendered.ContentType, ContentID: rendered.ContentID}
+
+		switch {
+		case rendered.URL != "":
+			content, contentType, filename, err := downloadAttachment(ctx, rendered.URL, proxy)
+			if err != nil {
+				return nil, fmt.Errorf("attachments[%d]下载失败: %w", i, err)
+			}
+			attachment.Content = content
+			if attachment.ContentType == "" {
+				attachment.ContentType = contentType
+			}
+			if attachment.Filename == "" {
+				attachment.Filename = filename
+			}
+		case rendered.Data != "":
+			content, contentType, err := decodeAttachmentData(rendered.Data)
+			if err != nil {
+				return nil, Permanent(fmt.Errorf("attachments[%d]: %v", i, err))
+			}
+			attachment.Content = content
+			if attachment.ContentType == "" {
+				attachment.ContentType = contentType
+			}
+		default:
+			// 消息中没有对应内容时忽略该附件
+			continue
+		}
+
+		if attachment.Filename == "" {
+			attachment.Filename = fmt.Sprintf("attachment-%d", i+1)
+		}
+		if total += len(attachment.Content); total > maxAttachmentsTotal {
+			return nil, Permanent(fmt.Errorf("附件总大小超过%dMB", maxAttachmentsTotal>>20))
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+// decodeAttachmentData 解码base64内容，支持 data:image/png;base64,... 形式并返回其中的类型
+func decodeAttachmentData(value string) ([]byte, string, error) {
+	contentType := ""
+	if strings.HasPrefix(value, "data:") {
+		meta, encoded, ok := strings.Cut(value[len("data:"):], ",")
+		if !ok || !strings.HasSuffix(meta, ";base64") {
+			return nil, "", errors.New("只支持base64编码的data URI")
+		}
+		contentType = strings.TrimSuffix(meta, ";base64")
+		value = encoded
+	}
+	value = strings.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' || r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, value)
+
+	content, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		if content, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(value, "=")); err != nil {
+			return nil, "", errors.New("base64内容格式错误")
+		}
+	}
+	if len(content) > maxAttachmentSize {
+		return nil, "", fmt.Errorf("附件超过%dMB", maxAttachmentSize>>20)
+	}
+	return content, contentType, nil
+}
+
+// downloadAttachment 下载附件，返回内容、响应的Content-Type和URL路径中的文件名
+// URL来自消息内容，只允许访问公网地址，见 newPublicHTTPClient
+func downloadAttachment(ctx context.Context, target, proxy string) ([]byte, string, string, error) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, "", "", Permanent(errors.New("附件URL格式错误"))
+	}
+	client, err := newPublicHTTPClient(proxy)
+	if err != nil {
+		return nil, "", "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, "", "", Permanent(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if errors.Is(err, errForbiddenAddress) {
+			return nil, "", "", Permanent(stripURL(err))
+		}
+		return nil, "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", "", StatusError(resp.StatusCode, errors.New(resp.Status))
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxAttachmentSize+1))
+	if err != nil {
+		return nil, "", "", err
+	}
+	if len(content) > maxAttachmentSize {
+		return nil, "", "", Permanent(fmt.Errorf("附件超过%dMB", maxAttachmentSize>>20))
+	}
+
+	contentType := ""
+	if mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && mediaType != "application/octet-stream" {
+		contentType = mime.FormatMediaType(mediaType, params)
+	}
+	filename := path.Base(u.Path)
+	if filename == "/" || filename == "." {
+		filename = ""
+	}
+	return content, contentType, filename, nil
+}
+
+// contains 判断字符串是否在列表中
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,223 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Email 邮件内容
+type Email struct {
+	Subject     string
+	Text        string       // 纯文本正文
+	HTML        string       // 可选，HTML正文，与纯文本正文同时存在时组成 multipart/alternative
+	Attachments []Attachment // 可选，附件和内嵌图片
+}
+
+// Attachment 邮件附件
+type Attachment struct {
+	Filename    string
+	ContentType string // 为空时按文件名或内容推断
+	Content     []byte
+	ContentID   string // 不为空时作为内嵌图片，HTML正文中通过 cid:<ContentID> 引用
+}
+
+// parseAddresses 解析逗号分隔的收件人列表，支持 "名称 <地址>" 形式
+func parseAddresses(list string) ([]*mail.Address, error) {
+	var addresses []*mail.Address
+	for _, recipient := range splitRecipients(list) {
+		address, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("邮件地址格式错误: %s", recipient)
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// formatAddresses 格式化邮件头中的地址列表，非ASCII名称按RFC 2047编码
+func formatAddresses(addresses []*mail.Address) string {
+	formatted := make([]string, len(addresses))
+	for i, address := range addresses {
+		formatted[i] = address.String()
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// mimePart MIME实体，header中只包含Content-*头
+type mimePart struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// buildEmail 生成完整的邮件内容，邮件头按固定顺序输出，密送地址不写入邮件头
+func buildEmail(from *mail.Address, to, cc []*mail.Address, email *Email, now time.Time) ([]byte, error) {
+	body, err := emailBody(email)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	writeHeader := func(key, value string) {
+		msg.WriteString(key + ": " + value + "\r\n")
+	}
+	writeHeader("From", from.String())
+	if len(to) > 0 {
+		writeHeader("To", formatAddresses(to))
+	}
+	if len(cc) > 0 {
+		writeHeader("Cc", formatAddresses(cc))
+	}
+	writeHeader("Subject", mime.BEncoding.Encode("utf-8", sanitizeHeader(email.Subject)))
+	writeHeader("Date", now.Format(time.RFC1123Z))
+	writeHeader("Message-ID", messageID(from.Address))
+	writeHeader("MIME-Version", "1.0")
+	for _, key := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+		if value := body.header.Get(key); value != "" {
+			writeHeader(key, value)
+		}
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.body)
+	return msg.Bytes(), nil
+}
+
+// emailBody 按正文和附件组装MIME结构：
+// mixed（附件） > related（内嵌图片） > alternative（纯文本和HTML）
+func emailBody(email *Email) (mimePart, error) {
+	body := textPart("text/plain", email.Text)
+	if email.HTML != "" {
+		html := textPart("text/html", email.HTML)
+		if email.Text == "" {
+			body = html
+		} else {
+			var err error
+			if body, err = multipartPart("alternative", body, html); err != nil {
+				return mimePart{}, err
+			}
+		}
+	}
+
+	var inline, attached []mimePart
+	for _, attachment := range email.Attachments {
+		if attachment.ContentID != "" {
+			inline = append(inline, attachmentPart(attachment))
+		} else {
+			attached = append(attached, attachmentPart(attachment))
+		}
+	}
+	var err error
+	if len(inline) > 0 {
+		if body, err = multipartPart("related", append([]mimePart{body}, inline...)...); err != nil {
+			return mimePart{}, err
+		}
+	}
+	if len(attached) > 0 {
+		if body, err = multipartPart("mixed", append([]mimePart{body}, attached...)...); err != nil {
+			return mimePart{}, err
+		}
+	}
+	return body, nil
+}
+
+// textPart 使用quoted-printable编码的UTF-8文本
+func textPart(contentType, text string) mimePart {
+	var body bytes.Buffer
+	w := quotedprintable.NewWriter(&body)
+	w.Write([]byte(text))
+	w.Close()
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Type", contentType+"; charset=utf-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	return mimePart{header: header, body: body.Bytes()}
+}
+
+// multipartPart 将多个实体组合为 multipart/<subtype>
+func multipartPart(subtype string, parts ...mimePart) (mimePart, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, part := range parts {
+		pw, err := w.CreatePart(part.header)
+		if err != nil {
+			return mimePart{}, err
+		}
+		if _, err := pw.Write(part.body); err != nil {
+			return mimePart{}, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return mimePart{}, err
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Type", mime.FormatMediaType("multipart/"+subtype, map[string]string{"boundary": w.Boundary()}))
+	return mimePart{header: header, body: body.Bytes()}, nil
+}
+
+// attachmentPart 使用base64编码的附件，文件名中的非ASCII字符按RFC 2231编码
+func attachmentPart(attachment Attachment) mimePart {
+	filename := sanitizeHeader(filepath.Base(attachment.Filename))
+	contentType := sanitizeHeader(attachment.ContentType)
+	if contentType == "" {
+		contentType = detectContentType(filename, attachment.Content)
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Transfer-Encoding", "base64")
+	if attachment.ContentID != "" {
+		header.Set("Content-ID", "<"+sanitizeHeader(attachment.ContentID)+">")
+		header.Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": filename}))
+	} else {
+		header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	return mimePart{header: header, body: wrapBase64(attachment.Content)}
+}
+
+// detectContentType 按文件扩展名推断类型，无法识别时按内容推断
+func detectContentType(filename string, content []byte) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(filename)); contentType != "" {
+		return contentType
+	}
+	return http.DetectContentType(content)
+}
+
+// wrapBase64 base64编码并按76个字符换行
+func wrapBase64(content []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	var wrapped bytes.Buffer
+	for len(encoded) > 76 {
+		wrapped.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	wrapped.WriteString(encoded)
+	return wrapped.Bytes()
+}
+
+// sanitizeHeader 去除换行，防止邮件头注入
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", "", "\n", " ").Replace(value)
+}
+
+// messageID 生成 Message-ID，使用发件人地址的域名
+func messageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 && i < len(from)-1 {
+		domain = from[i+1:]
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
@@ -0,0 +1,207 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseEmail 使用net/mail解析buildEmail生成的邮件
+func parseEmail(t *testing.T, email *Email, to, cc []*mail.Address) *mail.Message {
+	t.Helper()
+	from := &mail.Address{Name: "告警中心", Address: "noreply@example.com"}
+	raw, err := buildEmail(from, to, cc, email, time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, line := range strings.Split(string(raw[:bytes.Index(raw, []byte("\r\n\r\n"))]), "\r\n") {
+		for _, r := range line {
+			if r > 127 {
+				t.Fatalf("header line %d is not ASCII: %q", i, line)
+			}
+		}
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// mimeTree 返回MIME结构，如 multipart/alternative(text/plain,text/html)，并收集文本正文和附件文件名
+func mimeTree(t *testing.T, contentType string, body io.Reader, texts map[string]string, files *[]string) string {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		content, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(mediaType, "text/") {
+			texts[mediaType] = string(content)
+		}
+		return mediaType
+	}
+
+	r := multipart.NewReader(body, params["boundary"])
+	var children []string
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name := part.FileName(); name != "" {
+			*files = append(*files, name)
+		}
+		// multipart.Reader已解码quoted-printable，base64附件需要自行解码
+		var partBody io.Reader = part
+		if part.Header.Get("Content-Transfer-Encoding") == "base64" {
+			partBody = base64.NewDecoder(base64.StdEncoding, part)
+		}
+		children = append(children, mimeTree(t, part.Header.Get("Content-Type"), partBody, texts, files))
+	}
+	return mediaType + "(" + strings.Join(children, ",") + ")"
+}
+
+func TestBuildEmailHeaders(t *testing.T) {
+	subject := "【严重】磁盘使用率 97%，请尽快处理"
+	to := []*mail.Address{{Name: "张三", Address: "zhangsan@example.com"}, {Address: "ops@example.com"}}
+	cc := []*mail.Address{{Name: "Doe, John", Address: "john@example.com"}}
+	msg := parseEmail(t, &Email{Subject: subject, Text: "正文"}, to, cc)
+
+	// 中文主题按RFC 2047编码，解码后与原文一致
+	rawSubject := msg.Header.Get("Subject")
+	if !strings.HasPrefix(strings.ToLower(rawSubject), "=?utf-8?b?") {
+		t.Fatalf("Subject = %q, want RFC 2047 encoded word", rawSubject)
+	}
+	decoded, err := new(mime.WordDecoder).DecodeHeader(rawSubject)
+	if err != nil || decoded != subject {
+		t.Fatalf("decoded Subject = %q, %v, want %q", decoded, err, subject)
+	}
+
+	from, err := msg.Header.AddressList("From")
+	if err != nil || len(from) != 1 || from[0].Name != "告警中心" || from[0].Address != "noreply@example.com" {
+		t.Fatalf("From = %v, %v", from, err)
+	}
+	gotTo, err := msg.Header.AddressList("To")
+	if err != nil || len(gotTo) != 2 || gotTo[0].Name != "张三" || gotTo[1].Address != "ops@example.com" {
+		t.Fatalf("To = %v, %v", gotTo, err)
+	}
+	gotCc, err := msg.Header.AddressList("Cc")
+	if err != nil || len(gotCc) != 1 || gotCc[0].Name != "Doe, John" || gotCc[0].Address != "john@example.com" {
+		t.Fatalf("Cc = %v, %v", gotCc, err)
+	}
+	// 密送地址只出现在信封中，buildEmail不会写入Bcc头
+	if _, ok := msg.Header["Bcc"]; ok {
+		t.Fatal("Bcc header written")
+	}
+
+	if date, err := msg.Header.Date(); err != nil || !date.Equal(time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)) {
+		t.Fatalf("Date = %v, %v", date, err)
+	}
+	if id := msg.Header.Get("Message-ID"); !strings.HasSuffix(id, "@example.com>") {
+		t.Fatalf("Message-ID = %q", id)
+	}
+	if msg.Header.Get("MIME-Version") != "1.0" {
+		t.Fatalf("MIME-Version = %q", msg.Header.Get("MIME-Version"))
+	}
+}
+
+func TestBuildEmailHeaderInjection(t *testing.T) {
+	msg := parseEmail(t, &Email{
+		Subject: "磁盘告警\r\nBcc: evil@attacker.com\r\n\r\n伪造的正文",
+		Text:    "正文",
+		Attachments: []Attachment{{
+			Filename:    "report.txt\r\nX-Injected: 1",
+			ContentType: "text/plain\r\nX-Injected: 1",
+			Content:     []byte("data"),
+		}},
+	}, []*mail.Address{{Address: "ops@example.com"}}, nil)
+
+	if _, ok := msg.Header["Bcc"]; ok {
+		t.Fatal("CRLF in subject injected a Bcc header")
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || strings.ContainsAny(subject, "\r\n") || !strings.Contains(subject, "Bcc: evil@attacker.com") {
+		t.Fatalf("Subject = %q, %v, want the injected text kept on one line", subject, err)
+	}
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := part.Header["X-Injected"]; ok {
+			t.Fatalf("CRLF in attachment injected a header: %v", part.Header)
+		}
+	}
+}
+
+func TestBuildEmailStructure(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n" + strings.Repeat("x", 100))
+	text := "磁盘使用率 97%，请尽快处理。" + strings.Repeat("很长的一行", 30)
+	html := `<p>磁盘使用率 <b>97%</b></p><img src="cid:chart">`
+	tests := []struct {
+		name  string
+		email *Email
+		want  string
+		files []string
+	}{
+		{"纯文本", &Email{Text: text}, "text/plain", nil},
+		{"只有HTML", &Email{HTML: html}, "text/html", nil},
+		{"文本和HTML", &Email{Text: text, HTML: html}, "multipart/alternative(text/plain,text/html)", nil},
+		{"内嵌图片", &Email{Text: text, HTML: html, Attachments: []Attachment{{Filename: "chart.png", Content: png, ContentID: "chart"}}},
+			"multipart/related(multipart/alternative(text/plain,text/html),image/png)", []string{"chart.png"}},
+		{"附件", &Email{Text: text, Attachments: []Attachment{{Filename: "报告.pdf", Content: []byte("%PDF-1.4")}}},
+			"multipart/mixed(text/plain,application/pdf)", []string{"报告.pdf"}},
+		{"内嵌图片和附件", &Email{Text: text, HTML: html, Attachments: []Attachment{
+			{Filename: "chart.png", Content: png, ContentID: "chart"},
+			{Filename: "../../etc/报告.pdf", Content: []byte("%PDF-1.4")},
+		}}, "multipart/mixed(multipart/related(multipart/alternative(text/plain,text/html),image/png),application/pdf)", []string{"chart.png", "报告.pdf"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := parseEmail(t, tt.email, []*mail.Address{{Address: "ops@example.com"}}, nil)
+
+			var body io.Reader = msg.Body
+			if msg.Header.Get("Content-Transfer-Encoding") == "quoted-printable" {
+				body = quotedprintable.NewReader(msg.Body)
+			}
+			texts := map[string]string{}
+			var files []string
+			if got := mimeTree(t, msg.Header.Get("Content-Type"), body, texts, &files); got != tt.want {
+				t.Fatalf("structure = %s, want %s", got, tt.want)
+			}
+			if strings.Join(files, ",") != strings.Join(tt.files, ",") {
+				t.Fatalf("files = %v, want %v", files, tt.files)
+			}
+			if tt.email.Text != "" && texts["text/plain"] != tt.email.Text {
+				t.Fatalf("text/plain = %q, want %q", texts["text/plain"], tt.email.Text)
+			}
+			if tt.email.HTML != "" && texts["text/html"] != tt.email.HTML {
+				t.Fatalf("text/html = %q, want %q", texts["text/html"], tt.email.HTML)
+			}
+		})
+	}
+}
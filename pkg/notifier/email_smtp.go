@@ -0,0 +1,150 @@
+package notifier
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+// SMTP连接加密方式
+const (
+	smtpSecurityTLS      = "tls"      // 隐式TLS，通常为465端口
+	smtpSecurityStartTLS = "starttls" // 明文连接后通过STARTTLS升级，通常为587和25端口
+	smtpSecurityNone     = "none"     // 不加密，仅用于内网中继
+)
+
+// SMTP认证方式
+const (
+	smtpAuthPlain   = "plain"
+	smtpAuthLogin   = "login"
+	smtpAuthCRAMMD5 = "cram-md5"
+	smtpAuthNone    = "none"
+)
+
+// security 返回连接加密方式，未配置时587和25端口使用STARTTLS，其余端口使用隐式TLS
+func (cfg EmailConfig) security() string {
+	if security := strings.ToLower(strings.TrimSpace(cfg.Security)); security != "" {
+		return security
+	}
+	if cfg.Port == 587 || cfg.Port == 25 {
+		return smtpSecurityStartTLS
+	}
+	return smtpSecurityTLS
+}
+
+// authMethod 返回认证方式，未配置时有用户名则使用PLAIN，否则不认证
+func (cfg EmailConfig) authMethod() string {
+	if method := strings.ToLower(strings.TrimSpace(cfg.AuthMethod)); method != "" {
+		return method
+	}
+	if cfg.Username == "" {
+		return smtpAuthNone
+	}
+	return smtpAuthPlain
+}
+
+// validateSecurity 校验加密方式、认证方式和CA证书
+func (cfg EmailConfig) validateSecurity() error {
+	security := cfg.security()
+	switch security {
+	case smtpSecurityTLS, smtpSecurityStartTLS, smtpSecurityNone:
+	default:
+		return fmt.Errorf("不支持的加密方式: %s", cfg.Security)
+	}
+
+	method := cfg.authMethod()
+	switch method {
+	case smtpAuthPlain, smtpAuthLogin, smtpAuthCRAMMD5:
+		if cfg.Username == "" || cfg.Password == "" {
+			return errors.New("邮件认证需要用户名和密码")
+		}
+	case smtpAuthNone:
+	default:
+		return fmt.Errorf("不支持的认证方式: %s", cfg.AuthMethod)
+	}
+	// PLAIN和LOGIN以明文传输密码，与 net/smtp 相同只允许在加密连接或本机上使用
+	if security == smtpSecurityNone && (method == smtpAuthPlain || method == smtpAuthLogin) && !isLocalhost(cfg.Host) {
+		return errors.New("不加密的连接不能使用PLAIN或LOGIN认证，请使用TLS、STARTTLS或CRAM-MD5")
+	}
+
+	_, err := cfg.tlsConfig()
+	return err
+}
+
+// tlsConfig 返回校验服务器证书的TLS配置，配置了CA证书时同时信任其中的证书
+func (cfg EmailConfig) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName: cfg.Host,
+		MinVersion: tls.VersionTLS12,
+	}
+	if strings.TrimSpace(cfg.CACert) == "" {
+		return config, nil
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM([]byte(cfg.CACert)) {
+		return nil, errors.New("CA证书格式错误，需要PEM格式")
+	}
+	config.RootCAs = pool
+	return config, nil
+}
+
+// auth 返回认证方式对应的 smtp.Auth，不认证时返回nil
+func (cfg EmailConfig) auth() smtp.Auth {
+	switch cfg.authMethod() {
+	case smtpAuthPlain:
+		return smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	case smtpAuthLogin:
+		return &loginAuth{username: cfg.Username, password: cfg.Password, host: cfg.Host}
+	case smtpAuthCRAMMD5:
+		return smtp.CRAMMD5Auth(cfg.Username, cfg.Password)
+	}
+	return nil
+}
+
+// loginAuth 实现LOGIN认证，net/smtp 只提供PLAIN和CRAM-MD5
+type loginAuth struct {
+	username, password, host string
+	step                     int
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	// 与 smtp.PlainAuth 相同，不在未加密的连接上发送密码
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	a.step = 0
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	a.step++
+	prompt := strings.ToLower(string(fromServer))
+	switch {
+	case strings.Contains(prompt, "username"):
+		return []byte(a.username), nil
+	case strings.Contains(prompt, "password"):
+		return []byte(a.password), nil
+	case a.step == 1:
+		return []byte(a.username), nil
+	case a.step == 2:
+		return []byte(a.password), nil
+	}
+	return nil, fmt.Errorf("unexpected server challenge: %s", fromServer)
+}
+
+// isLocalhost 判断主机是否为本机
+func isLocalhost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
@@ -0,0 +1,337 @@
+package notifier
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"mime"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCert 生成127.0.0.1的自签名证书，返回证书和PEM格式的CA
+func fakeCert(t *testing.T) (tls.Certificate, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake smtp"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// fakeSMTP 基于net.Listener的SMTP服务器，用户名user、密码pass，记录认证方式和收到的邮件
+type fakeSMTP struct {
+	implicitTLS bool // 隐式TLS
+	startTLS    bool // EHLO中声明STARTTLS
+	stall       bool // 接受连接后不发送问候
+	cert        tls.Certificate
+
+	mu    sync.Mutex
+	auths []string
+	mails []fakeMail
+}
+
+type fakeMail struct {
+	from string
+	rcpt []string
+	data string
+	tls  bool
+}
+
+// start 在127.0.0.1的随机端口监听，返回端口
+func (f *fakeSMTP) start(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	t.Cleanup(func() {
+		l.Close()
+		wg.Wait()
+	})
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				f.serve(conn)
+			}()
+		}
+	}()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func (f *fakeSMTP) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if f.stall {
+		// 直到客户端关闭连接
+		conn.Read(make([]byte, 1))
+		return
+	}
+	isTLS := false
+	if f.implicitTLS {
+		conn = tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{f.cert}})
+		isTLS = true
+	}
+
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 fake ESMTP")
+	var mail fakeMail
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			tp.PrintfLine("250-fake")
+			if f.startTLS && !isTLS {
+				tp.PrintfLine("250-STARTTLS")
+			}
+			tp.PrintfLine("250 AUTH PLAIN LOGIN CRAM-MD5")
+		case cmd == "STARTTLS":
+			tp.PrintfLine("220 ready")
+			conn = tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{f.cert}})
+			tp = textproto.NewConn(conn)
+			isTLS = true
+		case strings.HasPrefix(cmd, "AUTH PLAIN "):
+			decoded, _ := base64.StdEncoding.DecodeString(line[len("AUTH PLAIN "):])
+			f.auth(tp, string(decoded) == "\x00user\x00pass", "plain")
+		case cmd == "AUTH LOGIN":
+			tp.PrintfLine("334 VXNlcm5hbWU6")
+			username, _ := tp.ReadLine()
+			tp.PrintfLine("334 UGFzc3dvcmQ6")
+			password, _ := tp.ReadLine()
+			u, _ := base64.StdEncoding.DecodeString(username)
+			p, _ := base64.StdEncoding.DecodeString(password)
+			f.auth(tp, string(u) == "user" && string(p) == "pass", "login")
+		case cmd == "AUTH CRAM-MD5":
+			challenge := "<123.456@fake>"
+			tp.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(challenge)))
+			response, _ := tp.ReadLine()
+			decoded, _ := base64.StdEncoding.DecodeString(response)
+			mac := hmac.New(md5.New, []byte("pass"))
+			mac.Write([]byte(challenge))
+			f.auth(tp, string(decoded) == "user "+hex.EncodeToString(mac.Sum(nil)), "cram-md5")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			mail = fakeMail{from: line[len("MAIL FROM:"):], tls: isTLS}
+			tp.PrintfLine("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			mail.rcpt = append(mail.rcpt, line[len("RCPT TO:"):])
+			tp.PrintfLine("250 ok")
+		case cmd == "DATA":
+			tp.PrintfLine("354 end with .")
+			lines, err := tp.ReadDotLines()
+			if err != nil {
+				return
+			}
+			mail.data = strings.Join(lines, "\n")
+			f.mu.Lock()
+			f.mails = append(f.mails, mail)
+			f.mu.Unlock()
+			tp.PrintfLine("250 Ok: queued as ABC123")
+		case cmd == "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 unknown command")
+		}
+	}
+}
+
+func (f *fakeSMTP) auth(tp *textproto.Conn, ok bool, method string) {
+	if !ok {
+		tp.PrintfLine("535 authentication failed")
+		return
+	}
+	f.mu.Lock()
+	f.auths = append(f.auths, method)
+	f.mu.Unlock()
+	tp.PrintfLine("235 ok")
+}
+
+func (f *fakeSMTP) received() ([]string, []fakeMail) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.auths...), append([]fakeMail(nil), f.mails...)
+}
+
+func TestSendEmailSMTP(t *testing.T) {
+	cert, ca := fakeCert(t)
+	tests := []struct {
+		name        string
+		implicitTLS bool
+		startTLS    bool
+		cfg         EmailConfig
+		wantAuth    string // 为空表示不认证
+		wantTLS     bool
+	}{
+		{"none", false, false, EmailConfig{Security: "none"}, "", false},
+		{"none CRAM-MD5", false, false, EmailConfig{Security: "none", AuthMethod: "cram-md5", Username: "user", Password: "pass"}, "cram-md5", false},
+		{"starttls PLAIN", false, true, EmailConfig{Security: "starttls", Username: "user", Password: "pass", CACert: ca}, "plain", true},
+		{"starttls LOGIN", false, true, EmailConfig{Security: "starttls", AuthMethod: "LOGIN", Username: "user", Password: "pass", CACert: ca}, "login", true},
+		{"starttls CRAM-MD5", false, true, EmailConfig{Security: "starttls", AuthMethod: "cram-md5", Username: "user", Password: "pass", CACert: ca}, "cram-md5", true},
+		{"tls PLAIN", true, false, EmailConfig{Security: "tls", Username: "user", Password: "pass", CACert: ca}, "plain", true},
+		{"tls LOGIN", true, false, EmailConfig{Security: "tls", AuthMethod: "login", Username: "user", Password: "pass", CACert: ca}, "login", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &fakeSMTP{implicitTLS: tt.implicitTLS, startTLS: tt.startTLS, cert: cert}
+			cfg := tt.cfg
+			cfg.Host, cfg.Port = "127.0.0.1", server.start(t)
+			cfg.From, cfg.To, cfg.Bcc = "Synapse <noreply@example.com>", "ops@example.com", "audit@example.com"
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			result, err := SendEmail(ctx, cfg, &Email{Subject: "磁盘告警", Text: "使用率97%"})
+			if err != nil {
+				t.Fatalf("SendEmail() error: %v", err)
+			}
+			if result.ProviderMessageID != "ABC123" {
+				t.Fatalf("ProviderMessageID = %q, want ABC123", result.ProviderMessageID)
+			}
+
+			auths, mails := server.received()
+			if tt.wantAuth == "" && len(auths) != 0 || tt.wantAuth != "" && (len(auths) != 1 || auths[0] != tt.wantAuth) {
+				t.Fatalf("auths = %v, want %q", auths, tt.wantAuth)
+			}
+			if len(mails) != 1 {
+				t.Fatalf("received %d mails, want 1", len(mails))
+			}
+			mail := mails[0]
+			if mail.tls != tt.wantTLS {
+				t.Fatalf("mail sent over TLS = %v, want %v", mail.tls, tt.wantTLS)
+			}
+			if mail.from != "<noreply@example.com>" || strings.Join(mail.rcpt, ",") != "<ops@example.com>,<audit@example.com>" {
+				t.Fatalf("envelope = %s -> %v", mail.from, mail.rcpt)
+			}
+			if strings.Contains(mail.data, "audit@example.com") {
+				t.Fatal("Bcc recipient written to message headers")
+			}
+			if want := "Subject: " + mime.BEncoding.Encode("utf-8", "磁盘告警"); !strings.Contains(mail.data, want) {
+				t.Fatalf("message does not contain %q", want)
+			}
+		})
+	}
+}
+
+func TestSendEmailSMTPErrors(t *testing.T) {
+	cert, ca := fakeCert(t)
+	_, otherCA := fakeCert(t)
+	tests := []struct {
+		name        string
+		implicitTLS bool
+		startTLS    bool
+		cfg         EmailConfig
+		want        string
+		retryable   bool
+	}{
+		{"密码错误", false, true, EmailConfig{Security: "starttls", Username: "user", Password: "wrong", CACert: ca}, "535", false},
+		{"starttls未信任证书", false, true, EmailConfig{Security: "starttls", Username: "user", Password: "pass"}, "certificate", false},
+		{"starttls其他CA", false, true, EmailConfig{Security: "starttls", Username: "user", Password: "pass", CACert: otherCA}, "certificate", false},
+		{"tls未信任证书", true, false, EmailConfig{Security: "tls", Username: "user", Password: "pass"}, "certificate", false},
+		{"服务器不支持STARTTLS", false, false, EmailConfig{Security: "starttls", Username: "user", Password: "pass", CACert: ca}, "不支持STARTTLS", false},
+		{"CA证书格式错误", false, false, EmailConfig{Security: "starttls", CACert: "not a pem"}, "CA证书", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &fakeSMTP{implicitTLS: tt.implicitTLS, startTLS: tt.startTLS, cert: cert}
+			cfg := tt.cfg
+			cfg.Host, cfg.Port = "127.0.0.1", server.start(t)
+			cfg.From, cfg.To = "noreply@example.com", "ops@example.com"
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err := SendEmail(ctx, cfg, &Email{Subject: "test", Text: "body"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("SendEmail() error = %v, want %q", err, tt.want)
+			}
+			if IsRetryable(err) != tt.retryable {
+				t.Fatalf("IsRetryable(%v) = %v, want %v", err, IsRetryable(err), tt.retryable)
+			}
+			if _, mails := server.received(); len(mails) != 0 {
+				t.Fatalf("server received %d mails", len(mails))
+			}
+		})
+	}
+}
+
+func TestSendEmailPlainAuthRequiresEncryption(t *testing.T) {
+	cfg := EmailConfig{Host: "mail.example.com", Port: 25, Security: "none", Username: "user", Password: "pass",
+		From: "noreply@example.com", To: "ops@example.com"}
+	_, err := SendEmail(context.Background(), cfg, &Email{Subject: "test", Text: "body"})
+	if err == nil || !strings.Contains(err.Error(), "不加密") || IsRetryable(err) {
+		t.Fatalf("SendEmail() error = %v, want permanent plaintext auth error", err)
+	}
+}
+
+func TestSendEmailCancel(t *testing.T) {
+	server := &fakeSMTP{stall: true}
+	cfg := EmailConfig{Host: "127.0.0.1", Port: server.start(t), Security: "none", From: "noreply@example.com", To: "ops@example.com"}
+
+	// 分发器的ctx没有截止时间，停止时取消
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := SendEmail(ctx, cfg, &Email{Subject: "test", Text: "body"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("SendEmail() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("SendEmail() returned after %v", elapsed)
+	}
+}
+
+func TestSendEmailDefaultTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { smtpTimeout = timeout }(smtpTimeout)
+	smtpTimeout = 200 * time.Millisecond
+
+	server := &fakeSMTP{stall: true}
+	cfg := EmailConfig{Host: "127.0.0.1", Port: server.start(t), Security: "none", From: "noreply@example.com", To: "ops@example.com"}
+
+	start := time.Now()
+	_, err := SendEmail(context.Background(), cfg, &Email{Subject: "test", Text: "body"})
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("SendEmail() error = %v, want timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("SendEmail() returned after %v", elapsed)
+	}
+}
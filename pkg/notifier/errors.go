@@ -0,0 +1,85 @@
+package notifier
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/textproto"
+)
+
+// Error 通知发送错误，携带HTTP状态码和是否可重试
+type Error struct {
+	StatusCode int
+	Retryable  bool
+	Err        error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Permanent 将错误标记为不可重试（配置错误、模板错误等）
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Err: err, Retryable: false}
+}
+
+// StatusError 根据HTTP状态码构造错误，408/425/429和5xx视为可重试
+func StatusError(statusCode int, err error) error {
+	return &Error{StatusCode: statusCode, Retryable: IsRetryableStatus(statusCode), Err: err}
+}
+
+// IsRetryableStatus 判断HTTP状态码是否值得重试
+func IsRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	}
+	return statusCode >= 500
+}
+
+// IsRetryable 判断发送错误是否值得重试
+// 未分类的错误（网络超时、连接被拒绝等）默认可重试
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var notifierErr *Error
+	if errors.As(err, &notifierErr) {
+		return notifierErr.Retryable
+	}
+
+	// SMTP 4xx为临时错误，5xx为永久错误
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code < 500
+	}
+
+	// 证书校验失败重试也无法恢复
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &certErr) {
+		return false
+	}
+
+	return true
+}
+
+// StatusCodeOf 返回错误携带的HTTP状态码或SMTP响应码，没有时返回0
+func StatusCodeOf(err error) int {
+	var notifierErr *Error
+	if errors.As(err, &notifierErr) && notifierErr.StatusCode != 0 {
+		return notifierErr.StatusCode
+	}
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code
+	}
+	return 0
+}
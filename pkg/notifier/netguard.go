@@ -0,0 +1,113 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// 附件URL来自Webhook消息内容，不可信。下载时只允许连接公网地址，避免读取云服务元数据、内网服务等（SSRF）
+
+// errForbiddenAddress 目标地址不是公网地址
+var errForbiddenAddress = errors.New("不允许访问内网、本机或保留地址")
+
+// forbiddenPrefixes IsPrivate、IsLoopback等方法未覆盖的保留地址段
+var forbiddenPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // 本网络
+	netip.MustParsePrefix("100.64.0.0/10"),  // 运营商级NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF协议分配
+	netip.MustParsePrefix("198.18.0.0/15"),  // 基准测试
+	netip.MustParsePrefix("240.0.0.0/4"),    // 保留
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64，可映射到内网IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"), // 本地NAT64
+	netip.MustParsePrefix("2002::/16"),      // 6to4，可映射到内网IPv4
+}
+
+// isPublicAddr 判断是否为允许连接的公网地址，拒绝回环、私有、链路本地（包括169.254.169.254元数据地址）、未指定、组播和保留地址
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	if addr == netip.AddrFrom4([4]byte{255, 255, 255, 255}) {
+		return false
+	}
+	for _, prefix := range forbiddenPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// publicOnlyControl 作为net.Dialer的Control钩子，在DNS解析之后、建立连接之前检查实际连接的地址
+func publicOnlyControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errForbiddenAddress, address)
+	}
+	if !isPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", errForbiddenAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// checkPublicHost 解析主机名并检查所有地址，用于经过代理的请求（连接由代理建立，Control钩子只能看到代理地址）
+// 代理会再次解析域名，无法完全防止DNS重绑定，需要严格隔离时不要为下载附件的通道配置代理
+func checkPublicHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !isPublicAddr(addr) {
+			return fmt.Errorf("%w: %s", errForbiddenAddress, addr)
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !isPublicAddr(addr) {
+			return fmt.Errorf("%w: %s -> %s", errForbiddenAddress, host, addr)
+		}
+	}
+	return nil
+}
+
+// newPublicHTTPClient 创建只能访问公网地址的HTTP客户端，用于请求不可信的URL
+// 未配置代理时直接连接（不使用环境变量中的代理），由Control钩子检查地址；配置代理时在每个请求（包括重定向）前解析检查目标主机
+func newPublicHTTPClient(proxy string) (*http.Client, error) {
+	proxyURL, err := parseProxy(proxy)
+	if err != nil {
+		return nil, Permanent(err)
+	}
+
+	if proxyURL == nil {
+		dialer := &net.Dialer{Timeout: dialTimeout, Control: publicOnlyControl}
+		return &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: dialTimeout},
+		}, nil
+	}
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: publicHostTransport{&http.Transport{Proxy: http.ProxyURL(proxyURL)}},
+	}, nil
+}
+
+// publicHostTransport 发送请求前检查目标主机是否为公网地址
+type publicHostTransport struct {
+	base http.RoundTripper
+}
+
+func (t publicHostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := checkPublicHost(req.Context(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,111 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"203.0.114.10", true},
+		{"2606:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // 云服务元数据
+		{"fd00:ec2::254", false},   // AWS IPv6元数据
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.100.100.200", false}, // 阿里云元数据，位于运营商级NAT地址段
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::ffff:127.0.0.1", false}, // IPv4映射地址
+		{"::ffff:169.254.169.254", false},
+		{"64:ff9b::a00:1", false}, // NAT64映射的10.0.0.1
+	}
+	for _, tt := range tests {
+		if got := isPublicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("isPublicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadAttachmentRejectsPrivateAddress(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte("secret"))
+	}))
+	defer server.Close()
+
+	port := server.URL[strings.LastIndex(server.URL, ":")+1:]
+	for _, target := range []string{
+		server.URL + "/internal",
+		"http://localhost:" + port + "/internal", // 域名解析后检查
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::ffff:127.0.0.1]:" + port + "/internal",
+	} {
+		_, _, _, err := downloadAttachment(context.Background(), target, "")
+		if !errors.Is(err, errForbiddenAddress) {
+			t.Errorf("downloadAttachment(%s) error = %v, want errForbiddenAddress", target, err)
+			continue
+		}
+		if IsRetryable(err) {
+			t.Errorf("downloadAttachment(%s) error is retryable", target)
+		}
+	}
+	if hits.Load() != 0 {
+		t.Fatalf("internal server received %d requests", hits.Load())
+	}
+}
+
+func TestDownloadAttachmentRejectsRedirectToPrivateAddress(t *testing.T) {
+	// 首个请求经过代理发往公网地址，代理返回指向内网的重定向
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	}))
+	defer proxy.Close()
+
+	_, _, _, err := downloadAttachment(context.Background(), "http://8.8.8.8/chart.png", proxy.URL)
+	if !errors.Is(err, errForbiddenAddress) {
+		t.Fatalf("downloadAttachment() error = %v, want errForbiddenAddress", err)
+	}
+	if len(proxied) != 1 || proxied[0] != "http://8.8.8.8/chart.png" {
+		t.Fatalf("proxied requests = %v, want only the public URL", proxied)
+	}
+}
+
+func TestDownloadAttachmentThroughProxyRejectsPrivateTarget(t *testing.T) {
+	// 代理本身可以是内网地址，但目标地址必须是公网地址
+	var hits atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte("secret"))
+	}))
+	defer proxy.Close()
+
+	for _, target := range []string{"http://127.0.0.1/", "http://localhost/", "http://[fd00:ec2::254]/"} {
+		_, _, _, err := downloadAttachment(context.Background(), target, proxy.URL)
+		if !errors.Is(err, errForbiddenAddress) {
+			t.Errorf("downloadAttachment(%s) error = %v, want errForbiddenAddress", target, err)
+		}
+	}
+	if hits.Load() != 0 {
+		t.Fatalf("proxy received %d requests", hits.Load())
+	}
+}
@@ -0,0 +1,250 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"synapse/pkg/render"
+)
+
+// Credentials 通道凭证，对应 model.Channel.Credentials
+type Credentials map[string]interface{}
+
+// Decode 将凭证解码到结构体
+func (c Credentials) Decode(v interface{}) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// Field 凭证字段描述
+type Field struct {
+	Name     string `json:"name"`
+	Label    string `json:"label"`
+	Type     string `json:"type"` // string/int/bool/map
+	Required bool   `json:"required"`
+	Secret   bool   `json:"secret"`
+	Endpoint bool   `json:"endpoint"` // 决定凭证发往何处，修改后掩码的密钥字段必须重新填写
+}
+
+// Schema 通道类型描述
+type Schema struct {
+	Type   string  `json:"type"`
+	Label  string  `json:"label"`
+	Fields []Field `json:"fields"`
+}
+
+// MaskedValue 响应中密钥字段的掩码
+const MaskedValue = "******"
+
+// Mask 返回掩码后的凭证副本，标记为Secret的非空字段替换为掩码
+func (s Schema) Mask(credentials Credentials) Credentials {
+	masked := make(Credentials, len(credentials))
+	for k, v := range credentials {
+		masked[k] = v
+	}
+	for _, field := range s.Fields {
+		if !field.Secret {
+			continue
+		}
+		switch v := credentials[field.Name].(type) {
+		case string:
+			if v != "" {
+				masked[field.Name] = MaskedValue
+			}
+		case map[string]interface{}:
+			m := make(map[string]interface{}, len(v))
+			for key := range v {
+				m[key] = MaskedValue
+			}
+			masked[field.Name] = m
+		}
+	}
+	return masked
+}
+
+// Unmask 将更新请求中仍为掩码的密钥字段替换为已保存的值
+// 修改了Endpoint字段时不沿用已保存的密钥，避免将其发往新的地址，此时仍为掩码的密钥字段返回错误
+func (s Schema) Unmask(credentials, stored Credentials) (Credentials, error) {
+	changed := s.changedEndpoint(credentials, stored)
+	for _, field := range s.Fields {
+		if !field.Secret {
+			continue
+		}
+		switch v := credentials[field.Name].(type) {
+		case string:
+			if v == MaskedValue {
+				if changed != "" {
+					return nil, fmt.Errorf("修改%s后需要重新填写%s", changed, field.Name)
+				}
+				credentials[field.Name] = stored[field.Name]
+			}
+		case map[string]interface{}:
+			storedMap, _ := stored[field.Name].(map[string]interface{})
+			for key, value := range v {
+				if value == MaskedValue {
+					if changed != "" {
+						return nil, fmt.Errorf("修改%s后需要重新填写%s.%s", changed, field.Name, key)
+					}
+					if storedValue, ok := storedMap[key]; ok {
+						v[key] = storedValue
+					} else {
+						delete(v, key)
+					}
+				}
+			}
+		}
+	}
+	return credentials, nil
+}
+
+// RejectMasked 检查没有已保存值可沿用的凭证（新建通道或修改了通道类型），任何字段仍为掩码时返回错误，
+// 避免把掩码当作真实的密钥保存
+func RejectMasked(credentials Credentials) error {
+	keys := make([]string, 0, len(credentials))
+	for k := range credentials {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		switch v := credentials[k].(type) {
+		case string:
+			if v == MaskedValue {
+				return fmt.Errorf("需要填写%s，不能使用掩码", k)
+			}
+		case map[string]interface{}:
+			for key, value := range v {
+				if value == MaskedValue {
+					return fmt.Errorf("需要填写%s.%s，不能使用掩码", k, key)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// changedEndpoint 返回与已保存的值不同的第一个Endpoint字段名，都未修改时返回空字符串
+func (s Schema) changedEndpoint(credentials, stored Credentials) string {
+	for _, field := range s.Fields {
+		if field.Endpoint && endpointValue(credentials[field.Name]) != endpointValue(stored[field.Name]) {
+			return field.Name
+		}
+	}
+	return ""
+}
+
+// endpointValue 比较用的字段值，未设置与空字符串相同
+func endpointValue(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// Message 按路由渲染后待发送的消息
+type Message struct {
+	ChannelID uint64                 // 通道ID
+	Subject   string                 // 渲染后的主题
+	Body      string                 // 渲染后的正文
+	HTMLBody  string                 // 渲染后的HTML正文，仅邮件通道使用
+	Payload   map[string]interface{} // 原始消息内容
+	Variables map[string]interface{} // 变量映射解析出的变量（未转义），用于渲染选项中的模板
+	Options   map[string]interface{} // 路由的通道扩展选项
+}
+
+// TemplateData 返回渲染选项中模板使用的数据：变量映射的结果和完整的原始消息内容 .Payload
+func (m *Message) TemplateData() map[string]interface{} {
+	return render.Data(m.Variables, m.Payload)
+}
+
+// maxResponseBody 读取服务方响应内容的最大字节数
+const maxResponseBody = 64 * 1024
+
+// Result 发送结果
+type Result struct {
+	StatusCode        int    // HTTP状态码或SMTP响应码
+	Response          string // 响应内容
+	ProviderMessageID string // 服务方返回的消息ID（Telegram message_id、Slack ts、SMTP队列ID等）
+}
+
+// Notifier 通道类型实现，每种通道类型在init中通过Register注册
+type Notifier interface {
+	// Schema 返回通道类型及凭证字段描述
+	Schema() Schema
+	// Validate 校验凭证
+	Validate(credentials Credentials) error
+	// Send 发送路由渲染后的消息
+	Send(ctx context.Context, credentials Credentials, msg *Message) (*Result, error)
+	// Test 使用给定凭证发送测试消息
+	Test(ctx context.Context, credentials Credentials, subject, content string) (*Result, error)
+}
+
+// Escaper 可选接口，需要对模板变量做转义的通道实现，使用text引擎时变量先经通道转义再渲染
+type Escaper interface {
+	Escape(value string, credentials Credentials, options map[string]interface{}) string
+}
+
+// TemplateEngine 可选接口，返回渲染路由模板使用的引擎（render.EngineText/EngineHTML），未实现时使用text引擎
+type TemplateEngine interface {
+	TemplateEngine(credentials Credentials, options map[string]interface{}) string
+}
+
+// OptionsValidator 可选接口，校验路由的通道扩展选项
+type OptionsValidator interface {
+	ValidateOptions(options map[string]interface{}) error
+}
+
+// Checker 可选接口，不发送消息地检查凭证是否可用（如Telegram getMe、SMTP登录）
+type Checker interface {
+	Check(ctx context.Context, credentials Credentials) (*Result, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Notifier)
+)
+
+// Register 注册通道类型，重复注册同一类型会panic
+func Register(n Notifier) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	channelType := n.Schema().Type
+	if channelType == "" {
+		panic("notifier: Register with empty channel type")
+	}
+	if _, dup := registry[channelType]; dup {
+		panic(fmt.Sprintf("notifier: Register called twice for channel type %q", channelType))
+	}
+	registry[channelType] = n
+}
+
+// Get 获取通道类型实现
+func Get(channelType string) (Notifier, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	n, ok := registry[channelType]
+	return n, ok
+}
+
+// Schemas 返回所有已注册通道类型的描述
+func Schemas() []Schema {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	schemas := make([]Schema, 0, len(registry))
+	for _, n := range registry {
+		schemas = append(schemas, n.Schema())
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Type < schemas[j].Type
+	})
+	return schemas
+}
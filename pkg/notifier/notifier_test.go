@@ -0,0 +1,216 @@
+package notifier
+
+import (
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var testSchema = Schema{
+	Type: "test",
+	Fields: []Field{
+		{Name: "url", Type: "string", Endpoint: true},
+		{Name: "port", Type: "int", Endpoint: true},
+		{Name: "proxy", Type: "string", Endpoint: true},
+		{Name: "name", Type: "string"},
+		{Name: "token", Type: "string", Secret: true},
+		{Name: "headers", Type: "map", Secret: true},
+	},
+}
+
+func storedCredentials() Credentials {
+	return Credentials{
+		"url":     "https://example.com/hook",
+		"port":    float64(443),
+		"name":    "prod",
+		"token":   "t0ken",
+		"headers": map[string]interface{}{"Authorization": "Bearer abc", "X-Env": "prod"},
+	}
+}
+
+func TestMask(t *testing.T) {
+	stored := storedCredentials()
+	stored["empty"] = ""
+	masked := testSchema.Mask(stored)
+
+	want := Credentials{
+		"url":     "https://example.com/hook",
+		"port":    float64(443),
+		"name":    "prod",
+		"token":   MaskedValue,
+		"headers": map[string]interface{}{"Authorization": MaskedValue, "X-Env": MaskedValue},
+		"empty":   "",
+	}
+	if !reflect.DeepEqual(masked, want) {
+		t.Fatalf("Mask() = %v, want %v", masked, want)
+	}
+	// 不修改原凭证
+	if stored["token"] != "t0ken" || stored["headers"].(map[string]interface{})["X-Env"] != "prod" {
+		t.Fatalf("Mask() modified the stored credentials: %v", stored)
+	}
+}
+
+func TestUnmask(t *testing.T) {
+	tests := []struct {
+		name        string
+		credentials Credentials
+		want        Credentials
+	}{
+		{
+			name:        "掩码沿用已保存的值",
+			credentials: testSchema.Mask(storedCredentials()),
+			want:        storedCredentials(),
+		},
+		{
+			name: "修改非Endpoint字段",
+			credentials: Credentials{
+				"url": "https://example.com/hook", "port": float64(443), "proxy": "", "name": "staging",
+				"token": MaskedValue, "headers": map[string]interface{}{"Authorization": MaskedValue},
+			},
+			want: Credentials{
+				"url": "https://example.com/hook", "port": float64(443), "proxy": "", "name": "staging",
+				"token": "t0ken", "headers": map[string]interface{}{"Authorization": "Bearer abc"},
+			},
+		},
+		{
+			name: "重新填写密钥",
+			credentials: Credentials{
+				"url": "https://example.com/hook", "port": float64(443), "token": "new",
+				"headers": map[string]interface{}{"Authorization": "Bearer new", "X-New": MaskedValue},
+			},
+			want: Credentials{
+				"url": "https://example.com/hook", "port": float64(443), "token": "new",
+				"headers": map[string]interface{}{"Authorization": "Bearer new"},
+			},
+		},
+		{
+			name: "修改Endpoint并重新填写所有密钥",
+			credentials: Credentials{
+				"url": "https://attacker.example/hook", "port": float64(443), "token": "new",
+				"headers": map[string]interface{}{"Authorization": "Bearer new"},
+			},
+			want: Credentials{
+				"url": "https://attacker.example/hook", "port": float64(443), "token": "new",
+				"headers": map[string]interface{}{"Authorization": "Bearer new"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testSchema.Unmask(tt.credentials, storedCredentials())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Unmask() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmaskEndpointChanged(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(Credentials)
+		want   string
+	}{
+		{"修改url", func(c Credentials) { c["url"] = "https://attacker.example/hook" }, "修改url后需要重新填写token"},
+		{"修改端口", func(c Credentials) { c["port"] = float64(8443) }, "修改port后需要重新填写token"},
+		{"设置代理", func(c Credentials) { c["proxy"] = "http://attacker.example:3128" }, "修改proxy后需要重新填写token"},
+		{"只有请求头仍为掩码", func(c Credentials) {
+			c["url"] = "https://attacker.example/hook"
+			c["token"] = "new"
+		}, "修改url后需要重新填写headers.Authorization"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credentials := testSchema.Mask(storedCredentials())
+			delete(credentials["headers"].(map[string]interface{}), "X-Env")
+			tt.modify(credentials)
+			_, err := testSchema.Unmask(credentials, storedCredentials())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Unmask() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaEndpointFields(t *testing.T) {
+	// 内置通道中决定连接地址的字段都需要标记为Endpoint
+	want := map[string][]string{
+		"email":    {"smtpHost", "smtpPort", "security", "caCert", "proxy"},
+		"slack":    {"proxy"},
+		"telegram": {"proxy"},
+		"webhook":  {"url", "proxy"},
+	}
+	for typ, fields := range want {
+		n, ok := Get(typ)
+		if !ok {
+			t.Fatalf("notifier %s not registered", typ)
+		}
+		var endpoints []string
+		for _, field := range n.Schema().Fields {
+			if field.Endpoint {
+				endpoints = append(endpoints, field.Name)
+			}
+		}
+		if !reflect.DeepEqual(endpoints, fields) {
+			t.Errorf("%s endpoint fields = %v, want %v", typ, endpoints, fields)
+		}
+	}
+}
+
+func TestPkgDoesNotImportInternal(t *testing.T) {
+	// pkg/下的包供外部模块复用，不能依赖internal/
+	fset := token.NewFileSet()
+	err := filepath.WalkDir("..", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") {
+			return err
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
+		if err != nil {
+			return err
+		}
+		for _, imp := range f.Imports {
+			if importPath, _ := strconv.Unquote(imp.Path.Value); strings.HasPrefix(importPath, "synapse/internal/") {
+				t.Errorf("%s imports %s", path, importPath)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRejectMasked(t *testing.T) {
+	tests := []struct {
+		name        string
+		credentials Credentials
+		want        string
+	}{
+		{"没有掩码", storedCredentials(), ""},
+		{"字符串字段", Credentials{"url": "https://example.com/hook", "token": MaskedValue}, "token"},
+		{"非密钥字段也拒绝", Credentials{"name": MaskedValue}, "name"},
+		{"map中的值", Credentials{"headers": map[string]interface{}{"X-Env": "prod", "Authorization": MaskedValue}}, "headers.Authorization"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RejectMasked(tt.credentials)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("RejectMasked() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("RejectMasked() error = %v, want mention of %q", err, tt.want)
+			}
+		})
+	}
+}
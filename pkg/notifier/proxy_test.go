@@ -0,0 +1,360 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeProxy 基于net.Listener的HTTP CONNECT或SOCKS5代理，记录请求的目标地址和认证信息后转发到目标
+type fakeProxy struct {
+	socks5   bool
+	status   int    // HTTP代理的CONNECT响应状态码，0表示200
+	username string // SOCKS5代理要求的用户名，为空时不认证
+	password string
+	coalesce bool // HTTP代理先读取目标的问候，与CONNECT响应一起发送
+
+	mu      sync.Mutex
+	targets []string
+	auths   []string // HTTP为Proxy-Authorization，SOCKS5为"用户名:密码"
+}
+
+// start 在127.0.0.1的随机端口监听，返回监听地址
+func (p *fakeProxy) start(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	t.Cleanup(func() {
+		l.Close()
+		wg.Wait()
+	})
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(5 * time.Second))
+				if p.socks5 {
+					p.serveSOCKS5(conn)
+				} else {
+					p.serveHTTP(conn)
+				}
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func (p *fakeProxy) record(target, auth string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.targets = append(p.targets, target)
+	p.auths = append(p.auths, auth)
+}
+
+func (p *fakeProxy) received() ([]string, []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.targets...), append([]string(nil), p.auths...)
+}
+
+func (p *fakeProxy) serveHTTP(conn net.Conn) {
+	br := bufio.NewReader(conn)
+	req, err := http.ReadRequest(br)
+	if err != nil || req.Method != http.MethodConnect {
+		return
+	}
+	p.record(req.Host, req.Header.Get("Proxy-Authorization"))
+
+	if p.status != 0 && p.status != http.StatusOK {
+		fmt.Fprintf(conn, "HTTP/1.1 %d %s\r\nContent-Length: 0\r\n\r\n", p.status, http.StatusText(p.status))
+		return
+	}
+	upstream, err := net.DialTimeout("tcp", req.Host, time.Second)
+	if err != nil {
+		fmt.Fprintf(conn, "HTTP/1.1 502 Bad Gateway\r\nContent-Length: 0\r\n\r\n")
+		return
+	}
+	defer upstream.Close()
+
+	response := []byte("HTTP/1.1 200 Connection established\r\n\r\n")
+	if p.coalesce {
+		upstream.SetReadDeadline(time.Now().Add(time.Second))
+		greeting := make([]byte, 512)
+		n, _ := upstream.Read(greeting)
+		upstream.SetReadDeadline(time.Time{})
+		response = append(response, greeting[:n]...)
+	}
+	if _, err := conn.Write(response); err != nil {
+		return
+	}
+	relay(&bufferedConn{Conn: conn, r: br}, upstream)
+}
+
+func (p *fakeProxy) serveSOCKS5(conn net.Conn) {
+	// 方法协商
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil || header[0] != 5 {
+		return
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return
+	}
+	method := byte(0x00)
+	if p.username != "" {
+		method = 0x02
+	}
+	offered := false
+	for _, m := range methods {
+		offered = offered || m == method
+	}
+	if !offered {
+		conn.Write([]byte{5, 0xff})
+		return
+	}
+	conn.Write([]byte{5, method})
+
+	// 用户名密码认证（RFC 1929）
+	auth := ""
+	if method == 0x02 {
+		username, password, ok := readSOCKS5Auth(conn)
+		if !ok {
+			return
+		}
+		auth = username + ":" + password
+		if username != p.username || password != p.password {
+			conn.Write([]byte{1, 1})
+			return
+		}
+		conn.Write([]byte{1, 0})
+	}
+
+	// CONNECT请求
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil || request[1] != 1 {
+		return
+	}
+	var host string
+	switch request[3] {
+	case 1:
+		ip := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return
+		}
+		host = net.IP(ip).String()
+	case 3:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return
+		}
+		name := make([]byte, length[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return
+		}
+		host = string(name)
+	case 4:
+		ip := make([]byte, net.IPv6len)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return
+		}
+		host = net.IP(ip).String()
+	default:
+		return
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return
+	}
+	target := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+	p.record(target, auth)
+
+	upstream, err := net.DialTimeout("tcp", target, time.Second)
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	relay(conn, upstream)
+}
+
+// readSOCKS5Auth 读取用户名密码认证请求
+func readSOCKS5Auth(conn net.Conn) (string, string, bool) {
+	readField := func() (string, bool) {
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return "", false
+		}
+		field := make([]byte, length[0])
+		if _, err := io.ReadFull(conn, field); err != nil {
+			return "", false
+		}
+		return string(field), true
+	}
+	version := make([]byte, 1)
+	if _, err := io.ReadFull(conn, version); err != nil || version[0] != 1 {
+		return "", "", false
+	}
+	username, ok := readField()
+	if !ok {
+		return "", "", false
+	}
+	password, ok := readField()
+	return username, password, ok
+}
+
+// relay 双向转发，任一方向结束后关闭两端
+func relay(client, upstream net.Conn) {
+	done := make(chan struct{})
+	go func() {
+		io.Copy(upstream, client)
+		upstream.Close()
+		close(done)
+	}()
+	io.Copy(client, upstream)
+	client.Close()
+	<-done
+}
+
+// sendEmailVia 经过代理向新的fakeSMTP发送一封邮件，返回SMTP服务器的地址和收到的邮件
+func sendEmailVia(t *testing.T, proxyURL string) (string, []fakeMail, error) {
+	t.Helper()
+	server := &fakeSMTP{}
+	port := server.start(t)
+	cfg := EmailConfig{
+		Host: "127.0.0.1", Port: port, Security: "none", Proxy: proxyURL,
+		From: "noreply@example.com", To: "ops@example.com",
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := SendEmail(ctx, cfg, &Email{Subject: "告警", Text: "经过代理"})
+	_, mails := server.received()
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), mails, err
+}
+
+func TestSendEmailViaHTTPConnectProxy(t *testing.T) {
+	tests := []struct {
+		name     string
+		userinfo string
+		coalesce bool
+		wantAuth string
+	}{
+		{"认证", "user:p%40ss@", false, "Basic " + base64.StdEncoding.EncodeToString([]byte("user:p@ss"))},
+		{"不认证", "", false, ""},
+		{"问候与CONNECT响应一起到达", "", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fakeProxy{coalesce: tt.coalesce}
+			addr := p.start(t)
+
+			target, mails, err := sendEmailVia(t, "http://"+tt.userinfo+addr)
+			if err != nil {
+				t.Fatalf("SendEmail() error: %v", err)
+			}
+			if len(mails) != 1 {
+				t.Fatalf("received %d mails, want 1", len(mails))
+			}
+			targets, auths := p.received()
+			if len(targets) != 1 || targets[0] != target {
+				t.Fatalf("CONNECT targets = %v, want [%s]", targets, target)
+			}
+			if auths[0] != tt.wantAuth {
+				t.Fatalf("Proxy-Authorization = %q, want %q", auths[0], tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestDialHTTPConnectRejected(t *testing.T) {
+	tests := []struct {
+		status        int
+		wantRetryable bool
+	}{
+		{http.StatusProxyAuthRequired, false},
+		{http.StatusForbidden, false},
+		{http.StatusBadGateway, true},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			p := &fakeProxy{status: tt.status}
+			dial, err := newProxyDialer("http://user:wrong@" + p.start(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			conn, err := dial(context.Background(), "tcp", "smtp.example.com:25")
+			if err == nil {
+				conn.Close()
+				t.Fatal("dial succeeded through a rejecting proxy")
+			}
+			var nerr *Error
+			if !errors.As(err, &nerr) || nerr.StatusCode != tt.status || nerr.Retryable != tt.wantRetryable {
+				t.Fatalf("dial error = %#v, want status %d retryable %v", err, tt.status, tt.wantRetryable)
+			}
+			if targets, _ := p.received(); len(targets) != 1 || targets[0] != "smtp.example.com:25" {
+				t.Fatalf("CONNECT targets = %v", targets)
+			}
+		})
+	}
+}
+
+func TestSendEmailViaSOCKS5Proxy(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string // 代理要求的用户名，为空时不认证
+		userinfo string
+		wantErr  bool
+	}{
+		{"不认证", "", "", false},
+		{"用户名密码认证", "user", "user:pass@", false},
+		{"密码错误", "user", "user:wrong@", true},
+		{"代理要求认证但未提供", "user", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fakeProxy{socks5: true, username: tt.username, password: "pass"}
+			addr := p.start(t)
+
+			target, mails, err := sendEmailVia(t, "socks5://"+tt.userinfo+addr)
+			if tt.wantErr {
+				if err == nil || len(mails) != 0 {
+					t.Fatalf("SendEmail() error = %v, mails = %d; want rejected by proxy", err, len(mails))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SendEmail() error: %v", err)
+			}
+			if len(mails) != 1 {
+				t.Fatalf("received %d mails, want 1", len(mails))
+			}
+			targets, auths := p.received()
+			if len(targets) != 1 || targets[0] != target {
+				t.Fatalf("SOCKS5 targets = %v, want [%s]", targets, target)
+			}
+			if tt.username != "" && auths[0] != "user:pass" {
+				t.Fatalf("SOCKS5 auth = %q, want user:pass", auths[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"synapse/pkg/render"
+)
+
+// 动态收件人
+//
+// 路由选项 recipients 为收件人字段到模板的映射，使用变量映射的结果和 .Payload 渲染，
+// 渲染结果覆盖通道凭证中的收件人，如：
+//
+//	{"recipients": {"chatId": "{{.Payload.owner.telegram}}"}}
+//
+// 通道凭证中的 allowedRecipients 为允许的收件人列表（逗号或换行分隔，* 匹配任意字符，不区分大小写），
+// 渲染出的每个收件人都必须匹配其中之一；未配置时不允许路由指定收件人。
+// 渲染结果为空的字段使用通道凭证中的默认值。
+
+const (
+	// recipientsOption 路由选项中收件人模板的键
+	recipientsOption = "recipients"
+	// allowedRecipientsField 通道凭证中允许的收件人列表的字段名
+	allowedRecipientsField = "allowedRecipients"
+)
+
+// allowedRecipientsSchemaField 允许的收件人列表的凭证字段描述
+var allowedRecipientsSchemaField = Field{Name: allowedRecipientsField, Label: "允许的收件人", Type: "string"}
+
+// recipientFields 通道支持的收件人字段，值表示是否允许逗号分隔的多个收件人；为nil时不限制字段名，每个字段只能有一个值
+type recipientFields map[string]bool
+
+// recipientTemplates 读取路由选项中的收件人模板并校验语法
+func recipientTemplates(options map[string]interface{}, fields recipientFields) (map[string]string, error) {
+	raw, ok := options[recipientsOption]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("recipients必须是收件人字段到模板的映射")
+	}
+	templates := make(map[string]string, len(m))
+	for field, v := range m {
+		if _, ok := fields[field]; fields != nil && !ok {
+			return nil, fmt.Errorf("不支持的收件人字段: %s", field)
+		}
+		text, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("recipients.%s必须是字符串", field)
+		}
+		if err := render.New(render.EngineText, nil).Parse(field, text); err != nil {
+			return nil, fmt.Errorf("recipients.%s模板错误: %v", field, err)
+		}
+		templates[field] = text
+	}
+	return templates, nil
+}
+
+// resolveRecipients 渲染路由指定的收件人并检查是否在通道允许的范围内，返回渲染结果非空的字段
+func resolveRecipients(credentials Credentials, msg *Message, fields recipientFields) (map[string]string, error) {
+	templates, err := recipientTemplates(msg.Options, fields)
+	if err != nil || len(templates) == 0 {
+		return nil, err
+	}
+
+	allowed := parseAllowList(credentials[allowedRecipientsField])
+	data := msg.TemplateData()
+	names := make([]string, 0, len(templates))
+	for field := range templates {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	resolved := make(map[string]string, len(templates))
+	for _, field := range names {
+		value, err := render.Text(templates[field], data)
+		if err != nil {
+			return nil, fmt.Errorf("recipients.%s渲染失败: %v", field, err)
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if len(allowed) == 0 {
+			return nil, errors.New("通道未配置允许的收件人，路由不能指定收件人")
+		}
+		recipients := splitRecipients(value)
+		if len(recipients) > 1 && !fields[field] {
+			return nil, fmt.Errorf("recipients.%s只能指定一个收件人", field)
+		}
+		for _, recipient := range recipients {
+			if !allowed.allows(recipient) {
+				return nil, fmt.Errorf("收件人 %s 不在通道允许的范围内", recipient)
+			}
+		}
+		resolved[field] = value
+	}
+	return resolved, nil
+}
+
+// splitRecipients 按逗号、分号和换行拆分收件人列表，双引号内的分隔符不拆分（如 "Doe, John" <john@example.com>）
+func splitRecipients(value string) []string {
+	var recipients []string
+	var current strings.Builder
+	quoted := false
+	flush := func() {
+		if recipient := strings.TrimSpace(current.String()); recipient != "" {
+			recipients = append(recipients, recipient)
+		}
+		current.Reset()
+	}
+	for _, r := range value {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case !quoted && (r == ',' || r == ';' || r == '\n' || r == '\r'):
+			flush()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	flush()
+	return recipients
+}
+
+// allowList 允许的收件人模式
+type allowList []*regexp.Regexp
+
+// parseAllowList 解析凭证中的允许列表，支持逗号或换行分隔的字符串和字符串数组
+func parseAllowList(raw interface{}) allowList {
+	var patterns []string
+	switch v := raw.(type) {
+	case string:
+		patterns = splitRecipients(v)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				patterns = append(patterns, splitRecipients(s)...)
+			}
+		}
+	}
+
+	list := make(allowList, 0, len(patterns))
+	for _, pattern := range patterns {
+		expr := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+		list = append(list, regexp.MustCompile("(?i)^"+expr+"$"))
+	}
+	return list
+}
+
+// allows 判断收件人是否匹配允许列表中的任一模式，"名称 <地址>" 形式的邮件收件人只匹配地址
+func (l allowList) allows(recipient string) bool {
+	address := envelopeAddress(recipient)
+	for _, re := range l {
+		if re.MatchString(address) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package notifier
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAllowList(t *testing.T) {
+	allowed := parseAllowList("*@example.com, oncall-*@ops.example.org\n-100123")
+	tests := []struct {
+		recipient string
+		want      bool
+	}{
+		{"alice@example.com", true},
+		{"ALICE@Example.COM", true},
+		{"oncall-db@ops.example.org", true},
+		{"-100123", true},
+		{"alice@example.com.evil.com", false},
+		{"alice@sub.evil.com", false},
+		{"oncall@ops.example.org", false},
+		{"-1001234", false},
+		// 只按实际投递的地址判断，名称和注释不参与匹配
+		{"Alice <alice@example.com>", true},
+		{`"Evil, Inc" <alice@example.com>`, true},
+		{"alice@example.com <evil@attacker.com>", false},
+		{"Evil <evil@attacker.com>", false},
+		{"evil@attacker.com (<alice@example.com>)", false},
+	}
+	for _, tt := range tests {
+		if got := allowed.allows(tt.recipient); got != tt.want {
+			t.Errorf("allows(%q) = %v, want %v", tt.recipient, got, tt.want)
+		}
+	}
+
+	// 字符串数组形式，元素中也可以用逗号分隔
+	list := parseAllowList([]interface{}{"a@x.com", "b@x.com, c@x.com", 42})
+	for _, recipient := range []string{"a@x.com", "b@x.com", "c@x.com"} {
+		if !list.allows(recipient) {
+			t.Errorf("allows(%q) = false", recipient)
+		}
+	}
+	if list.allows("d@x.com") {
+		t.Error("allows(d@x.com) = true")
+	}
+}
+
+func TestResolveRecipients(t *testing.T) {
+	credentials := Credentials{allowedRecipientsField: "*@example.com"}
+	message := func(recipients map[string]interface{}) *Message {
+		return &Message{
+			Payload: map[string]interface{}{"owner": "alice@example.com", "team": "alice@example.com, Bob <bob@example.com>", "evil": "Evil <a@example.com>, evil@attacker.com"},
+			Options: map[string]interface{}{recipientsOption: recipients},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		recipients map[string]interface{}
+		want       map[string]string
+		wantErr    string
+	}{
+		{"单个收件人", map[string]interface{}{"to": "{{.Payload.owner}}"}, map[string]string{"to": "alice@example.com"}, ""},
+		{"逗号分隔的多个收件人", map[string]interface{}{"cc": "{{.Payload.team}}"}, map[string]string{"cc": "alice@example.com, Bob <bob@example.com>"}, ""},
+		{"渲染为空时使用默认值", map[string]interface{}{"bcc": "{{.Payload.missing}}"}, map[string]string{}, ""},
+		{"其中一个不在允许范围", map[string]interface{}{"to": "{{.Payload.evil}}"}, nil, "evil@attacker.com"},
+		{"不支持的字段", map[string]interface{}{"replyTo": "{{.Payload.owner}}"}, nil, "不支持的收件人字段"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveRecipients(credentials, message(tt.recipients), emailRecipientFields)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveRecipients() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("resolveRecipients() = %v, want %v", got, tt.want)
+			}
+			for field, value := range tt.want {
+				if got[field] != value {
+					t.Fatalf("resolveRecipients() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	// 只允许单个收件人的字段不能用逗号指定多个
+	_, err := resolveRecipients(Credentials{allowedRecipientsField: "*"}, &Message{
+		Payload: map[string]interface{}{"chats": "-1001, -1002"},
+		Options: map[string]interface{}{recipientsOption: map[string]interface{}{"chatId": "{{.Payload.chats}}"}},
+	}, telegramRecipientFields)
+	if err == nil || !strings.Contains(err.Error(), "只能指定一个收件人") {
+		t.Fatalf("resolveRecipients() error = %v, want single recipient error", err)
+	}
+
+	// 通道未配置允许列表时不能指定收件人
+	if _, err := resolveRecipients(Credentials{}, message(map[string]interface{}{"to": "{{.Payload.owner}}"}), emailRecipientFields); err == nil {
+		t.Fatal("resolveRecipients() without allow list succeeded")
+	}
+}
+
+func TestExpandPathParams(t *testing.T) {
+	tests := []struct {
+		params map[string]string
+		want   string
+	}{
+		{map[string]string{"user": "alice"}, "https://api.example.com/users/alice/notify"},
+		{map[string]string{"user": "../admin"}, "https://api.example.com/users/..%2Fadmin/notify"},
+		{map[string]string{"user": "a/b"}, "https://api.example.com/users/a%2Fb/notify"},
+		{map[string]string{"user": ".."}, "https://api.example.com/users/%2E%2E/notify"},
+		{map[string]string{"user": "."}, "https://api.example.com/users/%2E/notify"},
+		{map[string]string{"user": "a?x=1#f"}, "https://api.example.com/users/a%3Fx=1%23f/notify"},
+		{map[string]string{"user": "张三"}, "https://api.example.com/users/%E5%BC%A0%E4%B8%89/notify"},
+	}
+	for _, tt := range tests {
+		got, err := expandPathParams("https://api.example.com/users/{user}/notify", tt.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("expandPathParams(%q) = %q, want %q", tt.params["user"], got, tt.want)
+		}
+	}
+
+	if _, err := expandPathParams("https://api.example.com/{org}/{user}", map[string]string{"user": "a"}); err == nil || !strings.Contains(err.Error(), "org") {
+		t.Fatalf("expandPathParams() error = %v, want missing org", err)
+	}
+}
@@ -0,0 +1,360 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+	slackAuthTestURL    = "https://slack.com/api/auth.test"
+)
+
+func init() {
+	Register(&slackNotifier{})
+}
+
+// ThreadStore 线程键与Slack线程根消息ts的映射存储
+type ThreadStore interface {
+	GetThread(channelID uint64, slackChannel, threadKey string) (string, bool)
+	SaveThread(channelID uint64, slackChannel, threadKey, ts string) error
+}
+
+var slackThreads ThreadStore
+
+// SetThreadStore 设置Slack线程存储，未设置时不支持线程回复
+func SetThreadStore(store ThreadStore) {
+	slackThreads = store
+}
+
+// slackCredentials Slack通道凭证
+type slackCredentials struct {
+	WebhookURL string `json:"webhookUrl"`
+	BotToken   string `json:"botToken"`
+	Channel    string `json:"channel"`
+	Proxy      string `json:"proxy"`
+}
+
+type slackNotifier struct{}
+
+func (n *slackNotifier) Schema() Schema {
+	return Schema{
+		Type:  "slack",
+		Label: "Slack",
+		Fields: []Field{
+			{Name: "webhookUrl", Label: "Incoming Webhook URL", Type: "string", Secret: true},
+			{Name: "botToken", Label: "Bot Token", Type: "string", Secret: true},
+			{Name: "channel", Label: "Channel", Type: "string"},
+			{Name: "proxy", Label: "代理", Type: "string", Endpoint: true},
+			allowedRecipientsSchemaField,
+		},
+	}
+}
+
+func (n *slackNotifier) Validate(credentials Credentials) error {
+	var config slackCredentials
+	if err := credentials.Decode(&config); err != nil {
+		return errors.New("Slack配置格式错误")
+	}
+	if config.WebhookURL == "" && config.BotToken == "" {
+		return errors.New("Slack配置不完整，需要Webhook URL或Bot Token")
+	}
+	if config.WebhookURL != "" {
+		u, err := url.Parse(config.WebhookURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return errors.New("Slack Webhook URL格式错误")
+		}
+	}
+	if config.BotToken != "" && config.Channel == "" {
+		return errors.New("使用Bot Token时必须指定Channel")
+	}
+	return validateProxy(config.Proxy)
+}
+
+// slackRecipientFields 路由可以指定的收件人字段
+var slackRecipientFields = recipientFields{"channel": false}
+
+// ValidateOptions 校验路由选项：format 为 mrkdwn 或 blocks，threadKey 为gjson路径，recipients 只能指定 channel
+func (n *slackNotifier) ValidateOptions(options map[string]interface{}) error {
+	if _, err := recipientTemplates(options, slackRecipientFields); err != nil {
+		return err
+	}
+	if format, ok := options["format"]; ok {
+		if format != "mrkdwn" && format != "blocks" && format != "" {
+			return errors.New("Slack消息格式只支持mrkdwn或blocks")
+		}
+	}
+	if threadKey, ok := options["threadKey"]; ok {
+		if _, ok := threadKey.(string); !ok {
+			return errors.New("Slack线程键必须是gjson路径字符串")
+		}
+	}
+	return nil
+}
+
+// Escape 转义模板变量，Block Kit模板中的变量应放在JSON字符串内
+func (n *slackNotifier) Escape(value string, credentials Credentials, options map[string]interface{}) string {
+	value = EscapeSlackText(value)
+	if format, _ := options["format"].(string); format == "blocks" {
+		quoted, _ := json.Marshal(value)
+		value = string(quoted[1 : len(quoted)-1])
+	}
+	return value
+}
+
+// Send 发送Slack消息
+// 路由选项 format 为 "blocks" 时正文按Block Kit JSON解析；
+// threadKey 为gjson路径，相同取值的消息回复到同一线程（仅Bot Token方式）；
+// recipients.channel 可以覆盖通道的Channel（仅Bot Token方式）
+func (n *slackNotifier) Send(ctx context.Context, credentials Credentials, msg *Message) (*Result, error) {
+	var config slackCredentials
+	if err := credentials.Decode(&config); err != nil {
+		return nil, Permanent(err)
+	}
+	recipients, err := resolveRecipients(credentials, msg, slackRecipientFields)
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	if channel, ok := recipients["channel"]; ok {
+		if config.BotToken == "" {
+			return nil, Permanent(errors.New("Incoming Webhook不支持指定频道，请使用Bot Token"))
+		}
+		config.Channel = channel
+	}
+
+	slackMessage := SlackMessage{Text: msg.Body}
+	if format, _ := msg.Options["format"].(string); format == "blocks" {
+		if slackMessage, err = ParseSlackBlocks(msg.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	// 查找线程，Incoming Webhook无法获取消息ts，不支持线程回复
+	threadKey := ""
+	if path, _ := msg.Options["threadKey"].(string); path != "" && config.BotToken != "" && slackThreads != nil {
+		payload, _ := json.Marshal(msg.Payload)
+		threadKey = slackThreadKey(gjson.GetBytes(payload, path).String())
+	}
+	if threadKey != "" {
+		if ts, ok := slackThreads.GetThread(msg.ChannelID, config.Channel, threadKey); ok {
+			slackMessage.ThreadTS = ts
+		}
+	}
+
+	ts, err := SendSlack(ctx, SlackConfig{
+		WebhookURL: config.WebhookURL,
+		BotToken:   config.BotToken,
+		Channel:    config.Channel,
+		Proxy:      config.Proxy,
+	}, slackMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	// 首条消息作为线程根消息
+	if threadKey != "" && slackMessage.ThreadTS == "" && ts != "" {
+		slackThreads.SaveThread(msg.ChannelID, config.Channel, threadKey, ts)
+	}
+	return &Result{StatusCode: http.StatusOK, ProviderMessageID: ts}, nil
+}
+
+func (n *slackNotifier) Test(ctx context.Context, credentials Credentials, subject, content string) (*Result, error) {
+	return n.Send(ctx, credentials, &Message{Subject: subject, Body: content})
+}
+
+// Check 检查凭证，Bot Token方式调用auth.test，Incoming Webhook方式只检查地址是否可达
+func (n *slackNotifier) Check(ctx context.Context, credentials Credentials) (*Result, error) {
+	var config slackCredentials
+	if err := credentials.Decode(&config); err != nil {
+		return nil, Permanent(err)
+	}
+	client, err := newHTTPClient(config.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	if config.BotToken == "" {
+		return checkURL(ctx, client, config.WebhookURL, nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", slackAuthTestURL, nil)
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+config.BotToken)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, stripURL(err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		Team  string `json:"team"`
+		User  string `json:"user"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, maxResponseBody)).Decode(&result)
+	if resp.StatusCode != http.StatusOK || !result.OK {
+		return &Result{StatusCode: resp.StatusCode, Response: result.Error},
+			StatusError(resp.StatusCode, errors.New("Slack API 返回错误: "+resp.Status+" "+result.Error))
+	}
+	return &Result{StatusCode: resp.StatusCode, Response: result.Team + "/" + result.User}, nil
+}
+
+// slackThreadKey 规范化线程键，超长的键使用哈希值
+func slackThreadKey(value string) string {
+	if len(value) <= 255 {
+		return value
+	}
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+type SlackConfig struct {
+	WebhookURL string // Incoming Webhook地址，与BotToken二选一
+	BotToken   string // Bot Token，使用chat.postMessage发送
+	Channel    string // Bot Token方式必填
+	Proxy      string // 可选
+}
+
+// SlackMessage Slack消息内容
+type SlackMessage struct {
+	Text     string          // mrkdwn文本，使用Block Kit时作为通知回退文本
+	Blocks   json.RawMessage // 可选，Block Kit数组
+	ThreadTS string          // 可选，回复到指定线程（仅Bot Token方式支持）
+}
+
+// slackAPIResponse chat.postMessage响应
+type slackAPIResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+	TS    string `json:"ts"`
+}
+
+// SendSlack 发送Slack消息
+// 使用Bot Token方式时返回消息的ts，可用于后续的线程回复
+func SendSlack(ctx context.Context, cfg SlackConfig, msg SlackMessage) (string, error) {
+	if cfg.WebhookURL == "" && (cfg.BotToken == "" || cfg.Channel == "") {
+		return "", Permanent(errors.New("Slack Webhook URL 或 Bot Token 和 Channel 不能为空"))
+	}
+	if msg.Text == "" && len(msg.Blocks) == 0 {
+		return "", Permanent(errors.New("Slack 消息内容不能为空"))
+	}
+
+	client, err := newHTTPClient(cfg.Proxy)
+	if err != nil {
+		return "", err
+	}
+
+	body := map[string]interface{}{
+		"text": msg.Text,
+	}
+	if len(msg.Blocks) > 0 {
+		body["blocks"] = msg.Blocks
+	}
+
+	if cfg.BotToken != "" {
+		body["channel"] = cfg.Channel
+		if msg.ThreadTS != "" {
+			body["thread_ts"] = msg.ThreadTS
+		}
+		return postSlackAPI(ctx, client, cfg.BotToken, body)
+	}
+	return "", postSlackWebhook(ctx, client, cfg.WebhookURL, body)
+}
+
+// postSlackWebhook 通过Incoming Webhook发送，成功时响应体为"ok"
+func postSlackWebhook(ctx context.Context, client *http.Client, webhookURL string, body map[string]interface{}) error {
+	jsonBody, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		return StatusError(resp.StatusCode, errors.New("Slack Webhook 响应失败: "+resp.Status+" "+strings.TrimSpace(string(respBody))))
+	}
+	return nil
+}
+
+// postSlackAPI 通过chat.postMessage发送，返回消息ts
+func postSlackAPI(ctx context.Context, client *http.Client, botToken string, body map[string]interface{}) (string, error) {
+	jsonBody, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, "POST", slackPostMessageURL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+botToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", StatusError(resp.StatusCode, errors.New("Slack API 响应失败: "+resp.Status))
+	}
+
+	var result slackAPIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if !result.OK {
+		err := errors.New("Slack API 返回错误: " + result.Error)
+		// 限流和服务端错误可重试，其余（token无效、频道不存在等）为永久错误
+		if result.Error == "ratelimited" || result.Error == "internal_error" || result.Error == "service_unavailable" {
+			return "", err
+		}
+		return "", Permanent(err)
+	}
+	return result.TS, nil
+}
+
+// EscapeSlackText 转义Slack mrkdwn中的控制字符
+func EscapeSlackText(text string) string {
+	replacer := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	return replacer.Replace(text)
+}
+
+// ParseSlackBlocks 解析Block Kit模板渲染结果
+// 支持直接的blocks数组，或包含blocks和text字段的对象
+func ParseSlackBlocks(rendered string) (SlackMessage, error) {
+	rendered = strings.TrimSpace(rendered)
+
+	var blocks []json.RawMessage
+	if err := json.Unmarshal([]byte(rendered), &blocks); err == nil {
+		return SlackMessage{Blocks: json.RawMessage(rendered)}, nil
+	}
+
+	var payload struct {
+		Text   string          `json:"text"`
+		Blocks json.RawMessage `json:"blocks"`
+	}
+	if err := json.Unmarshal([]byte(rendered), &payload); err != nil {
+		return SlackMessage{}, Permanent(errors.New("Block Kit 模板不是合法的JSON: " + err.Error()))
+	}
+	if len(payload.Blocks) == 0 {
+		return SlackMessage{}, Permanent(errors.New("Block Kit 模板缺少blocks字段"))
+	}
+	return SlackMessage{Text: payload.Text, Blocks: payload.Blocks}, nil
+}
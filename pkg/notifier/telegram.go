@@ -2,50 +2,308 @@ package notifier
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
-	"net/url"
-	"synapse/internal/model"
-	"time"
+	"strconv"
+	"strings"
+	"synapse/pkg/render"
 )
 
-func SendTelegramMessage(cfg model.TelegramConfig, message string) error {
+func init() {
+	Register(&telegramNotifier{})
+}
+
+// TelegramConfig Telegram通道凭证，也是 SendTelegram 的发送配置
+type TelegramConfig struct {
+	BotToken  string `json:"botToken"`
+	ChatID    string `json:"chatId"`
+	ParseMode string `json:"parseMode"`
+	Proxy     string `json:"proxy"`
+}
+
+type telegramNotifier struct{}
+
+func (n *telegramNotifier) Schema() Schema {
+	return Schema{
+		Type:  "telegram",
+		Label: "Telegram",
+		Fields: []Field{
+			{Name: "botToken", Label: "Bot Token", Type: "string", Required: true, Secret: true},
+			{Name: "chatId", Label: "Chat ID", Type: "string", Required: true},
+			{Name: "parseMode", Label: "解析模式", Type: "string"},
+			{Name: "proxy", Label: "代理", Type: "string", Endpoint: true},
+			allowedRecipientsSchemaField,
+		},
+	}
+}
+
+func (n *telegramNotifier) Validate(credentials Credentials) error {
+	var config TelegramConfig
+	if err := credentials.Decode(&config); err != nil {
+		return errors.New("Telegram配置格式错误")
+	}
+	if config.BotToken == "" || config.ChatID == "" {
+		return errors.New("Telegram配置不完整")
+	}
+	return validateProxy(config.Proxy)
+}
+
+// TemplateEngine 解析模式为HTML时使用html引擎，由模板自动转义变量
+func (n *telegramNotifier) TemplateEngine(credentials Credentials, options map[string]interface{}) string {
+	parseMode, _ := credentials["parseMode"].(string)
+	if strings.EqualFold(parseMode, "HTML") {
+		return render.EngineHTML
+	}
+	return render.EngineText
+}
+
+// telegramMarkdownReplacer 旧版Markdown解析模式需要转义的字符
+var telegramMarkdownReplacer = strings.NewReplacer("_", `\_`, "*", `\*`, "`", "\\`", "[", `\[`)
+
+// Escape 按解析模式转义模板变量，HTML模式由html引擎转义
+func (n *telegramNotifier) Escape(value string, credentials Credentials, options map[string]interface{}) string {
+	parseMode, _ := credentials["parseMode"].(string)
+	switch parseMode {
+	case "MarkdownV2":
+		return render.EscapeMarkdownV2(value)
+	case "Markdown":
+		return telegramMarkdownReplacer.Replace(value)
+	}
+	return value
+}
+
+// telegramRecipientFields 路由可以指定的收件人字段
+var telegramRecipientFields = recipientFields{"chatId": false}
+
+// ValidateOptions 校验路由选项：recipients 只能指定 chatId，photo、document、buttons、threadId、disableNotification 为模板
+func (n *telegramNotifier) ValidateOptions(options map[string]interface{}) error {
+	if _, err := recipientTemplates(options, telegramRecipientFields); err != nil {
+		return err
+	}
+	_, err := parseTelegramOptions(options)
+	return err
+}
+
+// Send 发送Telegram消息
+// 路由选项 recipients.chatId 可以覆盖通道的Chat ID，其余选项见 telegramOptions
+func (n *telegramNotifier) Send(ctx context.Context, credentials Credentials, msg *Message) (*Result, error) {
+	var config TelegramConfig
+	if err := credentials.Decode(&config); err != nil {
+		return nil, Permanent(err)
+	}
+	recipients, err := resolveRecipients(credentials, msg, telegramRecipientFields)
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	if chatID, ok := recipients["chatId"]; ok {
+		config.ChatID = chatID
+	}
+	message, err := resolveTelegramMessage(msg)
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	return SendTelegram(ctx, config, message)
+}
+
+func (n *telegramNotifier) Test(ctx context.Context, credentials Credentials, subject, content string) (*Result, error) {
+	return n.Send(ctx, credentials, &Message{Subject: subject, Body: content})
+}
+
+// Check 调用getMe检查Bot Token是否有效
+func (n *telegramNotifier) Check(ctx context.Context, credentials Credentials) (*Result, error) {
+	var config TelegramConfig
+	if err := credentials.Decode(&config); err != nil {
+		return nil, Permanent(err)
+	}
+	if config.BotToken == "" {
+		return nil, Permanent(errors.New("Token 不能为空"))
+	}
+
+	client, err := newHTTPClient(config.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", telegramAPIURL+"/bot"+config.BotToken+"/getMe", nil)
+	if err != nil {
+		return nil, Permanent(errors.New("Token 格式错误"))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, stripURL(err)
+	}
+	defer resp.Body.Close()
+
+	var apiResp struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+		Result      struct {
+			Username string `json:"username"`
+		} `json:"result"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, maxResponseBody)).Decode(&apiResp)
+	if resp.StatusCode != http.StatusOK || !apiResp.OK {
+		return &Result{StatusCode: resp.StatusCode, Response: apiResp.Description},
+			StatusError(resp.StatusCode, errors.New("Telegram API 响应失败: "+resp.Status+" "+apiResp.Description))
+	}
+	return &Result{StatusCode: resp.StatusCode, Response: "@" + apiResp.Result.Username}, nil
+}
+
+// telegramAPIURL Bot API地址
+var telegramAPIURL = "https://api.telegram.org"
+
+// telegramResponse Bot API响应
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+	Result      struct {
+		MessageID int64 `json:"message_id"`
+	} `json:"result"`
+}
+
+// SendTelegramMessage 发送Telegram文本消息，成功时结果中包含message_id
+func SendTelegramMessage(ctx context.Context, cfg TelegramConfig, message string) (*Result, error) {
+	return SendTelegram(ctx, cfg, &TelegramMessage{Text: message})
+}
+
+// SendTelegram 发送Telegram消息
+// 有图片或文件时先调用 sendPhoto/sendDocument，文本不超过说明的长度限制时作为说明发送，否则另外发送；
+// 超过4096个字符的文本在发送前拆分为多条消息，跨越拆分点的格式标记会闭合后重新打开，按钮附加在最后一条消息上。
+// 结果中的message_id以逗号分隔，中途失败时已发送的消息会在重试时重复发送
+func SendTelegram(ctx context.Context, cfg TelegramConfig, message *TelegramMessage) (*Result, error) {
 	if cfg.BotToken == "" || cfg.ChatID == "" {
-		return errors.New("Token 和 ChatID 不能为空")
+		return nil, Permanent(errors.New("Token 和 ChatID 不能为空"))
+	}
+	if message.Photo != nil && message.Document != nil {
+		return nil, Permanent(errors.New("图片和文件只能发送一个"))
+	}
+	client, err := newHTTPClient(cfg.Proxy)
+	if err != nil {
+		return nil, err
 	}
 
-	apiURL := "https://api.telegram.org/bot" + cfg.BotToken + "/sendMessage"
-	body := map[string]interface{}{
-		"chat_id":    cfg.ChatID,
-		"text":       message,
-		"parse_mode": cfg.ParseMode,
+	type call struct {
+		method string
+		params map[string]interface{}
+		file   *TelegramFile
+		field  string // 文件参数名
+	}
+	var calls []call
+	texts, err := splitTelegramText(message.Text, cfg.ParseMode, telegramMaxText)
+	if err != nil {
+		return nil, Permanent(err)
 	}
-	jsonBody, _ := json.Marshal(body)
+	if file, method, field := telegramMedia(message); file != nil {
+		params := map[string]interface{}{}
+		if message.Text != "" && telegramLen(message.Text) <= telegramMaxCaption {
+			params["caption"] = message.Text
+			texts = nil
+		}
+		calls = append(calls, call{method: method, params: params, file: file, field: field})
+		if len(texts) == 1 && texts[0] == "" {
+			texts = nil
+		}
+	}
+	for _, text := range texts {
+		calls = append(calls, call{method: "sendMessage", params: map[string]interface{}{"text": text}})
+	}
+
+	var ids []string
+	var result *Result
+	for i, c := range calls {
+		c.params["chat_id"] = cfg.ChatID
+		if cfg.ParseMode != "" && (c.method == "sendMessage" || c.params["caption"] != nil) {
+			c.params["parse_mode"] = cfg.ParseMode
+		}
+		if message.ThreadID != 0 {
+			c.params["message_thread_id"] = message.ThreadID
+		}
+		if message.DisableNotification {
+			c.params["disable_notification"] = true
+		}
+		if i == len(calls)-1 && len(message.Buttons) > 0 {
+			c.params["reply_markup"] = map[string]interface{}{"inline_keyboard": message.Buttons}
+		}
+
+		var messageID int64
+		result, messageID, err = telegramCall(ctx, client, cfg.BotToken, c.method, c.params, c.field, c.file)
+		if err != nil {
+			return result, err
+		}
+		if messageID != 0 {
+			ids = append(ids, strconv.FormatInt(messageID, 10))
+		}
+	}
+	result.ProviderMessageID = strings.Join(ids, ",")
+	return result, nil
+}
+
+// telegramMedia 返回消息中的图片或文件及对应的方法和参数名
+func telegramMedia(message *TelegramMessage) (*TelegramFile, string, string) {
+	switch {
+	case message.Photo != nil:
+		return message.Photo, "sendPhoto", "photo"
+	case message.Document != nil:
+		return message.Document, "sendDocument", "document"
+	}
+	return nil, "", ""
+}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	if cfg.Proxy != "" {
-		proxyURL, err := url.Parse(cfg.Proxy)
+// telegramCall 调用Bot API，上传文件时使用 multipart/form-data，否则使用JSON；失败时错误中包含Telegram返回的description
+func telegramCall(ctx context.Context, client *http.Client, token, method string, params map[string]interface{}, field string, file *TelegramFile) (*Result, int64, error) {
+	var body bytes.Buffer
+	contentType := "application/json"
+	switch {
+	case file != nil && file.Content == nil:
+		params[field] = file.URL
+		json.NewEncoder(&body).Encode(params)
+	case file != nil:
+		w := multipart.NewWriter(&body)
+		for key, value := range params {
+			text, ok := value.(string)
+			if !ok {
+				b, _ := json.Marshal(value)
+				text = string(b)
+			}
+			w.WriteField(key, text)
+		}
+		part, err := w.CreateFormFile(field, file.Filename)
 		if err != nil {
-			return errors.New("代理地址格式错误")
+			return nil, 0, Permanent(err)
 		}
-		client.Transport = &http.Transport{Proxy: http.ProxyURL(proxyURL)}
+		part.Write(file.Content)
+		w.Close()
+		contentType = w.FormDataContentType()
+	default:
+		json.NewEncoder(&body).Encode(params)
 	}
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", telegramAPIURL+"/bot"+token+"/"+method, &body)
 	if err != nil {
-		return err
+		return nil, 0, Permanent(err)
 	}
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", contentType)
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, 0, stripURL(err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return errors.New("Telegram API 响应失败: " + resp.Status)
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	result := &Result{StatusCode: resp.StatusCode, Response: string(respBody)}
+	var apiResp telegramResponse
+	json.Unmarshal(respBody, &apiResp)
+	if resp.StatusCode != http.StatusOK || !apiResp.OK {
+		detail := resp.Status
+		if apiResp.Description != "" {
+			detail = fmt.Sprintf("%d %s", resp.StatusCode, apiResp.Description)
+		}
+		return result, 0, StatusError(resp.StatusCode, errors.New("Telegram API 响应失败: "+detail))
 	}
-	return nil
+	return result, apiResp.Result.MessageID, nil
 }
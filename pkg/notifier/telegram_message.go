@@ -0,0 +1,500 @@
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"synapse/pkg/render"
+)
+
+// Telegram路由选项
+//
+// 除 recipients 外，路由选项还可以为Telegram消息添加图片或文件、按钮、话题和静默发送，各字符串均为模板：
+//
+//	{
+//	  "photo": {"url": "{{.Payload.chart_url}}"},
+//	  "document": {"data": "{{.Payload.report.base64}}", "filename": "report.pdf"},
+//	  "buttons": [[{"text": "打开面板", "url": "{{.Payload.dashboard_url}}"}]],
+//	  "threadId": "{{.Payload.topic_id}}",
+//	  "disableNotification": "{{if ne .Payload.severity \"critical\"}}true{{end}}"
+//	}
+//
+// photo 和 document 只能设置一个，url 由Telegram服务器下载，data 为base64内容，渲染结果都为空时只发送文本；
+// buttons 为按钮行的数组，只有一行时可以省略外层数组，文本或链接渲染为空的按钮会被忽略。
+
+const (
+	// telegramMaxText sendMessage 文本的最大长度（UTF-16编码单元）
+	telegramMaxText = 4096
+	// telegramMaxCaption 图片和文件说明的最大长度
+	telegramMaxCaption = 1024
+	// telegramMaxParts 长文本最多拆分的消息数，避免触发群组的频率限制，超出时投递失败
+	telegramMaxParts = 10
+)
+
+// telegramMediaFields photo 和 document 选项支持的字段
+var telegramMediaFields = []string{"filename", "url", "data"}
+
+// TelegramMessage 待发送的Telegram消息
+type TelegramMessage struct {
+	Text                string
+	Photo               *TelegramFile      // 可选，与Document只能设置一个
+	Document            *TelegramFile      // 可选
+	Buttons             [][]TelegramButton // 可选，内联键盘，附加在最后一条消息上
+	ThreadID            int64              // 可选，论坛话题ID
+	DisableNotification bool               // 静默发送
+}
+
+// TelegramFile 图片或文件，URL和Content二选一
+type TelegramFile struct {
+	URL      string // 由Telegram服务器下载
+	Filename string
+	Content  []byte // 上传的内容
+}
+
+// TelegramButton 内联键盘中的链接按钮
+type TelegramButton struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// telegramOptions 解析后的路由选项模板
+type telegramOptions struct {
+	Photo               *attachmentSpec
+	Document            *attachmentSpec
+	Buttons             [][]TelegramButton // Text和URL为模板
+	ThreadID            string
+	DisableNotification string
+}
+
+// parseTelegramOptions 读取路由选项并校验模板语法
+func parseTelegramOptions(options map[string]interface{}) (*telegramOptions, error) {
+	opts := &telegramOptions{}
+	for _, media := range []struct {
+		key string
+		dst **attachmentSpec
+	}{{"photo", &opts.Photo}, {"document", &opts.Document}} {
+		raw, ok := options[media.key]
+		if !ok || raw == nil {
+			continue
+		}
+		spec, err := parseAttachmentSpec(media.key, raw, telegramMediaFields)
+		if err != nil {
+			return nil, err
+		}
+		*media.dst = &spec
+	}
+	if opts.Photo != nil && opts.Document != nil {
+		return nil, errors.New("photo和document只能设置一个")
+	}
+
+	buttons, err := parseTelegramButtons(options["buttons"])
+	if err != nil {
+		return nil, err
+	}
+	opts.Buttons = buttons
+
+	for _, field := range []struct {
+		key string
+		dst *string
+	}{{"threadId", &opts.ThreadID}, {"disableNotification", &opts.DisableNotification}} {
+		switch v := options[field.key].(type) {
+		case nil:
+		case float64:
+			*field.dst = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			*field.dst = strconv.FormatBool(v)
+		case string:
+			if err := render.New(render.EngineText, nil).Parse(field.key, v); err != nil {
+				return nil, fmt.Errorf("%s模板错误: %v", field.key, err)
+			}
+			*field.dst = v
+		default:
+			return nil, fmt.Errorf("%s必须是字符串、数字或布尔值", field.key)
+		}
+	}
+	return opts, nil
+}
+
+// parseTelegramButtons 解析按钮行，只有一行时可以省略外层数组
+func parseTelegramButtons(raw interface{}) ([][]TelegramButton, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	rows, ok := raw.([]interface{})
+	if !ok {
+		return nil, errors.New("buttons必须是数组")
+	}
+	if len(rows) > 0 {
+		if _, ok := rows[0].(map[string]interface{}); ok {
+			rows = []interface{}{rows}
+		}
+	}
+
+	buttons := make([][]TelegramButton, 0, len(rows))
+	for i, rawRow := range rows {
+		items, ok := rawRow.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("buttons[%d]必须是按钮数组", i)
+		}
+		row := make([]TelegramButton, 0, len(items))
+		for j, item := range items {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("buttons[%d][%d]必须是对象", i, j)
+			}
+			var button TelegramButton
+			for key, v := range m {
+				text, ok := v.(string)
+				if !ok {
+					return nil, fmt.Errorf("buttons[%d][%d].%s必须是字符串", i, j, key)
+				}
+				switch key {
+				case "text":
+					button.Text = text
+				case "url":
+					button.URL = text
+				default:
+					return nil, fmt.Errorf("buttons[%d][%d]不支持的字段: %s", i, j, key)
+				}
+				if err := render.New(render.EngineText, nil).Parse(key, text); err != nil {
+					return nil, fmt.Errorf("buttons[%d][%d].%s模板错误: %v", i, j, key, err)
+				}
+			}
+			if button.Text == "" || button.URL == "" {
+				return nil, fmt.Errorf("buttons[%d][%d]需要text和url", i, j)
+			}
+			row = append(row, button)
+		}
+		buttons = append(buttons, row)
+	}
+	return buttons, nil
+}
+
+// resolveTelegramMessage 使用模板数据渲染路由选项，生成待发送的消息
+func resolveTelegramMessage(msg *Message) (*TelegramMessage, error) {
+	opts, err := parseTelegramOptions(msg.Options)
+	if err != nil {
+		return nil, err
+	}
+	data := msg.TemplateData()
+	message := &TelegramMessage{Text: msg.Body}
+
+	for _, media := range []struct {
+		key  string
+		spec *attachmentSpec
+		dst  **TelegramFile
+	}{{"photo", opts.Photo, &message.Photo}, {"document", opts.Document, &message.Document}} {
+		if media.spec == nil {
+			continue
+		}
+		rendered, err := media.spec.render(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s渲染失败: %v", media.key, err)
+		}
+		file := &TelegramFile{URL: rendered.URL, Filename: rendered.Filename}
+		switch {
+		case rendered.URL != "":
+		case rendered.Data != "":
+			if file.Content, _, err = decodeAttachmentData(rendered.Data); err != nil {
+				return nil, fmt.Errorf("%s: %v", media.key, err)
+			}
+			if file.Filename == "" {
+				file.Filename = media.key
+			}
+		default:
+			// 消息中没有对应内容时只发送文本
+			continue
+		}
+		*media.dst = file
+	}
+
+	for _, row := range opts.Buttons {
+		var renderedRow []TelegramButton
+		for _, button := range row {
+			text, err := render.Text(button.Text, data)
+			if err != nil {
+				return nil, fmt.Errorf("buttons渲染失败: %v", err)
+			}
+			link, err := render.Text(button.URL, data)
+			if err != nil {
+				return nil, fmt.Errorf("buttons渲染失败: %v", err)
+			}
+			if text, link = strings.TrimSpace(text), strings.TrimSpace(link); text != "" && link != "" {
+				renderedRow = append(renderedRow, TelegramButton{Text: text, URL: link})
+			}
+		}
+		if len(renderedRow) > 0 {
+			message.Buttons = append(message.Buttons, renderedRow)
+		}
+	}
+
+	if threadID, err := render.Text(opts.ThreadID, data); err != nil {
+		return nil, fmt.Errorf("threadId渲染失败: %v", err)
+	} else if threadID = strings.TrimSpace(threadID); threadID != "" {
+		if message.ThreadID, err = strconv.ParseInt(threadID, 10, 64); err != nil {
+			return nil, fmt.Errorf("threadId必须是整数: %s", threadID)
+		}
+	}
+	if silent, err := render.Text(opts.DisableNotification, data); err != nil {
+		return nil, fmt.Errorf("disableNotification渲染失败: %v", err)
+	} else if silent = strings.TrimSpace(silent); silent != "" {
+		if message.DisableNotification, err = strconv.ParseBool(silent); err != nil {
+			return nil, fmt.Errorf("disableNotification必须是true或false: %s", silent)
+		}
+	}
+	return message, nil
+}
+
+// telegramEntity 拆分点处未闭合的格式标记，拆分时在前一段末尾闭合，在后一段开头重新打开
+type telegramEntity struct {
+	open  string // 打开标记，如 "<a href=\"...\">"、"```go\n"
+	close string // 闭合标记，如 "</a>"、"```"
+}
+
+// telegramCut 可以拆分的位置
+type telegramCut struct {
+	pos   int              // 字节偏移
+	units int              // 之前文本的UTF-16长度
+	open  []telegramEntity // 该位置未闭合的格式标记，由外到内
+}
+
+// telegramHTMLTags Telegram HTML解析模式支持的标签
+var telegramHTMLTags = map[string]bool{
+	"b": true, "strong": true, "i": true, "em": true, "u": true, "ins": true, "s": true, "strike": true, "del": true,
+	"span": true, "tg-spoiler": true, "a": true, "code": true, "pre": true, "blockquote": true, "tg-emoji": true,
+}
+
+// splitTelegramText 将超过长度限制的文本拆分为多段，优先在换行处拆分，其次在空格处
+// 不在转义、HTML标签和实体、链接内部拆分，跨越拆分点的格式标记在前一段末尾闭合、在后一段开头重新打开；
+// 需要超过 telegramMaxParts 段或格式标记过长无法拆分时返回错误
+func splitTelegramText(text, parseMode string, limit int) ([]string, error) {
+	total := telegramLen(text)
+	if total <= limit {
+		return []string{text}, nil
+	}
+
+	cuts := telegramCuts(text, parseMode)
+	var parts []string
+	start, prefix := 0, ""
+	for {
+		head := telegramLen(prefix)
+		if head+total-cuts[start].units <= limit {
+			return append(parts, prefix+text[cuts[start].pos:]), nil
+		}
+		if len(parts) == telegramMaxParts-1 {
+			return nil, fmt.Errorf("消息过长，超过%d条消息的长度限制", telegramMaxParts)
+		}
+
+		// 依次选择最后一个换行、空格或其他可以拆分的位置
+		best, bestRank := -1, -1
+		for k := start + 1; k < len(cuts); k++ {
+			c := cuts[k]
+			size := head + c.units - cuts[start].units
+			if size > limit {
+				break
+			}
+			if size+telegramLen(telegramClosers(c.open)) > limit || c.pos == len(text) {
+				continue
+			}
+			rank := 0
+			switch text[c.pos] {
+			case '\n':
+				rank = 2
+			case ' ':
+				rank = 1
+			}
+			if rank >= bestRank {
+				best, bestRank = k, rank
+			}
+		}
+		if best < 0 {
+			return nil, errors.New("消息过长，且格式标记内的内容超过单条消息的长度限制，无法拆分")
+		}
+
+		c := cuts[best]
+		parts = append(parts, prefix+text[cuts[start].pos:c.pos]+telegramClosers(c.open))
+		prefix = telegramOpeners(c.open)
+		start = best
+		if bestRank > 0 {
+			// 拆分处的换行或空格不保留
+			start++
+		}
+	}
+}
+
+// telegramCuts 返回文本中可以拆分的位置，最后一项为文本末尾
+func telegramCuts(text, parseMode string) []telegramCut {
+	mode := strings.ToLower(parseMode)
+	var cuts []telegramCut
+	var open []telegramEntity
+	push := func(e telegramEntity) {
+		open = append(open[:len(open):len(open)], e)
+	}
+	pop := func(close string) bool {
+		for i := len(open) - 1; i >= 0; i-- {
+			if open[i].close == close {
+				open = append(append(make([]telegramEntity, 0, len(open)-1), open[:i]...), open[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+	toggle := func(marker string) {
+		if !pop(marker) {
+			push(telegramEntity{open: marker, close: marker})
+		}
+	}
+
+	units := 0
+	for i := 0; i < len(text); {
+		cuts = append(cuts, telegramCut{pos: i, units: units, open: open})
+		_, n := utf8.DecodeRuneInString(text[i:])
+		switch mode {
+		case "html":
+			n = telegramHTMLToken(text[i:], n, push, pop)
+		case "markdownv2", "markdown":
+			inCode := len(open) > 0 && (open[len(open)-1].close == "`" || open[len(open)-1].close == "```")
+			n = telegramMarkdownToken(text[i:], n, mode == "markdownv2", inCode, push, pop, toggle)
+		}
+		units += telegramLen(text[i : i+n])
+		i += n
+	}
+	return append(cuts, telegramCut{pos: len(text), units: units, open: open})
+}
+
+// telegramHTMLToken 返回HTML模式下不能拆开的标记长度，并记录打开和闭合的标签
+func telegramHTMLToken(text string, n int, push func(telegramEntity), pop func(string) bool) int {
+	switch text[0] {
+	case '<':
+		end := strings.IndexByte(text, '>')
+		if end < 0 {
+			return n
+		}
+		tag := text[:end+1]
+		name := strings.TrimPrefix(tag[1:end], "/")
+		if j := strings.IndexAny(name, " \t\n/"); j >= 0 {
+			name = name[:j]
+		}
+		name = strings.ToLower(name)
+		if telegramHTMLTags[name] {
+			if strings.HasPrefix(tag, "</") {
+				pop("</" + name + ">")
+			} else {
+				push(telegramEntity{open: tag, close: "</" + name + ">"})
+			}
+		}
+		return len(tag)
+	case '&':
+		if end := strings.IndexByte(text, ';'); end > 0 && end <= 10 {
+			return end + 1
+		}
+	}
+	return n
+}
+
+// telegramMarkdownToken 返回Markdown模式下不能拆开的标记长度，并记录打开和闭合的格式
+// v2为MarkdownV2，否则为旧版Markdown（只支持粗体、斜体、代码和链接）
+func telegramMarkdownToken(text string, n int, v2, inCode bool, push func(telegramEntity), pop func(string) bool, toggle func(string)) int {
+	switch {
+	case text[0] == '\\' && len(text) > 1:
+		_, size := utf8.DecodeRuneInString(text[1:])
+		return 1 + size
+	case strings.HasPrefix(text, "```"):
+		if inCode {
+			pop("```")
+			return 3
+		}
+		// 代码块的语言和换行与开始标记一起重新打开
+		opener := "```"
+		if end := strings.IndexByte(text, '\n'); end > 3 && end <= 35 && !strings.ContainsAny(text[3:end], " `") {
+			opener = text[:end+1]
+		}
+		push(telegramEntity{open: opener, close: "```"})
+		return len(opener)
+	case text[0] == '`':
+		if inCode {
+			pop("`")
+		} else {
+			push(telegramEntity{open: "`", close: "`"})
+		}
+		return 1
+	case inCode:
+		return n
+	case text[0] == '[', v2 && strings.HasPrefix(text, "!["):
+		if end := telegramLinkEnd(text); end > 0 {
+			return end
+		}
+	case v2 && (strings.HasPrefix(text, "__") || strings.HasPrefix(text, "||")):
+		toggle(text[:2])
+		return 2
+	case text[0] == '*', text[0] == '_', v2 && text[0] == '~':
+		toggle(text[:1])
+		return 1
+	}
+	return n
+}
+
+// telegramLinkEnd 返回 [文本](链接) 的长度，不是完整的链接时返回0
+func telegramLinkEnd(text string) int {
+	closeText := telegramUnescapedIndex(text, ']')
+	if closeText < 0 || !strings.HasPrefix(text[closeText+1:], "(") {
+		return 0
+	}
+	closeURL := telegramUnescapedIndex(text[closeText+1:], ')')
+	if closeURL < 0 {
+		return 0
+	}
+	return closeText + 1 + closeURL + 1
+}
+
+// telegramUnescapedIndex 返回第一个未被反斜杠转义的字符c的位置
+func telegramUnescapedIndex(text string, c byte) int {
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case c:
+			return i
+		}
+	}
+	return -1
+}
+
+// telegramClosers 按由内到外的顺序闭合格式标记
+func telegramClosers(open []telegramEntity) string {
+	var sb strings.Builder
+	for i := len(open) - 1; i >= 0; i-- {
+		sb.WriteString(open[i].close)
+	}
+	return sb.String()
+}
+
+// telegramOpeners 按由外到内的顺序重新打开格式标记
+func telegramOpeners(open []telegramEntity) string {
+	var sb strings.Builder
+	for _, e := range open {
+		sb.WriteString(e.open)
+	}
+	return sb.String()
+}
+
+// telegramLen 返回文本的UTF-16长度，Telegram按UTF-16编码单元计算消息长度
+func telegramLen(text string) int {
+	n := 0
+	for _, r := range text {
+		n += utf16Len(r)
+	}
+	return n
+}
+
+// utf16Len 返回字符的UTF-16编码单元数，辅助平面的字符（如emoji）占两个
+func utf16Len(r rune) int {
+	if utf16.IsSurrogate(r) || r < 0x10000 {
+		return 1
+	}
+	return 2
+}
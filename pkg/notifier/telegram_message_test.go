@@ -0,0 +1,102 @@
+package notifier
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitTelegramTextPlain(t *testing.T) {
+	text := strings.Repeat("a", 6) + "\n" + strings.Repeat("b", 6) + " " + strings.Repeat("c", 3)
+	parts, err := splitTelegramText(text, "", 10)
+	if err != nil {
+		t.Fatalf("splitTelegramText() error: %v", err)
+	}
+	want := []string{"aaaaaa", "bbbbbb ccc"}
+	if strings.Join(parts, "|") != strings.Join(want, "|") {
+		t.Fatalf("parts = %q, want %q", parts, want)
+	}
+
+	// 按UTF-16长度计算，表情符号占2个单位且不会被拆开
+	parts, err = splitTelegramText(strings.Repeat("😀", 5), "", 4)
+	if err != nil {
+		t.Fatalf("splitTelegramText() error: %v", err)
+	}
+	if len(parts) != 3 || parts[0] != "😀😀" || parts[2] != "😀" {
+		t.Fatalf("parts = %q", parts)
+	}
+}
+
+func TestSplitTelegramTextMarkdownV2(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{"转义不拆开", `aaaaaaaaa\.bbb`, 10, []string{`aaaaaaaaa`, `\.bbb`}},
+		{"粗体重新打开", "*aaaa bbbb cccc*", 12, []string{"*aaaa bbbb*", "*cccc*"}},
+		{"嵌套格式", "*_aaaa bbbb_*", 11, []string{"*_aaaa_*", "*_bbbb_*"}},
+		{"链接不拆开", "aaa [link text](https://ex.com) b", 30, []string{"aaa", "[link text](https://ex.com) b"}},
+		{"代码块保留语言", "```go\nline1\nline2\n```", 16, []string{"```go\nline1```", "```go\nline2\n```"}},
+		{"代码内的星号不是格式", "`a*b c*d e`", 8, []string{"`a*b`", "`c*d e`"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := splitTelegramText(tt.text, "MarkdownV2", tt.limit)
+			if err != nil {
+				t.Fatalf("splitTelegramText() error: %v", err)
+			}
+			if strings.Join(parts, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("parts = %q, want %q", parts, tt.want)
+			}
+			for _, part := range parts {
+				if telegramLen(part) > tt.limit {
+					t.Fatalf("part %q exceeds limit %d", part, tt.limit)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitTelegramTextHTML(t *testing.T) {
+	text := `<b>bold <a href="https://example.com">link text</a></b> &amp;&amp;`
+	parts, err := splitTelegramText(text, "HTML", 50)
+	if err != nil {
+		t.Fatalf("splitTelegramText() error: %v", err)
+	}
+	want := []string{
+		`<b>bold <a href="https://example.com">link</a></b>`,
+		`<b><a href="https://example.com">text</a></b>`,
+		`&amp;&amp;`,
+	}
+	if len(parts) != len(want) {
+		t.Fatalf("parts = %q, want %q", parts, want)
+	}
+	for i := range want {
+		if parts[i] != want[i] {
+			t.Fatalf("parts = %q, want %q", parts, want)
+		}
+	}
+
+	// 实体不拆开
+	parts, err = splitTelegramText("aaaa&amp;b", "HTML", 8)
+	if err != nil {
+		t.Fatalf("splitTelegramText() error: %v", err)
+	}
+	if strings.Join(parts, "|") != "aaaa|&amp;b" {
+		t.Fatalf("parts = %q", parts)
+	}
+}
+
+func TestSplitTelegramTextErrors(t *testing.T) {
+	// 超过最大消息数时返回错误而不是截断
+	text := strings.Repeat("word ", telegramMaxParts*4)
+	if _, err := splitTelegramText(text, "", 10); err == nil {
+		t.Fatal("splitTelegramText() succeeded for too many parts")
+	}
+
+	// 链接超过单条消息的长度限制，无法拆分
+	if _, err := splitTelegramText("[text](https://example.com/very/long/path)", "MarkdownV2", 20); err == nil {
+		t.Fatal("splitTelegramText() succeeded for an unsplittable link")
+	}
+}
@@ -2,32 +2,265 @@ package notifier
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"time"
+	"regexp"
+	"strings"
+
+	"synapse/pkg/render"
+	"synapse/pkg/signature"
 )
 
+func init() {
+	Register(&webhookNotifier{})
+}
+
+const defaultWebhookContentType = "application/json"
+
 type WebhookConfig struct {
-	URL     string
-	Method  string // POST/PUT/GET
-	Headers map[string]string
-	Proxy   string // 可选
+	URL           string
+	Method        string // POST/PUT/GET
+	Headers       map[string]string
+	Query         map[string]string // 追加到URL的查询参数
+	ContentType   string            // 默认 application/json
+	SigningSecret string            // 可选，设置后添加签名和时间戳请求头
+	Proxy         string            // 可选
 }
 
-func SendWebhook(cfg WebhookConfig, body []byte) (string, error) {
-	if cfg.URL == "" {
-		return "", errors.New("Webhook URL 不能为空")
+// webhookCredentials Webhook通道凭证
+type webhookCredentials struct {
+	URL           string            `json:"url"`
+	Method        string            `json:"method"`
+	Headers       map[string]string `json:"headers"`
+	SigningSecret string            `json:"signingSecret"`
+	Proxy         string            `json:"proxy"`
+}
+
+type webhookNotifier struct{}
+
+func (n *webhookNotifier) Schema() Schema {
+	return Schema{
+		Type:  "webhook",
+		Label: "Webhook",
+		Fields: []Field{
+			{Name: "url", Label: "URL", Type: "string", Required: true, Endpoint: true},
+			{Name: "method", Label: "请求方法", Type: "string"},
+			{Name: "headers", Label: "请求头", Type: "map", Secret: true},
+			{Name: "signingSecret", Label: "签名密钥", Type: "string", Secret: true},
+			{Name: "proxy", Label: "代理", Type: "string", Endpoint: true},
+			allowedRecipientsSchemaField,
+		},
+	}
+}
+
+func (n *webhookNotifier) Validate(credentials Credentials) error {
+	var config webhookCredentials
+	if err := credentials.Decode(&config); err != nil {
+		return errors.New("Webhook配置格式错误")
+	}
+	if config.URL == "" {
+		return errors.New("Webhook配置不完整")
+	}
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.New("Webhook URL格式错误")
+	}
+	return validateProxy(config.Proxy)
+}
+
+// ValidateOptions 校验路由选项：contentType 为字符串，query、headers 和 recipients 为字符串模板的映射
+func (n *webhookNotifier) ValidateOptions(options map[string]interface{}) error {
+	if _, err := recipientTemplates(options, nil); err != nil {
+		return err
+	}
+	if contentType, ok := options["contentType"]; ok {
+		if _, ok := contentType.(string); !ok {
+			return errors.New("Webhook contentType必须是字符串")
+		}
+	}
+	for _, key := range []string{"query", "headers"} {
+		if _, err := templateMap(options, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Escape 按请求体类型转义模板变量：JSON中变量应放在字符串内，表单中按URL编码
+func (n *webhookNotifier) Escape(value string, credentials Credentials, options map[string]interface{}) string {
+	contentType := webhookContentType(options)
+	switch {
+	case strings.Contains(contentType, "json"):
+		quoted, _ := json.Marshal(value)
+		return string(quoted[1 : len(quoted)-1])
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		return url.QueryEscape(value)
+	}
+	return value
+}
+
+// Send 发送Webhook请求
+// 路由配置了消息模板时使用渲染结果作为请求体，否则转发原始消息内容；
+// 路由选项 query 和 headers 中的模板使用变量映射的结果渲染；
+// recipients 中的模板渲染后替换通道URL中同名的路径参数，如 https://example.com/users/{user}/notify
+func (n *webhookNotifier) Send(ctx context.Context, credentials Credentials, msg *Message) (*Result, error) {
+	body := []byte(msg.Body)
+	if strings.TrimSpace(msg.Body) == "" {
+		body, _ = json.Marshal(msg.Payload)
+	}
+
+	query, err := renderTemplateMap(msg.Options, "query", msg.TemplateData())
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	headers, err := renderTemplateMap(msg.Options, "headers", msg.TemplateData())
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	pathParams, err := resolveRecipients(credentials, msg, nil)
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	return n.send(ctx, credentials, body, pathParams, query, headers, webhookContentType(msg.Options))
+}
+
+func (n *webhookNotifier) Test(ctx context.Context, credentials Credentials, subject, content string) (*Result, error) {
+	return n.send(ctx, credentials, []byte(content), nil, nil, nil, defaultWebhookContentType)
+}
+
+// Check 发送HEAD请求检查Webhook地址是否可达，不发送消息
+func (n *webhookNotifier) Check(ctx context.Context, credentials Credentials) (*Result, error) {
+	var config webhookCredentials
+	if err := credentials.Decode(&config); err != nil {
+		return nil, Permanent(err)
+	}
+	client, err := newHTTPClient(config.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	return checkURL(ctx, client, config.URL, config.Headers)
+}
+
+func (n *webhookNotifier) send(ctx context.Context, credentials Credentials, body []byte, pathParams, query, headers map[string]string, contentType string) (*Result, error) {
+	var config webhookCredentials
+	if err := credentials.Decode(&config); err != nil {
+		return nil, Permanent(err)
+	}
+	target, err := expandPathParams(config.URL, pathParams)
+	if err != nil {
+		return nil, Permanent(err)
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	if cfg.Proxy != "" {
-		proxyURL, err := url.Parse(cfg.Proxy)
+	// 路由的请求头覆盖通道的请求头
+	merged := make(map[string]string, len(config.Headers)+len(headers))
+	for k, v := range config.Headers {
+		merged[k] = v
+	}
+	for k, v := range headers {
+		merged[k] = v
+	}
+
+	return SendWebhook(ctx, WebhookConfig{
+		URL:           target,
+		Method:        config.Method,
+		Headers:       merged,
+		Query:         query,
+		ContentType:   contentType,
+		SigningSecret: config.SigningSecret,
+		Proxy:         config.Proxy,
+	}, body)
+}
+
+// pathParamPattern 匹配URL中的路径参数占位符，如 {user}
+var pathParamPattern = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+
+// expandPathParams 将URL中的路径参数占位符替换为转义后的值（"/"、"?"等和单独的"."、".."都会被转义），存在未设置的参数时返回错误
+func expandPathParams(target string, params map[string]string) (string, error) {
+	var missing []string
+	expanded := pathParamPattern.ReplaceAllStringFunc(target, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		value, ok := params[name]
+		if !ok {
+			missing = append(missing, name)
+			return placeholder
+		}
+		escaped := url.PathEscape(value)
+		// PathEscape不转义"."，单独的"."或".."会被当作相对路径
+		if escaped == "." || escaped == ".." {
+			escaped = strings.ReplaceAll(escaped, ".", "%2E")
+		}
+		return escaped
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("Webhook URL路径参数未设置: %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// webhookContentType 返回路由选项中的请求体类型
+func webhookContentType(options map[string]interface{}) string {
+	if contentType, _ := options["contentType"].(string); contentType != "" {
+		return contentType
+	}
+	return defaultWebhookContentType
+}
+
+// templateMap 读取选项中字符串到模板的映射并校验模板语法
+func templateMap(options map[string]interface{}, key string) (map[string]string, error) {
+	raw, ok := options[key]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Webhook %s必须是键值对象", key)
+	}
+	result := make(map[string]string, len(m))
+	for k, v := range m {
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("Webhook %s.%s必须是字符串", key, k)
+		}
+		if err := render.New(render.EngineText, nil).Parse(k, str); err != nil {
+			return nil, fmt.Errorf("Webhook %s.%s模板错误: %v", key, k, err)
+		}
+		result[k] = str
+	}
+	return result, nil
+}
+
+// renderTemplateMap 渲染选项中的模板映射
+func renderTemplateMap(options map[string]interface{}, key string, data interface{}) (map[string]string, error) {
+	templates, err := templateMap(options, key)
+	if err != nil || len(templates) == 0 {
+		return nil, err
+	}
+	result := make(map[string]string, len(templates))
+	for k, text := range templates {
+		rendered, err := render.Text(text, data)
 		if err != nil {
-			return "", errors.New("代理地址格式错误")
+			return nil, fmt.Errorf("Webhook %s.%s渲染失败: %v", key, k, err)
 		}
-		client.Transport = &http.Transport{Proxy: http.ProxyURL(proxyURL)}
+		result[k] = rendered
+	}
+	return result, nil
+}
+
+// SendWebhook 发送Webhook请求，失败时结果中仍包含状态码和响应内容
+func SendWebhook(ctx context.Context, cfg WebhookConfig, body []byte) (*Result, error) {
+	if cfg.URL == "" {
+		return nil, Permanent(errors.New("Webhook URL 不能为空"))
+	}
+
+	client, err := newHTTPClient(cfg.Proxy)
+	if err != nil {
+		return nil, err
 	}
 
 	method := cfg.Method
@@ -35,23 +268,48 @@ func SendWebhook(cfg WebhookConfig, body []byte) (string, error) {
 		method = "POST"
 	}
 
-	req, err := http.NewRequest(method, cfg.URL, bytes.NewBuffer(body))
+	target, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	if len(cfg.Query) > 0 {
+		values := target.Query()
+		for k, v := range cfg.Query {
+			values.Set(k, v)
+		}
+		target.RawQuery = values.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewBuffer(body))
 	if err != nil {
-		return "", err
+		return nil, Permanent(err)
+	}
+	contentType := cfg.ContentType
+	if contentType == "" {
+		contentType = defaultWebhookContentType
 	}
+	req.Header.Set("Content-Type", contentType)
 	for k, v := range cfg.Headers {
 		req.Header.Set(k, v)
 	}
+	if cfg.SigningSecret != "" {
+		signature.SignRequest(req, cfg.SigningSecret, body)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	respBody, _ := ioutil.ReadAll(resp.Body)
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	result := &Result{
+		StatusCode:        resp.StatusCode,
+		Response:          string(respBody),
+		ProviderMessageID: resp.Header.Get("X-Request-Id"),
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return string(respBody), errors.New("Webhook 响应失败: " + resp.Status)
+		return result, StatusError(resp.StatusCode, errors.New("Webhook 响应失败: "+resp.Status))
 	}
-	return string(respBody), nil
+	return result, nil
 }
@@ -0,0 +1,282 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // 容器镜像中可能没有时区数据
+	"unicode/utf8"
+)
+
+// Funcs 返回模板函数库
+//
+//	formatTime "2006-01-02 15:04" .Payload.created_at "Asia/Shanghai"  时间格式化，可指定时区
+//	now                                                                 当前时间
+//	since .Payload.started_at                                           距今的时长，可配合 humanizeDuration
+//	humanizeDuration 5400                                               时长（秒、Go时长字符串或time.Duration）转为 "1小时30分"
+//	truncate 100 .text                                                  按字符截断，超出部分以 … 结尾
+//	default "N/A" .value                                                值为空时使用默认值
+//	upper / lower                                                       大小写转换
+//	toJSON .Payload                                                     序列化为JSON
+//	regexReplace "\\s+" " " .text                                       正则替换
+//	escapeMarkdownV2 .text                                              转义Telegram MarkdownV2特殊字符
+func Funcs() map[string]interface{} {
+	return map[string]interface{}{
+		"formatTime":       formatTime,
+		"now":              time.Now,
+		"since":            since,
+		"humanizeDuration": humanizeDuration,
+		"truncate":         truncate,
+		"default":          defaultValue,
+		"upper":            func(v interface{}) string { return strings.ToUpper(toString(v)) },
+		"lower":            func(v interface{}) string { return strings.ToLower(toString(v)) },
+		"toJSON":           toJSON,
+		"regexReplace":     regexReplace,
+		"escapeMarkdownV2": func(v interface{}) string { return EscapeMarkdownV2(toString(v)) },
+	}
+}
+
+// layoutAliases formatTime支持的布局别名
+var layoutAliases = map[string]string{
+	"RFC3339":  time.RFC3339,
+	"datetime": "2006-01-02 15:04:05",
+	"date":     "2006-01-02",
+	"time":     "15:04:05",
+}
+
+// formatTime 按布局格式化时间，tz为IANA时区名，未指定时使用UTC
+func formatTime(layout string, value interface{}, tz ...string) (string, error) {
+	t, err := toTime(value)
+	if err != nil {
+		return "", err
+	}
+	if len(tz) > 0 && tz[0] != "" {
+		loc, err := time.LoadLocation(tz[0])
+		if err != nil {
+			return "", fmt.Errorf("未知的时区: %s", tz[0])
+		}
+		t = t.In(loc)
+	} else {
+		t = t.UTC()
+	}
+	if alias, ok := layoutAliases[layout]; ok {
+		layout = alias
+	}
+	return t.Format(layout), nil
+}
+
+// since 返回距今的时长
+func since(value interface{}) (time.Duration, error) {
+	t, err := toTime(value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Since(t), nil
+}
+
+// timeLayouts toTime依次尝试的字符串格式
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// toTime 转换为时间，支持time.Time、Unix时间戳（秒或毫秒）和常见的时间字符串
+func toTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		if v != nil {
+			return *v, nil
+		}
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return unixTime(n), nil
+		}
+		return time.Time{}, fmt.Errorf("无法解析时间: %q", v)
+	default:
+		if n, ok := toFloat(value); ok {
+			return unixTime(n), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法转换为时间: %v", value)
+}
+
+// unixTime 将Unix时间戳转为时间，大于1e12时按毫秒处理
+func unixTime(n float64) time.Time {
+	if n > 1e12 {
+		return time.UnixMilli(int64(n))
+	}
+	sec := int64(n)
+	return time.Unix(sec, int64((n-float64(sec))*1e9))
+}
+
+// humanizeDuration 将时长转为可读文本，最多保留两个单位，如 "2天3小时"、"1分30秒"
+func humanizeDuration(value interface{}) (string, error) {
+	var d time.Duration
+	switch v := value.(type) {
+	case time.Duration:
+		d = v
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return "", fmt.Errorf("无法解析时长: %q", v)
+			}
+			parsed = time.Duration(n * float64(time.Second))
+		}
+		d = parsed
+	default:
+		n, ok := toFloat(value)
+		if !ok {
+			return "", fmt.Errorf("无法转换为时长: %v", value)
+		}
+		d = time.Duration(n * float64(time.Second))
+	}
+
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	if d < time.Second {
+		return sign + "0秒", nil
+	}
+
+	units := []struct {
+		size time.Duration
+		name string
+	}{
+		{24 * time.Hour, "天"},
+		{time.Hour, "小时"},
+		{time.Minute, "分"},
+		{time.Second, "秒"},
+	}
+	var parts []string
+	for _, unit := range units {
+		if d >= unit.size {
+			parts = append(parts, fmt.Sprintf("%d%s", d/unit.size, unit.name))
+			d %= unit.size
+		} else if len(parts) > 0 {
+			// 只保留相邻的两个单位，如 "1天" 而不是 "1天5秒"
+			break
+		}
+		if len(parts) == 2 {
+			break
+		}
+	}
+	return sign + strings.Join(parts, ""), nil
+}
+
+// truncate 按字符截断，超出n个字符时截断并以 … 结尾
+func truncate(n int, value interface{}) string {
+	s := toString(value)
+	if n < 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n]) + "…"
+}
+
+// defaultValue 值为空（nil、空字符串、0、false、空集合）时返回默认值
+func defaultValue(def, value interface{}) interface{} {
+	if value == nil {
+		return def
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.String:
+		if v.Len() == 0 {
+			return def
+		}
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return def
+		}
+	default:
+		if v.IsZero() {
+			return def
+		}
+	}
+	return value
+}
+
+// toJSON 序列化为JSON
+func toJSON(value interface{}) (string, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// regexReplace 正则替换，repl中可使用 $1 引用分组
+func regexReplace(pattern, repl string, value interface{}) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("正则表达式错误: %w", err)
+	}
+	return re.ReplaceAllString(toString(value), repl), nil
+}
+
+// markdownV2Replacer Telegram MarkdownV2需要转义的字符
+var markdownV2Replacer = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+	"~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`,
+	"|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// EscapeMarkdownV2 转义Telegram MarkdownV2中的特殊字符
+func EscapeMarkdownV2(s string) string {
+	return markdownV2Replacer.Replace(s)
+}
+
+// toString 转为字符串，nil为空字符串
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// toFloat 转换数值类型
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	}
+	return 0, false
+}
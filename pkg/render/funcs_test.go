@@ -0,0 +1,145 @@
+package render
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// renderText 使用text引擎渲染模板
+func renderText(t *testing.T, text string, data interface{}) string {
+	t.Helper()
+	out, err := New(EngineText, nil).Render("test", text, data)
+	if err != nil {
+		t.Fatalf("Render(%q) error: %v", text, err)
+	}
+	return out
+}
+
+func TestFormatTime(t *testing.T) {
+	tests := []struct {
+		name  string
+		tmpl  string
+		value interface{}
+		want  string
+	}{
+		{"RFC3339字符串", `{{formatTime "datetime" .v}}`, "2024-03-01T08:30:00Z", "2024-03-01 08:30:00"},
+		{"指定时区", `{{formatTime "datetime" .v "Asia/Shanghai"}}`, "2024-03-01T08:30:00Z", "2024-03-01 16:30:00"},
+		{"Unix秒", `{{formatTime "date" .v}}`, float64(1709281800), "2024-03-01"},
+		{"Unix毫秒", `{{formatTime "time" .v}}`, float64(1709281800000), "08:30:00"},
+		{"数字字符串", `{{formatTime "RFC3339" .v}}`, "1709281800", "2024-03-01T08:30:00Z"},
+		{"自定义布局", `{{formatTime "01/02 15:04" .v}}`, "2024-03-01 08:30:00", "03/01 08:30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderText(t, tt.tmpl, map[string]interface{}{"v": tt.value}); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatTimeErrors(t *testing.T) {
+	for _, tmpl := range []string{
+		`{{formatTime "date" .v}}`,
+		`{{formatTime "date" .n "Mars/Olympus"}}`,
+	} {
+		_, err := New(EngineText, nil).Render("test", tmpl, map[string]interface{}{"v": "not a time", "n": float64(0)})
+		if err == nil {
+			t.Fatalf("Render(%q) succeeded", tmpl)
+		}
+	}
+}
+
+func TestHumanizeDuration(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{float64(5400), "1小时30分"},
+		{float64(90061), "1天1小时"},
+		{float64(86405), "1天"},
+		{float64(59), "59秒"},
+		{float64(0.5), "0秒"},
+		{float64(-90), "-1分30秒"},
+		{"2h3m", "2小时3分"},
+		{"75", "1分15秒"},
+		{3 * time.Minute, "3分"},
+	}
+	for _, tt := range tests {
+		got, err := humanizeDuration(tt.value)
+		if err != nil {
+			t.Fatalf("humanizeDuration(%v) error: %v", tt.value, err)
+		}
+		if got != tt.want {
+			t.Errorf("humanizeDuration(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+	if _, err := humanizeDuration("soon"); err == nil {
+		t.Error("humanizeDuration(\"soon\") succeeded")
+	}
+	if _, err := humanizeDuration(true); err == nil {
+		t.Error("humanizeDuration(true) succeeded")
+	}
+}
+
+func TestSince(t *testing.T) {
+	started := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	got := renderText(t, `{{humanizeDuration (since .v)}}`, map[string]interface{}{"v": started})
+	if got != "2小时" && got != "1小时59分" {
+		t.Fatalf("got %q, want about 2小时", got)
+	}
+}
+
+func TestStringFuncs(t *testing.T) {
+	data := map[string]interface{}{
+		"text":   "磁盘使用率过高，请尽快处理",
+		"empty":  "",
+		"zero":   float64(0),
+		"name":   "Api-Server",
+		"list":   []interface{}{},
+		"labels": map[string]interface{}{"env": "prod"},
+		"spaces": "a  b\t\tc",
+	}
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{`{{truncate 5 .text}}`, "磁盘使用率…"},
+		{`{{truncate 50 .text}}`, "磁盘使用率过高，请尽快处理"},
+		{`{{truncate -1 .text}}`, "磁盘使用率过高，请尽快处理"},
+		{`{{default "N/A" .empty}}`, "N/A"},
+		{`{{default "N/A" .zero}}`, "N/A"},
+		{`{{default "N/A" .list}}`, "N/A"},
+		{`{{default "N/A" .missing}}`, "N/A"},
+		{`{{default "N/A" .name}}`, "Api-Server"},
+		{`{{upper .name}} {{lower .name}}`, "API-SERVER api-server"},
+		{`{{upper .missing}}`, ""},
+		{`{{toJSON .labels}}`, `{"env":"prod"}`},
+		{`{{regexReplace "\\s+" " " .spaces}}`, "a b c"},
+		{`{{regexReplace "(\\w+)-(\\w+)" "$2/$1" .name}}`, "Server/Api"},
+		{`{{escapeMarkdownV2 .name}}`, `Api\-Server`},
+	}
+	for _, tt := range tests {
+		if got := renderText(t, tt.tmpl, data); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+
+	if _, err := New(EngineText, nil).Render("test", `{{regexReplace "(" "" .name}}`, data); err == nil {
+		t.Error("regexReplace with invalid pattern succeeded")
+	}
+}
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	special := "\\_*[]()~`>#+-=|{}.!"
+	escaped := EscapeMarkdownV2(special)
+	for _, r := range special {
+		if !strings.Contains(escaped, `\`+string(r)) {
+			t.Errorf("%q is not escaped in %q", r, escaped)
+		}
+	}
+	if got := EscapeMarkdownV2("CPU 97.5%"); got != `CPU 97\.5%` {
+		t.Errorf("EscapeMarkdownV2() = %q", got)
+	}
+}
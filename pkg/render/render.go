@@ -0,0 +1,213 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// 模板引擎
+const (
+	EngineText = "text" // text/template，不做转义，由通道按需转义变量
+	EngineHTML = "html" // html/template，按HTML上下文自动转义
+)
+
+// IsValidEngine 判断是否为支持的模板引擎
+func IsValidEngine(engine string) bool {
+	return engine == EngineText || engine == EngineHTML
+}
+
+// Renderer 模板渲染器，模板中可通过 {{template "名称" .}} 引用命名片段
+type Renderer struct {
+	engine   string
+	partials map[string]string
+}
+
+// New 创建渲染器，engine为空时使用text引擎
+func New(engine string, partials map[string]string) *Renderer {
+	if engine == "" {
+		engine = EngineText
+	}
+	return &Renderer{engine: engine, partials: partials}
+}
+
+// Parse 检查模板语法，以及引用的片段是否存在
+func (r *Renderer) Parse(name, text string) error {
+	tmpl, err := r.parse(name, text)
+	if err != nil {
+		return err
+	}
+
+	var tree *parse.Tree
+	var defined func(string) bool
+	switch t := tmpl.(type) {
+	case *template.Template:
+		tree, defined = t.Tree, func(n string) bool { return t.Lookup(n) != nil }
+	case *htmltemplate.Template:
+		tree, defined = t.Tree, func(n string) bool { return t.Lookup(n) != nil }
+	}
+	if tree == nil || tree.Root == nil {
+		return nil
+	}
+	for _, ref := range templateRefs(tree.Root, nil) {
+		if !defined(ref) {
+			return fmt.Errorf("引用的片段不存在: %s", ref)
+		}
+	}
+	return nil
+}
+
+// templateRefs 收集节点中 {{template "名称"}} 引用的模板名称
+func templateRefs(node parse.Node, refs []string) []string {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return refs
+		}
+		for _, child := range n.Nodes {
+			refs = templateRefs(child, refs)
+		}
+	case *parse.TemplateNode:
+		refs = append(refs, n.Name)
+	case *parse.IfNode:
+		refs = templateRefs(n.List, refs)
+		refs = templateRefs(n.ElseList, refs)
+	case *parse.RangeNode:
+		refs = templateRefs(n.List, refs)
+		refs = templateRefs(n.ElseList, refs)
+	case *parse.WithNode:
+		refs = templateRefs(n.List, refs)
+		refs = templateRefs(n.ElseList, refs)
+	}
+	return refs
+}
+
+// Render 渲染模板，模板为空时返回空字符串
+func (r *Renderer) Render(name, text string, data interface{}) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tmpl, err := r.parse(name, text)
+	if err != nil {
+		return "", err
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
+
+// executor text/template和html/template的公共方法
+type executor interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+func (r *Renderer) parse(name, text string) (executor, error) {
+	switch r.engine {
+	case EngineText:
+		tmpl := template.New(name).Funcs(Funcs())
+		for _, partial := range r.partialNames() {
+			if _, err := tmpl.New(partial).Parse(r.partials[partial]); err != nil {
+				return nil, fmt.Errorf("片段 %s: %w", partial, err)
+			}
+		}
+		return tmpl.Parse(text)
+	case EngineHTML:
+		tmpl := htmltemplate.New(name).Funcs(Funcs())
+		for _, partial := range r.partialNames() {
+			if _, err := tmpl.New(partial).Parse(r.partials[partial]); err != nil {
+				return nil, fmt.Errorf("片段 %s: %w", partial, err)
+			}
+		}
+		return tmpl.Parse(text)
+	default:
+		return nil, fmt.Errorf("不支持的模板引擎: %s", r.engine)
+	}
+}
+
+// partialNames 按名称排序的片段，保证解析错误稳定
+func (r *Renderer) partialNames() []string {
+	names := make([]string, 0, len(r.partials))
+	for name := range r.partials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Data 构造模板数据：变量映射的结果，以及完整的原始消息内容 .Payload
+func Data(variables, payload map[string]interface{}) map[string]interface{} {
+	data := make(map[string]interface{}, len(variables)+1)
+	for name, value := range variables {
+		data[name] = value
+	}
+	data["Payload"] = payload
+	return data
+}
+
+// missingFunc 选项模板中追加到输出管道末尾的函数名
+const missingFunc = "_missingAsEmpty"
+
+// Text 使用text引擎渲染，不含模板语法时原样返回，用于通道选项中的模板
+// 消息中缺失的字段渲染为空字符串而不是 "<no value>"，以便选项按渲染结果是否为空决定是否生效
+func Text(text string, data interface{}) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("option").Funcs(Funcs()).Funcs(template.FuncMap{
+		missingFunc: func(v interface{}) interface{} {
+			if v == nil {
+				return ""
+			}
+			return v
+		},
+	}).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	missingAsEmpty(tmpl.Tree.Root)
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
+
+// missingAsEmpty 在每个输出动作的管道末尾追加 missingFunc，缺失的字段和null值经过它后输出为空字符串
+func missingAsEmpty(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			missingAsEmpty(child)
+		}
+	case *parse.ActionNode:
+		// 变量声明 {{$x := ...}} 不输出内容
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{parse.NewIdentifier(missingFunc).SetPos(n.Pos)},
+		})
+	case *parse.IfNode:
+		missingAsEmpty(n.List)
+		missingAsEmpty(n.ElseList)
+	case *parse.RangeNode:
+		missingAsEmpty(n.List)
+		missingAsEmpty(n.ElseList)
+	case *parse.WithNode:
+		missingAsEmpty(n.List)
+		missingAsEmpty(n.ElseList)
+	}
+}
@@ -0,0 +1,276 @@
+// Package signature 实现Webhook的HMAC签名与校验
+//
+// GitHub和GitLab的请求不带时间戳，无法按时间拒绝旧请求，只能依靠必须提供的请求ID防止重放，
+// 这类请求ID保留 UntimedNonceWindow；其他方案校验时间戳，nonce保留到时间戳失效为止。
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 签名方案
+const (
+	SchemeGeneric = "generic" // X-Synapse-Timestamp + X-Synapse-Signature，签名内容为 timestamp + "." + body
+	SchemeGitHub  = "github"  // X-Hub-Signature-256，签名内容为body
+	SchemeGitLab  = "gitlab"  // X-Gitlab-Token，直接比较密钥
+	SchemeStripe  = "stripe"  // Stripe-Signature: t=...,v1=...，签名内容为 t + "." + body
+)
+
+// 通用方案的请求头
+const (
+	HeaderTimestamp = "X-Synapse-Timestamp"
+	HeaderSignature = "X-Synapse-Signature"
+	HeaderNonce     = "X-Synapse-Nonce"
+)
+
+var (
+	ErrMissingSignature = errors.New("缺少签名")
+	ErrInvalidSignature = errors.New("签名无效")
+	ErrStaleTimestamp   = errors.New("签名时间戳已过期")
+	ErrMissingNonce     = errors.New("缺少请求ID")
+	ErrReplayed         = errors.New("重复的请求")
+	ErrNonceStore       = errors.New("记录nonce失败") // NonceStore出错，不代表请求无效
+)
+
+// UntimedNonceWindow 不带时间戳的方案（github、gitlab）中请求ID的保留时间
+const UntimedNonceWindow = 7 * 24 * time.Hour
+
+// IsValidScheme 判断签名方案是否支持
+func IsValidScheme(scheme string) bool {
+	switch scheme {
+	case SchemeGeneric, SchemeGitHub, SchemeGitLab, SchemeStripe:
+		return true
+	}
+	return false
+}
+
+// Sign 计算通用方案的签名，返回 "sha256=<hex>"
+func Sign(secret string, timestamp int64, body []byte) string {
+	return "sha256=" + hmacHex(secret, []byte(strconv.FormatInt(timestamp, 10)+"."), body)
+}
+
+// SignRequest 为出站请求设置通用方案的时间戳和签名请求头
+func SignRequest(req *http.Request, secret string, body []byte) {
+	timestamp := time.Now().Unix()
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+}
+
+func hmacHex(secret string, parts ...[]byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	for _, p := range parts {
+		mac.Write(p)
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NonceStore 记录已使用的nonce
+type NonceStore interface {
+	// Remember 记录nonce并保留ttl，保留期内已存在时返回false
+	Remember(key string, ttl time.Duration) (bool, error)
+	// Forget 删除nonce记录，使同一请求可以再次通过校验
+	Forget(key string) error
+}
+
+// Verifier 入站签名校验器，通过NonceStore记录已使用的nonce防止重放
+type Verifier struct {
+	tolerance time.Duration
+	window    time.Duration
+	store     NonceStore
+}
+
+// NewVerifier 创建签名校验器
+// tolerance 为允许的时间戳偏差，window 为nonce保留时间（不小于tolerance），store 为空时使用 MemoryNonceStore
+func NewVerifier(tolerance, window time.Duration, store NonceStore) *Verifier {
+	if window < tolerance {
+		window = tolerance
+	}
+	if store == nil {
+		store = NewMemoryNonceStore()
+	}
+	return &Verifier{
+		tolerance: tolerance,
+		window:    window,
+		store:     store,
+	}
+}
+
+// Verify 按签名方案校验请求并记录nonce，scope 用于隔离不同主题的nonce
+// 返回记录的nonce键，请求最终没有被接受时（如保存失败）应调用 Release 释放，使发送方的重试可以通过校验
+func (v *Verifier) Verify(scheme, secret string, header http.Header, body []byte, scope string) (string, error) {
+	nonce, ttl := "", v.window
+	switch scheme {
+	case SchemeGitHub:
+		sig := header.Get("X-Hub-Signature-256")
+		if sig == "" {
+			return "", ErrMissingSignature
+		}
+		if !equalSignature(sig, "sha256="+hmacHex(secret, body)) {
+			return "", ErrInvalidSignature
+		}
+		if nonce = header.Get("X-GitHub-Delivery"); nonce == "" {
+			return "", ErrMissingNonce
+		}
+		ttl = UntimedNonceWindow
+
+	case SchemeGitLab:
+		token := header.Get("X-Gitlab-Token")
+		if token == "" {
+			return "", ErrMissingSignature
+		}
+		if !equalSignature(token, secret) {
+			return "", ErrInvalidSignature
+		}
+		if nonce = header.Get("X-Gitlab-Event-UUID"); nonce == "" {
+			return "", ErrMissingNonce
+		}
+		ttl = UntimedNonceWindow
+
+	case SchemeStripe:
+		sigHeader := header.Get("Stripe-Signature")
+		if sigHeader == "" {
+			return "", ErrMissingSignature
+		}
+		var timestamp string
+		var signatures []string
+		for _, part := range strings.Split(sigHeader, ",") {
+			k, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch k {
+			case "t":
+				timestamp = val
+			case "v1":
+				signatures = append(signatures, val)
+			}
+		}
+		if timestamp == "" || len(signatures) == 0 {
+			return "", ErrInvalidSignature
+		}
+		if err := v.checkTimestamp(timestamp); err != nil {
+			return "", err
+		}
+		expected := hmacHex(secret, []byte(timestamp+"."), body)
+		matched := false
+		for _, sig := range signatures {
+			if equalSignature(sig, expected) {
+				matched = true
+				nonce = sig
+				break
+			}
+		}
+		if !matched {
+			return "", ErrInvalidSignature
+		}
+
+	case SchemeGeneric:
+		timestamp := header.Get(HeaderTimestamp)
+		sig := header.Get(HeaderSignature)
+		if timestamp == "" || sig == "" {
+			return "", ErrMissingSignature
+		}
+		if err := v.checkTimestamp(timestamp); err != nil {
+			return "", err
+		}
+		if !equalSignature(sig, "sha256="+hmacHex(secret, []byte(timestamp+"."), body)) {
+			return "", ErrInvalidSignature
+		}
+		nonce = header.Get(HeaderNonce)
+		if nonce == "" {
+			nonce = sig
+		}
+
+	default:
+		return "", errors.New("不支持的签名方案")
+	}
+
+	// 记录的键为scope和nonce的摘要，长度固定
+	key := sha256.Sum256([]byte(scope + ":" + nonce))
+	nonceKey := hex.EncodeToString(key[:])
+	fresh, err := v.store.Remember(nonceKey, ttl)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrNonceStore, err)
+	}
+	if !fresh {
+		return "", ErrReplayed
+	}
+	return nonceKey, nil
+}
+
+// Release 释放 Verify 记录的nonce
+func (v *Verifier) Release(nonceKey string) error {
+	if err := v.store.Forget(nonceKey); err != nil {
+		return fmt.Errorf("%w: %v", ErrNonceStore, err)
+	}
+	return nil
+}
+
+// checkTimestamp 校验Unix时间戳（秒）是否在允许的偏差内
+func (v *Verifier) checkTimestamp(value string) error {
+	ts, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	diff := time.Since(time.Unix(ts, 0))
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > v.tolerance {
+		return ErrStaleTimestamp
+	}
+	return nil
+}
+
+// MemoryNonceStore 进程内存中的nonce记录，重启后丢失且不在实例间共享，只适用于单实例部署和测试
+type MemoryNonceStore struct {
+	mu        sync.Mutex
+	nonces    map[string]time.Time // nonce -> 过期时间
+	lastSweep time.Time
+}
+
+// NewMemoryNonceStore 创建内存nonce记录
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: make(map[string]time.Time)}
+}
+
+// Remember 记录nonce，保留期内已存在时返回false
+func (s *MemoryNonceStore) Remember(key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, expiresAt := range s.nonces {
+			if now.After(expiresAt) {
+				delete(s.nonces, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if expiresAt, ok := s.nonces[key]; ok && !now.After(expiresAt) {
+		return false, nil
+	}
+	s.nonces[key] = now.Add(ttl)
+	return true, nil
+}
+
+// Forget 删除nonce记录
+func (s *MemoryNonceStore) Forget(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.nonces, key)
+	return nil
+}
+
+func equalSignature(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,222 @@
+package signature
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// recordingStore 记录每次调用的保留时间，可模拟存储出错
+type recordingStore struct {
+	*MemoryNonceStore
+	ttls []time.Duration
+	err  error
+}
+
+func newRecordingStore() *recordingStore {
+	return &recordingStore{MemoryNonceStore: NewMemoryNonceStore()}
+}
+
+func (s *recordingStore) Remember(key string, ttl time.Duration) (bool, error) {
+	s.ttls = append(s.ttls, ttl)
+	if s.err != nil {
+		return false, s.err
+	}
+	return s.MemoryNonceStore.Remember(key, ttl)
+}
+
+const secret = "s3cret"
+
+var body = []byte(`{"event":"deploy"}`)
+
+func genericHeader(timestamp int64, nonce string) http.Header {
+	header := http.Header{}
+	header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	header.Set(HeaderSignature, Sign(secret, timestamp, body))
+	if nonce != "" {
+		header.Set(HeaderNonce, nonce)
+	}
+	return header
+}
+
+func githubHeader(delivery string) http.Header {
+	header := http.Header{}
+	header.Set("X-Hub-Signature-256", "sha256="+hmacHex(secret, body))
+	if delivery != "" {
+		header.Set("X-GitHub-Delivery", delivery)
+	}
+	return header
+}
+
+func gitlabHeader(token, event string) http.Header {
+	header := http.Header{}
+	header.Set("X-Gitlab-Token", token)
+	if event != "" {
+		header.Set("X-Gitlab-Event-UUID", event)
+	}
+	return header
+}
+
+func stripeHeader(timestamp int64) http.Header {
+	t := strconv.FormatInt(timestamp, 10)
+	header := http.Header{}
+	header.Set("Stripe-Signature", "t="+t+",v1=deadbeef,v1="+hmacHex(secret, []byte(t+"."), body))
+	return header
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now().Unix()
+	tests := []struct {
+		name   string
+		scheme string
+		header http.Header
+		secret string
+		want   error
+	}{
+		{"generic", SchemeGeneric, genericHeader(now, "n1"), secret, nil},
+		{"generic无nonce", SchemeGeneric, genericHeader(now, ""), secret, nil},
+		{"generic密钥错误", SchemeGeneric, genericHeader(now, "n2"), "other", ErrInvalidSignature},
+		{"generic时间戳过期", SchemeGeneric, genericHeader(now-600, "n3"), secret, ErrStaleTimestamp},
+		{"generic缺少签名", SchemeGeneric, http.Header{}, secret, ErrMissingSignature},
+		{"github", SchemeGitHub, githubHeader("d1"), secret, nil},
+		{"github密钥错误", SchemeGitHub, githubHeader("d2"), "other", ErrInvalidSignature},
+		{"github缺少请求ID", SchemeGitHub, githubHeader(""), secret, ErrMissingNonce},
+		{"gitlab", SchemeGitLab, gitlabHeader(secret, "e1"), secret, nil},
+		{"gitlab令牌错误", SchemeGitLab, gitlabHeader("other", "e2"), secret, ErrInvalidSignature},
+		{"gitlab缺少请求ID", SchemeGitLab, gitlabHeader(secret, ""), secret, ErrMissingNonce},
+		{"stripe", SchemeStripe, stripeHeader(now), secret, nil},
+		{"stripe时间戳过期", SchemeStripe, stripeHeader(now - 600), secret, ErrStaleTimestamp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVerifier(5*time.Minute, 10*time.Minute, nil)
+			_, err := v.Verify(tt.scheme, tt.secret, tt.header, body, "1")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyReplay(t *testing.T) {
+	now := time.Now().Unix()
+	tests := []struct {
+		name   string
+		scheme string
+		header http.Header
+	}{
+		{"generic", SchemeGeneric, genericHeader(now, "n1")},
+		{"generic无nonce时使用签名", SchemeGeneric, genericHeader(now, "")},
+		{"github", SchemeGitHub, githubHeader("d1")},
+		{"gitlab", SchemeGitLab, gitlabHeader(secret, "e1")},
+		{"stripe", SchemeStripe, stripeHeader(now)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 两个校验器共享存储，模拟多个实例
+			store := NewMemoryNonceStore()
+			first := NewVerifier(5*time.Minute, 10*time.Minute, store)
+			second := NewVerifier(5*time.Minute, 10*time.Minute, store)
+
+			if _, err := first.Verify(tt.scheme, secret, tt.header, body, "1"); err != nil {
+				t.Fatalf("first Verify() = %v", err)
+			}
+			if _, err := second.Verify(tt.scheme, secret, tt.header, body, "1"); !errors.Is(err, ErrReplayed) {
+				t.Fatalf("replayed Verify() = %v, want ErrReplayed", err)
+			}
+			// 不同主题的nonce互不影响
+			if _, err := second.Verify(tt.scheme, secret, tt.header, body, "2"); err != nil {
+				t.Fatalf("other scope Verify() = %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyNonceTTL(t *testing.T) {
+	now := time.Now().Unix()
+	store := newRecordingStore()
+	v := NewVerifier(5*time.Minute, time.Minute, store)
+
+	if _, err := v.Verify(SchemeGeneric, secret, genericHeader(now, "n1"), body, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(SchemeGitHub, secret, githubHeader("d1"), body, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(SchemeGitLab, secret, gitlabHeader(secret, "e1"), body, "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 窗口不小于时间戳偏差；不带时间戳的方案保留更久
+	want := []time.Duration{5 * time.Minute, UntimedNonceWindow, UntimedNonceWindow}
+	if len(store.ttls) != len(want) {
+		t.Fatalf("ttls = %v, want %v", store.ttls, want)
+	}
+	for i := range want {
+		if store.ttls[i] != want[i] {
+			t.Fatalf("ttls = %v, want %v", store.ttls, want)
+		}
+	}
+}
+
+func TestVerifyStoreError(t *testing.T) {
+	store := newRecordingStore()
+	store.err = errors.New("database is down")
+	v := NewVerifier(5*time.Minute, 10*time.Minute, store)
+
+	_, err := v.Verify(SchemeGitHub, secret, githubHeader("d1"), body, "1")
+	if !errors.Is(err, ErrNonceStore) {
+		t.Fatalf("Verify() = %v, want ErrNonceStore", err)
+	}
+}
+
+func TestVerifyRejectsBeforeRemembering(t *testing.T) {
+	store := newRecordingStore()
+	v := NewVerifier(5*time.Minute, 10*time.Minute, store)
+
+	// 签名无效的请求不应占用nonce
+	if _, err := v.Verify(SchemeGitHub, "other", githubHeader("d1"), body, "1"); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Verify() = %v, want ErrInvalidSignature", err)
+	}
+	if len(store.ttls) != 0 {
+		t.Fatalf("invalid request remembered a nonce")
+	}
+	if _, err := v.Verify(SchemeGitHub, secret, githubHeader("d1"), body, "1"); err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+}
+
+func TestVerifyRelease(t *testing.T) {
+	v := NewVerifier(5*time.Minute, 10*time.Minute, nil)
+
+	key, err := v.Verify(SchemeGitHub, secret, githubHeader("d1"), body, "1")
+	if err != nil || key == "" {
+		t.Fatalf("Verify() = %q, %v", key, err)
+	}
+	// 请求未被接受时释放nonce，重新投递的同一请求可以通过校验
+	if err := v.Release(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(SchemeGitHub, secret, githubHeader("d1"), body, "1"); err != nil {
+		t.Fatalf("Verify() after Release = %v", err)
+	}
+	if _, err := v.Verify(SchemeGitHub, secret, githubHeader("d1"), body, "1"); !errors.Is(err, ErrReplayed) {
+		t.Fatalf("Verify() = %v, want ErrReplayed", err)
+	}
+}
+
+func TestMemoryNonceStoreExpiry(t *testing.T) {
+	store := NewMemoryNonceStore()
+	if ok, _ := store.Remember("k", 20*time.Millisecond); !ok {
+		t.Fatal("first Remember() = false")
+	}
+	if ok, _ := store.Remember("k", 20*time.Millisecond); ok {
+		t.Fatal("second Remember() = true")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if ok, _ := store.Remember("k", 20*time.Millisecond); !ok {
+		t.Fatal("Remember() after expiry = false")
+	}
+}
@@ -66,6 +66,8 @@ export interface Routing {
   priority: number
   variableMappings: Record<string, any>
   messageTemplate: string
+  subjectTemplate?: string
+  htmlTemplate?: string
   createdAt: string
   updatedAt: string
 }
@@ -103,6 +105,7 @@ export interface CreateRoutingRequest {
   variableMappings: Record<string, any>
   messageTemplate: string
   subjectTemplate?: string
+  htmlTemplate?: string
 }
 
 export interface UpdateRoutingRequest {
@@ -110,6 +113,7 @@ export interface UpdateRoutingRequest {
   variableMappings: Record<string, any>
   messageTemplate: string
   subjectTemplate?: string
+  htmlTemplate?: string
 }
 
 // 通道API